@@ -0,0 +1,79 @@
+package maxminddb
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// LookupAddr is like Lookup but takes the IP address as a netip.Addr, which
+// avoids allocating a net.IP for every lookup. IPv4-mapped IPv6 addresses are
+// looked up as IPv4 addresses, and addresses with a zone are handled
+// according to the ZonePolicy of the Reader.
+func (r *Reader) LookupAddr(addr netip.Addr, result interface{}) error {
+	pointer, _, err := r.lookupAddrPointer(addr)
+	if pointer == 0 || err != nil {
+		return err
+	}
+	return r.retrieveData(pointer, result)
+}
+
+// LookupAddrOffset is like LookupOffset but takes the IP address as a
+// netip.Addr.
+func (r *Reader) LookupAddrOffset(addr netip.Addr) (uintptr, error) {
+	pointer, _, err := r.lookupAddrPointer(addr)
+	if pointer == 0 || err != nil {
+		return NotFound, err
+	}
+	return r.resolveDataPointer(pointer)
+}
+
+// LookupAddrNetwork is like LookupNetwork but takes the IP address as a
+// netip.Addr and returns the network as a netip.Prefix. The prefix of an
+// IPv4 address is an IPv4 prefix, even in an IPv6 database.
+func (r *Reader) LookupAddrNetwork(addr netip.Addr, result interface{}) (prefix netip.Prefix, ok bool, err error) {
+	var pointer uint
+	pointer, prefix, err = r.lookupAddrPointer(addr)
+	if pointer == 0 || err != nil {
+		return prefix, false, err
+	}
+	return prefix, true, r.retrieveData(pointer, result)
+}
+
+// lookupAddrPointer returns the record pointer for addr, which is 0 if there
+// is no record, and the network of the search tree containing it.
+func (r *Reader) lookupAddrPointer(addr netip.Addr) (uint, netip.Prefix, error) {
+	if r.buffer == nil {
+		return 0, netip.Prefix{}, ErrClosed
+	}
+	if !addr.IsValid() {
+		return 0, netip.Prefix{}, errors.New("addr passed to LookupAddr is not valid")
+	}
+	addr, err := r.unzone(addr)
+	if err != nil {
+		return 0, netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+
+	// The bytes stay on the stack, unlike those of addr.AsSlice.
+	var buf [16]byte
+	var ip net.IP
+	if addr.Is4() {
+		b := addr.As4()
+		ip = buf[:copy(buf[:], b[:])]
+	} else {
+		if r.Metadata.IPVersion == 4 {
+			return 0, netip.Prefix{}, fmt.Errorf("error looking up '%s': you attempted to look up an IPv6 address in an IPv4-only database", addr)
+		}
+		buf = addr.As16()
+		ip = buf[:]
+	}
+
+	pointer, prefixLength, err := r.findAddressInTree(ip, nil)
+	if err != nil {
+		return 0, netip.Prefix{}, err
+	}
+	prefix, err := addr.Prefix(int(prefixLength))
+	return pointer, prefix, err
+}
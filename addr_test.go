@@ -0,0 +1,105 @@
+package maxminddb
+
+import (
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestLookupAddr(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	for _, address := range []string{"81.2.69.160", "::ffff:81.2.69.160", "2001:218::1", "10.0.0.1"} {
+		var expected, result interface{}
+		if err := reader.Lookup(net.ParseIP(address), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if err := reader.LookupAddr(netip.MustParseAddr(address), &result); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Errorf("unexpected record for %s: %v", address, result)
+		}
+
+		expectedOffset, err := reader.LookupOffset(net.ParseIP(address))
+		if err != nil {
+			t.Fatal(err)
+		}
+		offset, err := reader.LookupAddrOffset(netip.MustParseAddr(address))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != expectedOffset {
+			t.Errorf("expected offset %d for %s, got %d", expectedOffset, address, offset)
+		}
+	}
+
+	var result interface{}
+	if err := reader.LookupAddr(netip.Addr{}, &result); err == nil {
+		t.Error("expected an error for the zero address")
+	}
+	if err := reader.LookupAddr(netip.MustParseAddr("fe80::1%eth0"), &result); err != nil {
+		t.Errorf("expected the zone to be stripped, got %v", err)
+	}
+}
+
+func TestLookupAddrNetwork(t *testing.T) {
+	networks := map[string]string{
+		"1.1.1.3":         "1.1.1.2/31",
+		"::ffff:1.1.1.3":  "1.1.1.2/31",
+		"::2:0:49":        "::2:0:40/124",
+		"1.1.1.33":        "1.1.1.33/32",
+		"255.255.255.255": "128.0.0.0/1",
+	}
+	for _, fileName := range []string{"MaxMind-DB-test-mixed-24.mmdb", "MaxMind-DB-test-ipv4-24.mmdb"} {
+		reader, err := Open("test-data/test-data/" + fileName)
+		if err != nil {
+			t.Fatalf("unexpected error while opening database: %v", err)
+		}
+
+		for address, expected := range networks {
+			addr := netip.MustParseAddr(address)
+			if reader.Metadata.IPVersion == 4 && addr.Unmap().Is6() {
+				if _, _, err := reader.LookupAddrNetwork(addr, new(interface{})); err == nil {
+					t.Errorf("expected an error looking up %s in %s", address, fileName)
+				}
+				continue
+			}
+			var result map[string]string
+			prefix, ok, err := reader.LookupAddrNetwork(addr, &result)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if prefix.String() != expected {
+				t.Errorf("expected %s for %s in %s, got %s", expected, address, fileName, prefix)
+			}
+			if ok != (result != nil) {
+				t.Errorf("expected ok to match the record for %s in %s", address, fileName)
+			}
+		}
+		reader.Close()
+	}
+}
+
+func TestLookupAddrOffsetAllocations(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	addr := netip.MustParseAddr("81.2.69.160")
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := reader.LookupAddrOffset(addr); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
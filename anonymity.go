@@ -0,0 +1,46 @@
+package maxminddb
+
+import "net"
+
+// RiskSummary combines the GeoIP2 Anonymous IP flags and the autonomous
+// system of an address.
+type RiskSummary struct {
+	IsAnonymous        bool   `maxminddb:"is_anonymous"`
+	IsAnonymousVPN     bool   `maxminddb:"is_anonymous_vpn"`
+	IsHostingProvider  bool   `maxminddb:"is_hosting_provider"`
+	IsPublicProxy      bool   `maxminddb:"is_public_proxy"`
+	IsResidentialProxy bool   `maxminddb:"is_residential_proxy"`
+	IsTorExitNode      bool   `maxminddb:"is_tor_exit_node"`
+	ASN                uint   `maxminddb:"autonomous_system_number"`
+	ASOrganization     string `maxminddb:"autonomous_system_organization"`
+}
+
+// AnonymityChecker looks up addresses in a GeoIP2 Anonymous IP database and
+// an ASN database (GeoLite2 ASN or GeoIP2 ISP) to build a RiskSummary. It is
+// safe for concurrent use.
+type AnonymityChecker struct {
+	anonymousIP *Reader
+	asn         *Reader
+}
+
+// NewAnonymityChecker returns an AnonymityChecker using the given databases.
+// Either may be nil, in which case the corresponding fields of the summary
+// are left unset.
+func NewAnonymityChecker(anonymousIP, asn *Reader) *AnonymityChecker {
+	return &AnonymityChecker{anonymousIP: anonymousIP, asn: asn}
+}
+
+// Check returns the RiskSummary for ipAddress. Each database is traversed
+// once and its record is decoded directly into the summary.
+func (c *AnonymityChecker) Check(ipAddress net.IP) (RiskSummary, error) {
+	var summary RiskSummary
+	for _, reader := range []*Reader{c.anonymousIP, c.asn} {
+		if reader == nil {
+			continue
+		}
+		if err := reader.Lookup(ipAddress, &summary); err != nil {
+			return RiskSummary{}, err
+		}
+	}
+	return summary, nil
+}
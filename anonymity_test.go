@@ -0,0 +1,50 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAnonymityChecker(t *testing.T) {
+	anonymousIP, err := Open("test-data/test-data/GeoIP2-Anonymous-IP-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer anonymousIP.Close()
+
+	isp, err := Open("test-data/test-data/GeoIP2-ISP-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer isp.Close()
+
+	checker := NewAnonymityChecker(anonymousIP, isp)
+
+	summaries := map[string]RiskSummary{
+		"81.2.69.1": {
+			IsAnonymous:       true,
+			IsAnonymousVPN:    true,
+			IsHostingProvider: true,
+			IsPublicProxy:     true,
+			IsTorExitNode:     true,
+		},
+		"1.2.0.1": {
+			IsAnonymous:    true,
+			IsAnonymousVPN: true,
+		},
+		"1.128.0.1": {
+			ASN:            1221,
+			ASOrganization: "Telstra Pty Ltd",
+		},
+		"10.0.0.1": {},
+	}
+	for address, expected := range summaries {
+		summary, err := checker.Check(net.ParseIP(address))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if summary != expected {
+			t.Errorf("unexpected summary for %s: %+v", address, summary)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package maxminddb
+
+import (
+	"errors"
+	"net"
+)
+
+// LookupOffsets is a batch version of LookupOffset: it stores the record
+// offset of ips[i] in offsets[i], which is NotFound if there is no record.
+// offsets must be at least as long as ips. The first error encountered is
+// returned.
+//
+// When built with the experimental maxminddb_batch tag, lookups in
+// databases with 24-bit records walk the search tree of several addresses
+// at once, so that the memory accesses of one address overlap with those of
+// the others.
+func (r *Reader) LookupOffsets(ips []net.IP, offsets []uintptr) error {
+	if len(offsets) < len(ips) {
+		return errors.New("maxminddb: offsets is shorter than ips")
+	}
+	return r.lookupOffsets(ips, offsets)
+}
+
+func (r *Reader) lookupOffsetsSerial(ips []net.IP, offsets []uintptr) error {
+	for i, ip := range ips {
+		offset, err := r.LookupOffset(ip)
+		if err != nil {
+			return err
+		}
+		offsets[i] = offset
+	}
+	return nil
+}
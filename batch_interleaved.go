@@ -0,0 +1,74 @@
+// +build maxminddb_batch
+
+package maxminddb
+
+import "net"
+
+// batchLanes is the number of addresses whose search tree walks are
+// interleaved.
+const batchLanes = 4
+
+func (r *Reader) lookupOffsets(ips []net.IP, offsets []uintptr) error {
+	if r.Metadata.RecordSize != 24 {
+		return r.lookupOffsetsSerial(ips, offsets)
+	}
+
+	nodeCount := r.Metadata.NodeCount
+	for start := 0; start < len(ips); start += batchLanes {
+		n := len(ips) - start
+		if n > batchLanes {
+			n = batchLanes
+		}
+
+		var (
+			addresses [batchLanes]net.IP
+			nodes     [batchLanes]uint
+			depths    [batchLanes]uint
+			bitCounts [batchLanes]uint
+		)
+		for lane := 0; lane < n; lane++ {
+			address, err := r.normalizeAddress(ips[start+lane])
+			if err != nil {
+				return err
+			}
+			addresses[lane] = address
+			bitCounts[lane] = uint(len(address) * 8)
+			if bitCounts[lane] == 32 {
+				nodes[lane] = r.ipv4Start
+			}
+		}
+
+		for active := true; active; {
+			active = false
+			for lane := 0; lane < n; lane++ {
+				i, node := depths[lane], nodes[lane]
+				if i >= bitCounts[lane] || node >= nodeCount {
+					continue
+				}
+				bit := uint(1) & (uint(addresses[lane][i>>3]) >> (7 - (i % 8)))
+				offset := node*6 + bit*3
+				b := r.buffer[offset : offset+3]
+				nodes[lane] = uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+				depths[lane]++
+				active = true
+			}
+		}
+
+		for lane := 0; lane < n; lane++ {
+			node := nodes[lane]
+			switch {
+			case node == nodeCount:
+				offsets[start+lane] = NotFound
+			case node > nodeCount:
+				offset, err := r.resolveDataPointer(node)
+				if err != nil {
+					return err
+				}
+				offsets[start+lane] = offset
+			default:
+				return newInvalidDatabaseError("invalid node in search tree")
+			}
+		}
+	}
+	return nil
+}
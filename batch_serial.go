@@ -0,0 +1,9 @@
+// +build !maxminddb_batch
+
+package maxminddb
+
+import "net"
+
+func (r *Reader) lookupOffsets(ips []net.IP, offsets []uintptr) error {
+	return r.lookupOffsetsSerial(ips, offsets)
+}
@@ -0,0 +1,85 @@
+package maxminddb
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"testing"
+)
+
+func TestLookupOffsets(t *testing.T) {
+	for _, recordSize := range []uint{24, 28, 32} {
+		for _, ipVersion := range []uint{4, 6} {
+			fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-ipv%d-%d.mmdb", ipVersion, recordSize)
+			reader, err := Open(fileName)
+			if err != nil {
+				t.Fatalf("unexpected error while opening database: %v", err)
+			}
+
+			ips := []net.IP{
+				net.ParseIP("1.1.1.1"),
+				net.ParseIP("1.1.1.3"),
+				net.ParseIP("1.1.1.32"),
+				net.ParseIP("10.0.0.1"),
+				net.ParseIP("1.1.1.16"),
+			}
+			if ipVersion == 6 {
+				ips = append(ips, net.ParseIP("::1:ffff:ffff"), net.ParseIP("::2:0:58"), net.ParseIP("2001::"))
+			}
+			offsets := make([]uintptr, len(ips))
+			if err := reader.LookupOffsets(ips, offsets); err != nil {
+				t.Fatal(err)
+			}
+
+			for i, ip := range ips {
+				expected, err := reader.LookupOffset(ip)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if offsets[i] != expected {
+					t.Errorf("%s: offset of %s is %d, expected %d", fileName, ip, offsets[i], expected)
+				}
+			}
+
+			if err := reader.LookupOffsets(ips, offsets[1:]); err == nil {
+				t.Error("expected an error for a short offsets slice")
+			}
+			reader.Close()
+		}
+	}
+}
+
+func BenchmarkLookupOffsets(b *testing.B) {
+	db, err := Open("GeoLite2-City.mmdb")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	// The addresses are generated up front so that the timings only cover
+	// the lookups. Both variants look up the same batches.
+	r := rand.New(rand.NewSource(0))
+	batches := make([][]net.IP, 256)
+	for i := range batches {
+		batches[i] = make([]net.IP, 64)
+		for j := range batches[i] {
+			batches[i][j] = randomIPv4Address(b, r)
+		}
+	}
+	offsets := make([]uintptr, len(batches[0]))
+
+	b.Run("Serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := db.lookupOffsetsSerial(batches[i%len(batches)], offsets); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := db.LookupOffsets(batches[i%len(batches)], offsets); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package maxminddb
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/netip"
+	"sort"
+	"strconv"
+)
+
+// NormalizeNetwork returns network with its host bits zeroed and, for IPv4
+// networks, a 4 byte address and mask. An error is returned if the mask is
+// not a valid prefix mask or does not match the address length.
+func NormalizeNetwork(network *net.IPNet) (*net.IPNet, error) {
+	prefix, err := NetworkToPrefix(network)
+	if err != nil {
+		return nil, err
+	}
+	return PrefixToNetwork(prefix), nil
+}
+
+// NetworkToPrefix converts network to a netip.Prefix with its host bits
+// zeroed. IPv4 networks, including those with a 16 byte address, are
+// converted to IPv4 prefixes. An error is returned if the mask is not a
+// valid prefix mask or does not match the address length.
+func NetworkToPrefix(network *net.IPNet) (netip.Prefix, error) {
+	if network == nil {
+		return netip.Prefix{}, fmt.Errorf("maxminddb: nil network")
+	}
+	ones, bits := network.Mask.Size()
+	if bits == 0 {
+		return netip.Prefix{}, fmt.Errorf("maxminddb: invalid mask %v", network.Mask)
+	}
+
+	addr, ok := netip.AddrFromSlice(network.IP)
+	if !ok {
+		return netip.Prefix{}, fmt.Errorf("maxminddb: invalid IP address %v", network.IP)
+	}
+	switch {
+	case bits == 32 && addr.Is4In6():
+		addr = addr.Unmap()
+	case bits == 128 && addr.Is4():
+		// A 4 byte address with a 16 byte mask, as net.ParseCIDR never
+		// returns but callers may build.
+		addr = netip.AddrFrom16(addr.As16())
+	}
+	if addr.BitLen() != bits {
+		return netip.Prefix{}, fmt.Errorf("maxminddb: mask %v does not match IP address %v", network.Mask, network.IP)
+	}
+	return addr.Prefix(ones)
+}
+
+// PrefixToNetwork converts prefix to a net.IPNet with its host bits zeroed.
+func PrefixToNetwork(prefix netip.Prefix) *net.IPNet {
+	prefix = prefix.Masked()
+	return &net.IPNet{
+		IP:   net.IP(prefix.Addr().AsSlice()),
+		Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+	}
+}
+
+// SplitPrefix returns the two halves of prefix. The bool is false if prefix
+// is invalid or a single address, which cannot be split.
+func SplitPrefix(prefix netip.Prefix) (netip.Prefix, netip.Prefix, bool) {
+	prefix = prefix.Masked()
+	if !prefix.IsValid() || prefix.Bits() == prefix.Addr().BitLen() {
+		return netip.Prefix{}, netip.Prefix{}, false
+	}
+
+	bits := prefix.Bits() + 1
+	lower := netip.PrefixFrom(prefix.Addr(), bits)
+	upper := netip.PrefixFrom(setBit(prefix.Addr(), prefix.Bits()), bits)
+	return lower, upper, true
+}
+
+// MergePrefixes returns the smallest sorted list of prefixes covering the
+// same addresses as prefixes: host bits are zeroed, IPv4-mapped IPv6
+// prefixes are converted to IPv4, prefixes contained in others are dropped
+// and sibling prefixes are merged into their parent. Invalid prefixes are
+// ignored.
+func MergePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	sorted := make([]netip.Prefix, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		if !prefix.IsValid() {
+			continue
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		sorted = append(sorted, prefix.Masked())
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if c := sorted[i].Addr().Compare(sorted[j].Addr()); c != 0 {
+			return c < 0
+		}
+		return sorted[i].Bits() < sorted[j].Bits()
+	})
+
+	var merged []netip.Prefix
+	for _, prefix := range sorted {
+		if n := len(merged); n > 0 && merged[n-1].Bits() <= prefix.Bits() && merged[n-1].Contains(prefix.Addr()) {
+			continue
+		}
+		merged = append(merged, prefix)
+
+		for len(merged) >= 2 {
+			lower, upper := merged[len(merged)-2], merged[len(merged)-1]
+			if lower.Bits() != upper.Bits() || lower.Bits() == 0 {
+				break
+			}
+			parent := netip.PrefixFrom(lower.Addr(), lower.Bits()-1).Masked()
+			if parent.Addr() != lower.Addr() || !parent.Contains(upper.Addr()) {
+				break
+			}
+			merged = append(merged[:len(merged)-2], parent)
+		}
+	}
+	return merged
+}
+
+// AddressCount returns the number of addresses in prefix, which exceeds
+// uint64 for IPv6 prefixes shorter than /64. It returns 0 for invalid
+// prefixes.
+func AddressCount(prefix netip.Prefix) *big.Int {
+	if !prefix.IsValid() {
+		return new(big.Int)
+	}
+	return new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits()))
+}
+
+// TotalAddressCount returns the number of distinct addresses covered by
+// prefixes. Overlapping prefixes are counted once, and IPv4-mapped IPv6
+// prefixes are counted as the IPv4 prefixes they map.
+func TotalAddressCount(prefixes []netip.Prefix) *big.Int {
+	total := new(big.Int)
+	for _, prefix := range MergePrefixes(prefixes) {
+		total.Add(total, AddressCount(prefix))
+	}
+	return total
+}
+
+var countScales = []struct {
+	value  float64
+	suffix string
+}{
+	{1e12, "T"},
+	{1e9, "B"},
+	{1e6, "M"},
+	{1e3, "K"},
+}
+
+// FormatAddressCount formats count for humans with three significant
+// digits, e.g., "256", "16.8M" or "4.29B". Counts of a quadrillion or more,
+// as IPv6 counts usually are, use the exponent notation, e.g., "1.84e+19".
+func FormatAddressCount(count *big.Int) string {
+	if count.IsInt64() && count.Int64() < 1000 {
+		return count.String()
+	}
+	value, _ := new(big.Float).SetInt(count).Float64()
+	// Rounding first picks the scale of the rounded value, so that 999950
+	// is "1M" rather than "1e+03K".
+	value, _ = strconv.ParseFloat(strconv.FormatFloat(value, 'g', 3, 64), 64)
+	if value >= 1e15 {
+		return strconv.FormatFloat(value, 'e', 2, 64)
+	}
+	for _, scale := range countScales {
+		if value >= scale.value {
+			return strconv.FormatFloat(value/scale.value, 'g', 3, 64) + scale.suffix
+		}
+	}
+	return count.String()
+}
+
+// setBit returns addr with the given bit, counted from the most significant
+// one, set.
+func setBit(addr netip.Addr, bit int) netip.Addr {
+	if addr.Is4() {
+		b := addr.As4()
+		b[bit/8] |= 0x80 >> uint(bit%8)
+		return netip.AddrFrom4(b)
+	}
+	b := addr.As16()
+	b[bit/8] |= 0x80 >> uint(bit%8)
+	return netip.AddrFrom16(b)
+}
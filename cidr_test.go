@@ -0,0 +1,147 @@
+package maxminddb
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeNetwork(t *testing.T) {
+	tests := []struct {
+		network  *net.IPNet
+		expected string
+	}{
+		{&net.IPNet{IP: net.ParseIP("1.2.3.4"), Mask: net.CIDRMask(24, 32)}, "1.2.3.0/24"},
+		{&net.IPNet{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(32, 128)}, "2001:db8::/32"},
+		{&net.IPNet{IP: net.IP{10, 0, 0, 1}, Mask: net.CIDRMask(8, 32)}, "10.0.0.0/8"},
+	}
+	for _, test := range tests {
+		network, err := NormalizeNetwork(test.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if network.String() != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, network)
+		}
+		if test.network.IP.To4() != nil && len(network.IP) != 4 {
+			t.Errorf("expected a 4 byte IP for %s, got %d bytes", network, len(network.IP))
+		}
+	}
+
+	for _, network := range []*net.IPNet{
+		nil,
+		{IP: net.ParseIP("1.2.3.4"), Mask: net.IPMask{255, 0, 255, 0}},
+		{IP: net.ParseIP("2001:db8::1"), Mask: net.CIDRMask(8, 32)},
+		{IP: net.IP{1, 2, 3}, Mask: net.CIDRMask(8, 32)},
+	} {
+		if _, err := NormalizeNetwork(network); err == nil {
+			t.Errorf("expected an error for %v", network)
+		}
+	}
+}
+
+func TestPrefixToNetwork(t *testing.T) {
+	network := PrefixToNetwork(netip.MustParsePrefix("192.0.2.77/24"))
+	if network.String() != "192.0.2.0/24" {
+		t.Errorf("unexpected network %s", network)
+	}
+
+	prefix, err := NetworkToPrefix(network)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prefix != netip.MustParsePrefix("192.0.2.0/24") {
+		t.Errorf("unexpected prefix %s", prefix)
+	}
+}
+
+func TestSplitPrefix(t *testing.T) {
+	lower, upper, ok := SplitPrefix(netip.MustParsePrefix("10.0.0.0/8"))
+	if !ok || lower.String() != "10.0.0.0/9" || upper.String() != "10.128.0.0/9" {
+		t.Errorf("unexpected split: %s, %s, %v", lower, upper, ok)
+	}
+	lower, upper, ok = SplitPrefix(netip.MustParsePrefix("2001:db8::/127"))
+	if !ok || lower.String() != "2001:db8::/128" || upper.String() != "2001:db8::1/128" {
+		t.Errorf("unexpected split: %s, %s, %v", lower, upper, ok)
+	}
+	if _, _, ok := SplitPrefix(netip.MustParsePrefix("10.0.0.1/32")); ok {
+		t.Error("expected a single address not to be split")
+	}
+}
+
+func TestMergePrefixes(t *testing.T) {
+	var prefixes []netip.Prefix
+	for _, p := range []string{
+		"10.0.1.0/24",
+		"10.0.0.0/24",
+		"10.0.0.128/25",
+		"10.0.2.0/23",
+		"192.0.2.5/24",
+		"::ffff:198.51.100.0/120",
+		"2001:db8::/33",
+		"2001:db8:8000::/33",
+	} {
+		prefixes = append(prefixes, netip.MustParsePrefix(p))
+	}
+
+	var merged []string
+	for _, prefix := range MergePrefixes(prefixes) {
+		merged = append(merged, prefix.String())
+	}
+	expected := []string{"10.0.0.0/22", "192.0.2.0/24", "198.51.100.0/24", "2001:db8::/32"}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected %v, got %v", expected, merged)
+	}
+}
+
+func TestAddressCount(t *testing.T) {
+	counts := map[string]string{
+		"192.0.2.0/24":   "256",
+		"0.0.0.0/0":      "4294967296",
+		"2001:db8::/32":  "79228162514264337593543950336",
+		"::/0":           "340282366920938463463374607431768211456",
+		"2001:db8::/128": "1",
+	}
+	for prefix, expected := range counts {
+		if count := AddressCount(netip.MustParsePrefix(prefix)); count.String() != expected {
+			t.Errorf("expected %s addresses in %s, got %v", expected, prefix, count)
+		}
+	}
+	if count := AddressCount(netip.Prefix{}); count.Sign() != 0 {
+		t.Errorf("expected no addresses in an invalid prefix, got %v", count)
+	}
+
+	total := TotalAddressCount([]netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.0.0.128/25"),
+		netip.MustParsePrefix("::ffff:10.0.1.0/120"),
+		netip.MustParsePrefix("2001:db8::/126"),
+	})
+	if total.String() != "516" {
+		t.Errorf("expected 516 addresses in total, got %v", total)
+	}
+}
+
+func TestFormatAddressCount(t *testing.T) {
+	tests := map[string]string{
+		"0":                    "0",
+		"256":                  "256",
+		"65536":                "65.5K",
+		"16777216":             "16.8M",
+		"4294967296":           "4.29B",
+		"1099511627776":        "1.1T",
+		"999499":               "999K",
+		"999950":               "1M",
+		"999999999":            "1B",
+		"999999999999999":      "1.00e+15",
+		"18446744073709551616": "1.84e+19",
+	}
+	for count, expected := range tests {
+		n, _ := new(big.Int).SetString(count, 10)
+		if s := FormatAddressCount(n); s != expected {
+			t.Errorf("expected %q for %s, got %q", expected, count, s)
+		}
+	}
+}
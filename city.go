@@ -0,0 +1,133 @@
+package maxminddb
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrNoTimeZone is returned by Location.TimeZone when the record has no time
+// zone.
+var ErrNoTimeZone = errors.New("maxminddb: record has no time zone")
+
+var (
+	timeZonesMu sync.RWMutex
+	timeZones   = map[string]*time.Location{}
+)
+
+// City is a record of the GeoIP2 and GeoLite2 City databases. The Country
+// databases have the same structure without the City, Location, Postal and
+// Subdivisions fields, so City may be used to decode them too.
+type City struct {
+	City               Place              `maxminddb:"city"`
+	Continent          Continent          `maxminddb:"continent"`
+	Country            Country            `maxminddb:"country"`
+	Location           Location           `maxminddb:"location"`
+	Postal             Postal             `maxminddb:"postal"`
+	RegisteredCountry  Country            `maxminddb:"registered_country"`
+	RepresentedCountry RepresentedCountry `maxminddb:"represented_country"`
+	Subdivisions       []Subdivision      `maxminddb:"subdivisions"`
+	Traits             Traits             `maxminddb:"traits"`
+}
+
+// Place is a named place of a City record.
+type Place struct {
+	GeoNameID uint              `maxminddb:"geoname_id"`
+	Names     map[string]string `maxminddb:"names"`
+}
+
+// Continent is the continent of a City record.
+type Continent struct {
+	Code      string            `maxminddb:"code"`
+	GeoNameID uint              `maxminddb:"geoname_id"`
+	Names     map[string]string `maxminddb:"names"`
+}
+
+// Country is a country of a City record.
+type Country struct {
+	GeoNameID         uint              `maxminddb:"geoname_id"`
+	IsInEuropeanUnion bool              `maxminddb:"is_in_european_union"`
+	IsoCode           string            `maxminddb:"iso_code"`
+	Names             map[string]string `maxminddb:"names"`
+}
+
+// RepresentedCountry is the country represented by the users of an IP
+// address, such as the country of a military base.
+type RepresentedCountry struct {
+	Country
+	Type string `maxminddb:"type"`
+}
+
+// Location is the approximate location of a City record.
+type Location struct {
+	AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+	Latitude       float64 `maxminddb:"latitude"`
+	Longitude      float64 `maxminddb:"longitude"`
+	MetroCode      uint    `maxminddb:"metro_code"`
+	TimeZoneName   string  `maxminddb:"time_zone"`
+}
+
+// TimeZone loads the IANA time zone of the location, e.g., to tell the local
+// time of a visitor with time.Now().In(loc). Loaded zones are cached, so
+// repeated calls for the same zone do not read the zone database again.
+func (l Location) TimeZone() (*time.Location, error) {
+	if l.TimeZoneName == "" {
+		return nil, ErrNoTimeZone
+	}
+
+	timeZonesMu.RLock()
+	loc, ok := timeZones[l.TimeZoneName]
+	timeZonesMu.RUnlock()
+	if ok {
+		return loc, nil
+	}
+
+	loc, err := time.LoadLocation(l.TimeZoneName)
+	if err != nil {
+		return nil, err
+	}
+
+	timeZonesMu.Lock()
+	timeZones[l.TimeZoneName] = loc
+	timeZonesMu.Unlock()
+	return loc, nil
+}
+
+// Postal is the postal code of a City record.
+type Postal struct {
+	Code string `maxminddb:"code"`
+}
+
+// Subdivision is a subdivision of a country, such as a state or a region.
+type Subdivision struct {
+	GeoNameID uint              `maxminddb:"geoname_id"`
+	IsoCode   string            `maxminddb:"iso_code"`
+	Names     map[string]string `maxminddb:"names"`
+}
+
+// Traits holds the traits of a City record.
+type Traits struct {
+	IsAnonymousProxy    bool `maxminddb:"is_anonymous_proxy"`
+	IsAnycast           bool `maxminddb:"is_anycast"`
+	IsSatelliteProvider bool `maxminddb:"is_satellite_provider"`
+}
+
+// MostSpecificSubdivision returns the smallest subdivision of the record,
+// e.g., the county rather than the state. The bool is false if the record
+// has no subdivisions.
+func (c *City) MostSpecificSubdivision() (Subdivision, bool) {
+	if len(c.Subdivisions) == 0 {
+		return Subdivision{}, false
+	}
+	return c.Subdivisions[len(c.Subdivisions)-1], true
+}
+
+// SubdivisionISOCodes returns the ISO codes of the subdivisions of the
+// record, from the largest to the smallest.
+func (c *City) SubdivisionISOCodes() []string {
+	codes := make([]string, 0, len(c.Subdivisions))
+	for _, subdivision := range c.Subdivisions {
+		codes = append(codes, subdivision.IsoCode)
+	}
+	return codes
+}
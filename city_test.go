@@ -0,0 +1,100 @@
+package maxminddb
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestCity(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var city City
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &city); err != nil {
+		t.Fatal(err)
+	}
+	if city.Country.IsoCode != "GB" {
+		t.Errorf("unexpected country: %q", city.Country.IsoCode)
+	}
+	if codes := city.SubdivisionISOCodes(); !reflect.DeepEqual(codes, []string{"ENG"}) {
+		t.Errorf("unexpected subdivision ISO codes: %v", codes)
+	}
+	subdivision, ok := city.MostSpecificSubdivision()
+	if !ok || subdivision.IsoCode != "ENG" {
+		t.Errorf("unexpected most specific subdivision: %+v, %v", subdivision, ok)
+	}
+
+	var empty City
+	if _, ok := empty.MostSpecificSubdivision(); ok {
+		t.Error("expected no subdivision for an empty record")
+	}
+	if codes := empty.SubdivisionISOCodes(); len(codes) != 0 {
+		t.Errorf("unexpected subdivision ISO codes: %v", codes)
+	}
+}
+
+func TestLocationTimeZone(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var city City
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &city); err != nil {
+		t.Fatal(err)
+	}
+	loc, err := city.Location.TimeZone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loc.String() != "Europe/London" {
+		t.Errorf("unexpected time zone: %v", loc)
+	}
+	cached, err := city.Location.TimeZone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != loc {
+		t.Error("expected the cached location to be returned")
+	}
+
+	if _, err := (Location{}).TimeZone(); err != ErrNoTimeZone {
+		t.Errorf("expected ErrNoTimeZone, got %v", err)
+	}
+	if _, err := (Location{TimeZoneName: "Nowhere/Invalid"}).TimeZone(); err == nil {
+		t.Error("expected an error for an unknown time zone")
+	}
+}
+
+func TestDecodeFixedSizeArray(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var short struct {
+		Array [2]uint `maxminddb:"array"`
+	}
+	if err := reader.Lookup(net.ParseIP("::1.1.1.0"), &short); err != nil {
+		t.Fatal(err)
+	}
+	if short.Array != [2]uint{1, 2} {
+		t.Errorf("unexpected array: %v", short.Array)
+	}
+
+	long := struct {
+		Array [5]uint `maxminddb:"array"`
+	}{Array: [5]uint{9, 9, 9, 9, 9}}
+	if err := reader.Lookup(net.ParseIP("::1.1.1.0"), &long); err != nil {
+		t.Fatal(err)
+	}
+	if long.Array != [5]uint{1, 2, 3} {
+		t.Errorf("unexpected array: %v", long.Array)
+	}
+}
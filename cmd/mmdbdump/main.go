@@ -0,0 +1,193 @@
+// Command mmdbdump inspects MaxMind DB files.
+//
+// Usage:
+//
+//	mmdbdump explain <ip> <database>
+//	mmdbdump dot [-depth n] [-network cidr] [-label path] <database>
+//
+// The explain command prints how the lookup of an IP address goes through
+// the search tree, the matched network, the raw bytes of the record and the
+// decoded record with the type of each value.
+//
+// The dot command prints the search tree as a Graphviz DOT graph, e.g., for
+// "mmdbdump dot -depth 8 GeoLite2-Country.mmdb | dot -Tsvg > tree.svg". Data
+// leaves are labeled with the value at the dot-separated label path, e.g.,
+// "country.iso_code", or with the offset of their record.
+package main
+
+import (
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+const usage = `usage: mmdbdump explain <ip> <database>
+       mmdbdump dot [-depth n] [-network cidr] [-label path] <database>`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "explain":
+		runExplain(os.Args[2:])
+	case "dot":
+		runDOT(os.Args[2:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func runExplain(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ip := net.ParseIP(args[0])
+	if ip == nil {
+		log.Fatalf("invalid IP address %q", args[0])
+	}
+	reader, err := maxminddb.Open(args[1])
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := explain(os.Stdout, reader, ip); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func runDOT(args []string) {
+	flags := flag.NewFlagSet("dot", flag.ExitOnError)
+	depth := flags.Int("depth", 0, "maximum depth of the graph, 0 for no limit")
+	network := flags.String("network", "", "only graph the subtree of this network")
+	label := flags.String("label", "", "dot-separated path of the value labeling data leaves")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	options := maxminddb.DOTOptions{MaxDepth: *depth}
+	if *network != "" {
+		_, ipNet, err := net.ParseCIDR(*network)
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.Network = ipNet
+	}
+	if *label != "" {
+		options.LabelPath = strings.Split(*label, ".")
+	}
+
+	reader, err := maxminddb.Open(flags.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer reader.Close()
+
+	if err := reader.WriteDOT(os.Stdout, options); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func explain(w io.Writer, reader *maxminddb.Reader, ip net.IP) error {
+	trace, err := reader.TraceLookup(ip)
+	if err != nil {
+		return err
+	}
+
+	metadata := reader.Metadata
+	fmt.Fprintf(w, "database: %s (IPv%d, %d-bit records, %d nodes)\n",
+		metadata.DatabaseType, metadata.IPVersion, metadata.RecordSize, metadata.NodeCount)
+	fmt.Fprintln(w, "traversal:")
+	for depth, step := range trace.Steps {
+		next := fmt.Sprintf("node %d", step.Next)
+		switch {
+		case step.Next == metadata.NodeCount:
+			next = "empty"
+		case step.Next > metadata.NodeCount:
+			next = fmt.Sprintf("data pointer %d", step.Next)
+		}
+		fmt.Fprintf(w, "  depth %3d: node %d, bit %d -> %s\n", depth, step.Node, step.Bit, next)
+	}
+	fmt.Fprintf(w, "network: %s\n", trace.Network)
+
+	if trace.Offset == maxminddb.NotFound {
+		fmt.Fprintln(w, "record: none")
+		return nil
+	}
+
+	raw, err := reader.RawValue(trace.Offset)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "record offset: %d\n", trace.Offset)
+	fmt.Fprintf(w, "raw record (%d bytes):\n%s", len(raw), hex.Dump(raw))
+
+	value, err := reader.DecodeTyped(trace.Offset)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "record:")
+	printValue(w, value, 1)
+	return nil
+}
+
+func printValue(w io.Writer, value maxminddb.TypedValue, depth int) {
+	indent := strings.Repeat("  ", depth)
+	switch v := value.Value.(type) {
+	case map[string]maxminddb.TypedValue:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := v[key]
+			if child.Kind == maxminddb.KindMap || child.Kind == maxminddb.KindSlice {
+				fmt.Fprintf(w, "%s%s (%s):\n", indent, key, child.Kind)
+				printValue(w, child, depth+1)
+			} else {
+				fmt.Fprintf(w, "%s%s (%s): %s\n", indent, key, child.Kind, formatScalar(child.Value))
+			}
+		}
+	case []maxminddb.TypedValue:
+		for i, child := range v {
+			if child.Kind == maxminddb.KindMap || child.Kind == maxminddb.KindSlice {
+				fmt.Fprintf(w, "%s[%d] (%s):\n", indent, i, child.Kind)
+				printValue(w, child, depth+1)
+			} else {
+				fmt.Fprintf(w, "%s[%d] (%s): %s\n", indent, i, child.Kind, formatScalar(child.Value))
+			}
+		}
+	default:
+		fmt.Fprintf(w, "%s(%s): %s\n", indent, value.Kind, formatScalar(value.Value))
+	}
+}
+
+func formatScalar(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []byte:
+		return hex.EncodeToString(v)
+	case *big.Int:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
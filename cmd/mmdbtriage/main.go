@@ -0,0 +1,55 @@
+// Command mmdbtriage checks a MaxMind DB input that makes the reader fail,
+// prints where the failure is in the structure of the database and
+// optionally writes a minimized input that fails the same way.
+//
+// Usage:
+//
+//	mmdbtriage [-minimize out.mmdb] crash.mmdb
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/oschwald/maxminddb-golang/triage"
+)
+
+func main() {
+	minimized := flag.String("minimize", "", "write a minimized input failing the same way to this file")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: mmdbtriage [-minimize out.mmdb] input.mmdb")
+		os.Exit(2)
+	}
+
+	data, err := ioutil.ReadFile(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	failure := triage.Check(data)
+	if failure == nil {
+		fmt.Println("no failure")
+		return
+	}
+	fmt.Println(failure)
+	if failure.Offset >= 0 {
+		fmt.Println(triage.Annotate(data, failure.Offset))
+	}
+
+	if *minimized == "" {
+		os.Exit(1)
+	}
+	data = triage.Minimize(data, triage.SameFailure(failure))
+	if err := ioutil.WriteFile(*minimized, data, 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("minimized to %d bytes: %s\n", len(data), *minimized)
+	if failure := triage.Check(data); failure != nil && failure.Offset >= 0 {
+		fmt.Println(triage.Annotate(data, failure.Offset))
+	}
+	os.Exit(1)
+}
@@ -0,0 +1,161 @@
+package maxminddb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Config describes how to open a database, so that services can set up a
+// Reader from environment variables or from a JSON or YAML file instead of
+// code. The field names used in files are given by the json and yaml tags.
+type Config struct {
+	// Path is the path of the MaxMind DB file. It is required.
+	Path string `json:"path" yaml:"path"`
+
+	// InMemory reads the database into memory, as OpenInMemory does,
+	// instead of mapping it.
+	InMemory bool `json:"in_memory" yaml:"in_memory"`
+
+	// HugePages asks for huge pages when InMemory is set.
+	HugePages bool `json:"huge_pages" yaml:"huge_pages"`
+
+	// OmittedPaths are the record paths skipped when decoding, as given to
+	// NewDecodeProfile.
+	OmittedPaths []string `json:"omitted_paths" yaml:"omitted_paths"`
+
+	// ZonePolicy is "strip", the default, "reject" or "link-local".
+	ZonePolicy ZonePolicy `json:"zone_policy" yaml:"zone_policy"`
+
+	// MaxDecodeDepth, if positive, is given to WithMaxDecodeDepth.
+	MaxDecodeDepth int `json:"max_decode_depth" yaml:"max_decode_depth"`
+
+	LenientMetadata    bool `json:"lenient_metadata" yaml:"lenient_metadata"`
+	MappedIPv4Fallback bool `json:"mapped_ipv4_fallback" yaml:"mapped_ipv4_fallback"`
+	ProfilerLabels     bool `json:"profiler_labels" yaml:"profiler_labels"`
+	ValidateUTF8       bool `json:"validate_utf8" yaml:"validate_utf8"`
+}
+
+// ConfigFromEnv returns the Config set by the environment variables named
+// after the json tags of its fields, upper-cased and prefixed with
+// "MAXMINDDB_", e.g., MAXMINDDB_PATH or MAXMINDDB_IN_MEMORY. Booleans are
+// parsed by strconv.ParseBool, MAXMINDDB_MAX_DECODE_DEPTH by strconv.Atoi
+// and MAXMINDDB_OMITTED_PATHS is a comma-separated list. Unset variables
+// leave the zero value.
+func ConfigFromEnv() (Config, error) {
+	var config Config
+	config.Path = os.Getenv("MAXMINDDB_PATH")
+	if paths := os.Getenv("MAXMINDDB_OMITTED_PATHS"); paths != "" {
+		for _, path := range strings.Split(paths, ",") {
+			if path = strings.TrimSpace(path); path != "" {
+				config.OmittedPaths = append(config.OmittedPaths, path)
+			}
+		}
+	}
+	if policy := os.Getenv("MAXMINDDB_ZONE_POLICY"); policy != "" {
+		if err := config.ZonePolicy.UnmarshalText([]byte(policy)); err != nil {
+			return Config{}, err
+		}
+	}
+	if depth := os.Getenv("MAXMINDDB_MAX_DECODE_DEPTH"); depth != "" {
+		n, err := strconv.Atoi(depth)
+		if err != nil {
+			return Config{}, fmt.Errorf("maxminddb: invalid MAXMINDDB_MAX_DECODE_DEPTH value %q", depth)
+		}
+		config.MaxDecodeDepth = n
+	}
+
+	for name, field := range map[string]*bool{
+		"MAXMINDDB_IN_MEMORY":            &config.InMemory,
+		"MAXMINDDB_HUGE_PAGES":           &config.HugePages,
+		"MAXMINDDB_LENIENT_METADATA":     &config.LenientMetadata,
+		"MAXMINDDB_MAPPED_IPV4_FALLBACK": &config.MappedIPv4Fallback,
+		"MAXMINDDB_PROFILER_LABELS":      &config.ProfilerLabels,
+		"MAXMINDDB_VALIDATE_UTF8":        &config.ValidateUTF8,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("maxminddb: invalid %s value %q", name, value)
+		}
+		*field = b
+	}
+	return config, nil
+}
+
+// Options returns the ReaderOptions set by the config.
+func (c Config) Options() []ReaderOption {
+	var options []ReaderOption
+	if len(c.OmittedPaths) > 0 {
+		options = append(options, WithDecodeProfile(NewDecodeProfile(c.OmittedPaths...)))
+	}
+	if c.ZonePolicy != ZoneStrip {
+		options = append(options, WithZonePolicy(c.ZonePolicy))
+	}
+	if c.MaxDecodeDepth > 0 {
+		options = append(options, WithMaxDecodeDepth(c.MaxDecodeDepth))
+	}
+	if c.LenientMetadata {
+		options = append(options, WithLenientMetadata())
+	}
+	if c.MappedIPv4Fallback {
+		options = append(options, WithMappedIPv4Fallback())
+	}
+	if c.ProfilerLabels {
+		options = append(options, WithProfilerLabels())
+	}
+	if c.ValidateUTF8 {
+		options = append(options, WithUTF8Validation())
+	}
+	return options
+}
+
+// NewFromConfig opens the database described by config. Options given in
+// addition, such as WithDecodeStats, are applied after those of the config.
+func NewFromConfig(config Config, options ...ReaderOption) (*Reader, error) {
+	if config.Path == "" {
+		return nil, errors.New("maxminddb: the config has no database path")
+	}
+	options = append(config.Options(), options...)
+	if config.InMemory {
+		return OpenInMemory(config.Path, config.HugePages, options...)
+	}
+	return Open(config.Path, options...)
+}
+
+var zonePolicyNames = [...]string{
+	ZoneStrip:     "strip",
+	ZoneReject:    "reject",
+	ZoneLinkLocal: "link-local",
+}
+
+func (p ZonePolicy) String() string {
+	if p < 0 || int(p) >= len(zonePolicyNames) {
+		return fmt.Sprintf("ZonePolicy(%d)", int(p))
+	}
+	return zonePolicyNames[p]
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (p ZonePolicy) MarshalText() ([]byte, error) {
+	if p < 0 || int(p) >= len(zonePolicyNames) {
+		return nil, fmt.Errorf("maxminddb: unknown zone policy %d", int(p))
+	}
+	return []byte(zonePolicyNames[p]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (p *ZonePolicy) UnmarshalText(text []byte) error {
+	for policy, name := range zonePolicyNames {
+		if string(text) == name {
+			*p = ZonePolicy(policy)
+			return nil
+		}
+	}
+	return fmt.Errorf("maxminddb: unknown zone policy %q", text)
+}
@@ -0,0 +1,84 @@
+package maxminddb
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestConfigJSON(t *testing.T) {
+	var config Config
+	err := json.Unmarshal([]byte(`{
+		"path": "test-data/test-data/GeoIP2-City-Test.mmdb",
+		"in_memory": true,
+		"omitted_paths": ["city.names"],
+		"zone_policy": "reject"
+	}`), &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{
+		Path:         "test-data/test-data/GeoIP2-City-Test.mmdb",
+		InMemory:     true,
+		OmittedPaths: []string{"city.names"},
+		ZonePolicy:   ZoneReject,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config)
+	}
+
+	reader, err := NewFromConfig(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var city City
+	if err := reader.LookupString("81.2.69.160", &city); err != nil {
+		t.Fatal(err)
+	}
+	if city.Country.IsoCode != "GB" || city.City.Names != nil {
+		t.Errorf("unexpected record: %+v", city)
+	}
+	if err := reader.LookupString("fe80::1%eth0", &city); err == nil {
+		t.Error("expected the zoned address to be rejected")
+	}
+
+	if err := json.Unmarshal([]byte(`{"zone_policy": "drop"}`), &config); err == nil {
+		t.Error("expected an error for an unknown zone policy")
+	}
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("MAXMINDDB_PATH", "test-data/test-data/GeoIP2-City-Test.mmdb")
+	t.Setenv("MAXMINDDB_OMITTED_PATHS", "city.names, location")
+	t.Setenv("MAXMINDDB_ZONE_POLICY", "link-local")
+	t.Setenv("MAXMINDDB_LENIENT_METADATA", "true")
+	t.Setenv("MAXMINDDB_MAX_DECODE_DEPTH", "16")
+	t.Setenv("MAXMINDDB_VALIDATE_UTF8", "1")
+
+	config, err := ConfigFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := Config{
+		Path:            "test-data/test-data/GeoIP2-City-Test.mmdb",
+		OmittedPaths:    []string{"city.names", "location"},
+		ZonePolicy:      ZoneLinkLocal,
+		MaxDecodeDepth:  16,
+		LenientMetadata: true,
+		ValidateUTF8:    true,
+	}
+	if !reflect.DeepEqual(config, expected) {
+		t.Errorf("expected %+v, got %+v", expected, config)
+	}
+
+	t.Setenv("MAXMINDDB_IN_MEMORY", "maybe")
+	if _, err := ConfigFromEnv(); err == nil {
+		t.Error("expected an error for an invalid boolean")
+	}
+
+	if _, err := NewFromConfig(Config{}); err == nil {
+		t.Error("expected an error for a config without a path")
+	}
+}
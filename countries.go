@@ -0,0 +1,307 @@
+package maxminddb
+
+// countryInfo holds static facts about a country that the Country and ASN
+// databases do not carry.
+type countryInfo struct {
+	continentCode string
+	europeanUnion bool
+	callingCode   string
+}
+
+// countries maps ISO 3166-1 alpha-2 codes, plus XK for Kosovo as used by
+// MaxMind, to their static facts. Continent codes follow GeoNames, as in the
+// databases.
+var countries = map[string]countryInfo{
+	"AD": {"EU", false, "376"},
+	"AE": {"AS", false, "971"},
+	"AF": {"AS", false, "93"},
+	"AG": {"NA", false, "1"},
+	"AI": {"NA", false, "1"},
+	"AL": {"EU", false, "355"},
+	"AM": {"AS", false, "374"},
+	"AO": {"AF", false, "244"},
+	"AQ": {"AN", false, "672"},
+	"AR": {"SA", false, "54"},
+	"AS": {"OC", false, "1"},
+	"AT": {"EU", true, "43"},
+	"AU": {"OC", false, "61"},
+	"AW": {"NA", false, "297"},
+	"AX": {"EU", false, "358"},
+	"AZ": {"AS", false, "994"},
+	"BA": {"EU", false, "387"},
+	"BB": {"NA", false, "1"},
+	"BD": {"AS", false, "880"},
+	"BE": {"EU", true, "32"},
+	"BF": {"AF", false, "226"},
+	"BG": {"EU", true, "359"},
+	"BH": {"AS", false, "973"},
+	"BI": {"AF", false, "257"},
+	"BJ": {"AF", false, "229"},
+	"BL": {"NA", false, "590"},
+	"BM": {"NA", false, "1"},
+	"BN": {"AS", false, "673"},
+	"BO": {"SA", false, "591"},
+	"BQ": {"NA", false, "599"},
+	"BR": {"SA", false, "55"},
+	"BS": {"NA", false, "1"},
+	"BT": {"AS", false, "975"},
+	"BV": {"AN", false, "47"},
+	"BW": {"AF", false, "267"},
+	"BY": {"EU", false, "375"},
+	"BZ": {"NA", false, "501"},
+	"CA": {"NA", false, "1"},
+	"CC": {"AS", false, "61"},
+	"CD": {"AF", false, "243"},
+	"CF": {"AF", false, "236"},
+	"CG": {"AF", false, "242"},
+	"CH": {"EU", false, "41"},
+	"CI": {"AF", false, "225"},
+	"CK": {"OC", false, "682"},
+	"CL": {"SA", false, "56"},
+	"CM": {"AF", false, "237"},
+	"CN": {"AS", false, "86"},
+	"CO": {"SA", false, "57"},
+	"CR": {"NA", false, "506"},
+	"CU": {"NA", false, "53"},
+	"CV": {"AF", false, "238"},
+	"CW": {"NA", false, "599"},
+	"CX": {"AS", false, "61"},
+	"CY": {"EU", true, "357"},
+	"CZ": {"EU", true, "420"},
+	"DE": {"EU", true, "49"},
+	"DJ": {"AF", false, "253"},
+	"DK": {"EU", true, "45"},
+	"DM": {"NA", false, "1"},
+	"DO": {"NA", false, "1"},
+	"DZ": {"AF", false, "213"},
+	"EC": {"SA", false, "593"},
+	"EE": {"EU", true, "372"},
+	"EG": {"AF", false, "20"},
+	"EH": {"AF", false, "212"},
+	"ER": {"AF", false, "291"},
+	"ES": {"EU", true, "34"},
+	"ET": {"AF", false, "251"},
+	"FI": {"EU", true, "358"},
+	"FJ": {"OC", false, "679"},
+	"FK": {"SA", false, "500"},
+	"FM": {"OC", false, "691"},
+	"FO": {"EU", false, "298"},
+	"FR": {"EU", true, "33"},
+	"GA": {"AF", false, "241"},
+	"GB": {"EU", false, "44"},
+	"GD": {"NA", false, "1"},
+	"GE": {"AS", false, "995"},
+	"GF": {"SA", false, "594"},
+	"GG": {"EU", false, "44"},
+	"GH": {"AF", false, "233"},
+	"GI": {"EU", false, "350"},
+	"GL": {"NA", false, "299"},
+	"GM": {"AF", false, "220"},
+	"GN": {"AF", false, "224"},
+	"GP": {"NA", false, "590"},
+	"GQ": {"AF", false, "240"},
+	"GR": {"EU", true, "30"},
+	"GS": {"AN", false, "500"},
+	"GT": {"NA", false, "502"},
+	"GU": {"OC", false, "1"},
+	"GW": {"AF", false, "245"},
+	"GY": {"SA", false, "592"},
+	"HK": {"AS", false, "852"},
+	"HM": {"AN", false, "672"},
+	"HN": {"NA", false, "504"},
+	"HR": {"EU", true, "385"},
+	"HT": {"NA", false, "509"},
+	"HU": {"EU", true, "36"},
+	"ID": {"AS", false, "62"},
+	"IE": {"EU", true, "353"},
+	"IL": {"AS", false, "972"},
+	"IM": {"EU", false, "44"},
+	"IN": {"AS", false, "91"},
+	"IO": {"AS", false, "246"},
+	"IQ": {"AS", false, "964"},
+	"IR": {"AS", false, "98"},
+	"IS": {"EU", false, "354"},
+	"IT": {"EU", true, "39"},
+	"JE": {"EU", false, "44"},
+	"JM": {"NA", false, "1"},
+	"JO": {"AS", false, "962"},
+	"JP": {"AS", false, "81"},
+	"KE": {"AF", false, "254"},
+	"KG": {"AS", false, "996"},
+	"KH": {"AS", false, "855"},
+	"KI": {"OC", false, "686"},
+	"KM": {"AF", false, "269"},
+	"KN": {"NA", false, "1"},
+	"KP": {"AS", false, "850"},
+	"KR": {"AS", false, "82"},
+	"KW": {"AS", false, "965"},
+	"KY": {"NA", false, "1"},
+	"KZ": {"AS", false, "7"},
+	"LA": {"AS", false, "856"},
+	"LB": {"AS", false, "961"},
+	"LC": {"NA", false, "1"},
+	"LI": {"EU", false, "423"},
+	"LK": {"AS", false, "94"},
+	"LR": {"AF", false, "231"},
+	"LS": {"AF", false, "266"},
+	"LT": {"EU", true, "370"},
+	"LU": {"EU", true, "352"},
+	"LV": {"EU", true, "371"},
+	"LY": {"AF", false, "218"},
+	"MA": {"AF", false, "212"},
+	"MC": {"EU", false, "377"},
+	"MD": {"EU", false, "373"},
+	"ME": {"EU", false, "382"},
+	"MF": {"NA", false, "590"},
+	"MG": {"AF", false, "261"},
+	"MH": {"OC", false, "692"},
+	"MK": {"EU", false, "389"},
+	"ML": {"AF", false, "223"},
+	"MM": {"AS", false, "95"},
+	"MN": {"AS", false, "976"},
+	"MO": {"AS", false, "853"},
+	"MP": {"OC", false, "1"},
+	"MQ": {"NA", false, "596"},
+	"MR": {"AF", false, "222"},
+	"MS": {"NA", false, "1"},
+	"MT": {"EU", true, "356"},
+	"MU": {"AF", false, "230"},
+	"MV": {"AS", false, "960"},
+	"MW": {"AF", false, "265"},
+	"MX": {"NA", false, "52"},
+	"MY": {"AS", false, "60"},
+	"MZ": {"AF", false, "258"},
+	"NA": {"AF", false, "264"},
+	"NC": {"OC", false, "687"},
+	"NE": {"AF", false, "227"},
+	"NF": {"OC", false, "672"},
+	"NG": {"AF", false, "234"},
+	"NI": {"NA", false, "505"},
+	"NL": {"EU", true, "31"},
+	"NO": {"EU", false, "47"},
+	"NP": {"AS", false, "977"},
+	"NR": {"OC", false, "674"},
+	"NU": {"OC", false, "683"},
+	"NZ": {"OC", false, "64"},
+	"OM": {"AS", false, "968"},
+	"PA": {"NA", false, "507"},
+	"PE": {"SA", false, "51"},
+	"PF": {"OC", false, "689"},
+	"PG": {"OC", false, "675"},
+	"PH": {"AS", false, "63"},
+	"PK": {"AS", false, "92"},
+	"PL": {"EU", true, "48"},
+	"PM": {"NA", false, "508"},
+	"PN": {"OC", false, "64"},
+	"PR": {"NA", false, "1"},
+	"PS": {"AS", false, "970"},
+	"PT": {"EU", true, "351"},
+	"PW": {"OC", false, "680"},
+	"PY": {"SA", false, "595"},
+	"QA": {"AS", false, "974"},
+	"RE": {"AF", false, "262"},
+	"RO": {"EU", true, "40"},
+	"RS": {"EU", false, "381"},
+	"RU": {"EU", false, "7"},
+	"RW": {"AF", false, "250"},
+	"SA": {"AS", false, "966"},
+	"SB": {"OC", false, "677"},
+	"SC": {"AF", false, "248"},
+	"SD": {"AF", false, "249"},
+	"SE": {"EU", true, "46"},
+	"SG": {"AS", false, "65"},
+	"SH": {"AF", false, "290"},
+	"SI": {"EU", true, "386"},
+	"SJ": {"EU", false, "47"},
+	"SK": {"EU", true, "421"},
+	"SL": {"AF", false, "232"},
+	"SM": {"EU", false, "378"},
+	"SN": {"AF", false, "221"},
+	"SO": {"AF", false, "252"},
+	"SR": {"SA", false, "597"},
+	"SS": {"AF", false, "211"},
+	"ST": {"AF", false, "239"},
+	"SV": {"NA", false, "503"},
+	"SX": {"NA", false, "1"},
+	"SY": {"AS", false, "963"},
+	"SZ": {"AF", false, "268"},
+	"TC": {"NA", false, "1"},
+	"TD": {"AF", false, "235"},
+	"TF": {"AN", false, "262"},
+	"TG": {"AF", false, "228"},
+	"TH": {"AS", false, "66"},
+	"TJ": {"AS", false, "992"},
+	"TK": {"OC", false, "690"},
+	"TL": {"OC", false, "670"},
+	"TM": {"AS", false, "993"},
+	"TN": {"AF", false, "216"},
+	"TO": {"OC", false, "676"},
+	"TR": {"AS", false, "90"},
+	"TT": {"NA", false, "1"},
+	"TV": {"OC", false, "688"},
+	"TW": {"AS", false, "886"},
+	"TZ": {"AF", false, "255"},
+	"UA": {"EU", false, "380"},
+	"UG": {"AF", false, "256"},
+	"UM": {"OC", false, "1"},
+	"US": {"NA", false, "1"},
+	"UY": {"SA", false, "598"},
+	"UZ": {"AS", false, "998"},
+	"VA": {"EU", false, "39"},
+	"VC": {"NA", false, "1"},
+	"VE": {"SA", false, "58"},
+	"VG": {"NA", false, "1"},
+	"VI": {"NA", false, "1"},
+	"VN": {"AS", false, "84"},
+	"VU": {"OC", false, "678"},
+	"WF": {"OC", false, "681"},
+	"WS": {"OC", false, "685"},
+	"XK": {"EU", false, "383"},
+	"YE": {"AS", false, "967"},
+	"YT": {"AF", false, "262"},
+	"ZA": {"AF", false, "27"},
+	"ZM": {"AF", false, "260"},
+	"ZW": {"AF", false, "263"},
+}
+
+// CountryContinentCode returns the two-letter continent code, e.g., "EU", of
+// the country with the given ISO code.
+func CountryContinentCode(isoCode string) (string, bool) {
+	info, ok := countries[isoCode]
+	return info.continentCode, ok
+}
+
+// CountryInEuropeanUnion reports whether the country with the given ISO code
+// is a member state of the European Union.
+func CountryInEuropeanUnion(isoCode string) bool {
+	return countries[isoCode].europeanUnion
+}
+
+// CountryCallingCode returns the international calling code of the country
+// with the given ISO code, without the leading "+". Members of the North
+// American Numbering Plan all return "1".
+func CountryCallingCode(isoCode string) (string, bool) {
+	info, ok := countries[isoCode]
+	return info.callingCode, ok
+}
+
+// ContinentCode returns the continent code of the country from the static
+// table, or "" if it is unknown. This is useful for records, such as the
+// registered country, that do not come with a continent.
+func (c Country) ContinentCode() string {
+	code, _ := CountryContinentCode(c.IsoCode)
+	return code
+}
+
+// InEuropeanUnion reports whether the country is a member state of the
+// European Union, according to either the record or the static table.
+func (c Country) InEuropeanUnion() bool {
+	return c.IsInEuropeanUnion || CountryInEuropeanUnion(c.IsoCode)
+}
+
+// CallingCode returns the international calling code of the country, without
+// the leading "+", or "" if it is unknown.
+func (c Country) CallingCode() string {
+	code, _ := CountryCallingCode(c.IsoCode)
+	return code
+}
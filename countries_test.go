@@ -0,0 +1,51 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCountryTables(t *testing.T) {
+	if code, ok := CountryContinentCode("DE"); !ok || code != "EU" {
+		t.Errorf("unexpected continent for DE: %q, %v", code, ok)
+	}
+	if _, ok := CountryContinentCode("ZZ"); ok {
+		t.Error("expected no continent for an unknown country")
+	}
+	if !CountryInEuropeanUnion("FR") || CountryInEuropeanUnion("GB") || CountryInEuropeanUnion("") {
+		t.Error("unexpected European Union membership")
+	}
+	if code, ok := CountryCallingCode("CA"); !ok || code != "1" {
+		t.Errorf("unexpected calling code for CA: %q, %v", code, ok)
+	}
+
+	for isoCode, info := range countries {
+		if len(isoCode) != 2 || len(info.continentCode) != 2 || info.callingCode == "" {
+			t.Errorf("invalid entry for %q: %+v", isoCode, info)
+		}
+	}
+}
+
+func TestCountryAccessors(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	for _, address := range []string{"81.2.69.160", "2001:218::1"} {
+		var city City
+		if err := reader.Lookup(net.ParseIP(address), &city); err != nil {
+			t.Fatal(err)
+		}
+		if code := city.Country.ContinentCode(); code != city.Continent.Code {
+			t.Errorf("expected continent %q for %s, got %q", city.Continent.Code, address, code)
+		}
+		if city.Country.InEuropeanUnion() != city.Country.IsInEuropeanUnion {
+			t.Errorf("unexpected European Union membership for %s", address)
+		}
+		if city.Country.CallingCode() == "" {
+			t.Errorf("expected a calling code for %s", address)
+		}
+	}
+}
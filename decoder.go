@@ -2,66 +2,207 @@ package maxminddb
 
 import (
 	"encoding/binary"
+	"fmt"
 	"math"
 	"math/big"
+	"net/netip"
 	"reflect"
+	"strings"
 	"sync"
+	"unicode/utf8"
 )
 
 type decoder struct {
 	buffer []byte
-}
-
-type dataType int
 
+	// profile holds the paths to skip while decoding, if any. node is the
+	// position in profile of the value currently being decoded.
+	profile *DecodeProfile
+	node    *DecodeProfile
+
+	// stats collects schema mismatches, if set. path holds the keys leading
+	// to the value currently being decoded.
+	stats *DecodeStats
+	path  []string
+
+	// reuse makes the decoder clear and refill the maps and slices already
+	// in the result instead of allocating new ones.
+	reuse bool
+
+	// pointers is the number of pointers followed to reach the value
+	// currently being decoded, and depth the number of maps and arrays
+	// containing it. maxDepth limits depth, to defaultMaxDepth if it is not
+	// positive.
+	pointers int
+	depth    int
+	maxDepth int
+
+	// validateUTF8 makes the decoder reject strings and map keys that are
+	// not valid UTF-8.
+	validateUTF8 bool
+}
+
+// defaultMaxDepth is the default limit of the number of maps and arrays
+// containing a decoded value. It is the limit of libmaxminddb, far beyond
+// the nesting of real databases.
+const defaultMaxDepth = 512
+
+// maxPointerDepth is the most pointers followed to reach a value. Since a
+// pointer cannot point to another pointer, valid data has at most one
+// pointer per level of nesting, and libmaxminddb rejects data nested more
+// than 512 levels deep. More pointers than that form a cycle.
+const maxPointerDepth = 512
+
+// Kind is the type of a value in the data section of a MaxMind DB file. The
+// numeric value of each Kind is its type number in the MaxMind DB format.
+type Kind int
+
+// The data types defined by the MaxMind DB format.
 const (
-	_Extended dataType = iota
-	_Pointer
-	_String
-	_Float64
-	_Bytes
-	_Uint16
-	_Uint32
-	_Map
-	_Int32
-	_Uint64
-	_Uint128
-	_Slice
-	_Container
-	_Marker
-	_Bool
-	_Float32
+	// KindExtended (0) marks a control byte whose type is stored in the
+	// following byte. It never describes a value.
+	KindExtended Kind = iota
+	// KindPointer (1) is a pointer to another value in the data section.
+	KindPointer
+	// KindString (2) is a UTF-8 string.
+	KindString
+	// KindFloat64 (3) is an IEEE 754 double.
+	KindFloat64
+	// KindBytes (4) is a sequence of bytes.
+	KindBytes
+	// KindUint16 (5) is an unsigned 16-bit integer.
+	KindUint16
+	// KindUint32 (6) is an unsigned 32-bit integer.
+	KindUint32
+	// KindMap (7) is a map from strings to values.
+	KindMap
+	// KindInt32 (8) is a signed 32-bit integer.
+	KindInt32
+	// KindUint64 (9) is an unsigned 64-bit integer.
+	KindUint64
+	// KindUint128 (10) is an unsigned 128-bit integer.
+	KindUint128
+	// KindSlice (11) is an array of values.
+	KindSlice
+	// KindContainer (12) is reserved for data cache containers.
+	KindContainer
+	// KindEndMarker (13) marks the end of the data section.
+	KindEndMarker
+	// KindBool (14) is a boolean.
+	KindBool
+	// KindFloat32 (15) is an IEEE 754 float.
+	KindFloat32
 )
 
+var kindNames = [...]string{
+	KindExtended:  "extended",
+	KindPointer:   "pointer",
+	KindString:    "utf8_string",
+	KindFloat64:   "double",
+	KindBytes:     "bytes",
+	KindUint16:    "uint16",
+	KindUint32:    "uint32",
+	KindMap:       "map",
+	KindInt32:     "int32",
+	KindUint64:    "uint64",
+	KindUint128:   "uint128",
+	KindSlice:     "array",
+	KindContainer: "container",
+	KindEndMarker: "end_marker",
+	KindBool:      "boolean",
+	KindFloat32:   "float",
+}
+
+// String returns the name of the type in the MaxMind DB specification, such
+// as "utf8_string" or "uint32".
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
 func (d *decoder) decode(offset uint, result reflect.Value) (uint, error) {
-	typeNum, size, newOffset := d.decodeCtrlData(offset)
+	newOffset, err := d.decodeValue(offset, result)
+	if err != nil {
+		return 0, d.locate(err, offset)
+	}
+	return newOffset, nil
+}
+
+func (d *decoder) decodeValue(offset uint, result reflect.Value) (uint, error) {
+	typeNum, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		return 0, err
+	}
 
-	if typeNum != _Pointer && result.Kind() == reflect.Uintptr {
+	if typeNum != KindPointer && result.Kind() == reflect.Uintptr && !d.node.restricts() {
 		result.Set(reflect.ValueOf(uintptr(offset)))
-		return d.nextValueOffset(offset, 1), nil
+		return d.nextValueOffset(offset, 1)
+	}
+	if u, ok := unmarshaler(result); ok {
+		// The Unmarshaler gets a copy so that d, which the callers of Decode
+		// keep on the stack, does not escape.
+		dc := *d
+		if err := u.UnmarshalMaxMindDB(&Decoder{d: &dc, offset: offset}); err != nil {
+			return 0, err
+		}
+		return d.nextValueOffset(offset, 1)
+	}
+	if typeNum == KindMap || typeNum == KindSlice {
+		if err := d.enter(); err != nil {
+			return 0, err
+		}
+		newOffset, err = d.decodeFromType(typeNum, size, newOffset, result)
+		d.depth--
+		return newOffset, err
 	}
 	return d.decodeFromType(typeNum, size, newOffset, result)
 }
 
-func (d *decoder) decodeCtrlData(offset uint) (dataType, uint, uint) {
+// enter increments the nesting depth before decoding the contents of a map
+// or an array, or returns an error if they would be nested too deeply.
+// The recursion of the decoder is bounded by the depth, so hostile data
+// cannot exhaust the stack.
+func (d *decoder) enter() error {
+	maxDepth := d.maxDepth
+	if maxDepth <= 0 {
+		maxDepth = defaultMaxDepth
+	}
+	if d.depth >= maxDepth {
+		return newInvalidDatabaseError("maps and arrays are nested more than %d levels deep", maxDepth)
+	}
+	d.depth++
+	return nil
+}
+
+func (d *decoder) decodeCtrlData(offset uint) (Kind, uint, uint, error) {
 	newOffset := offset + 1
+	if offset >= uint(len(d.buffer)) {
+		return 0, 0, 0, newInvalidDatabaseError("unexpected end of database at offset %d", offset)
+	}
 	ctrlByte := d.buffer[offset]
 
-	typeNum := dataType(ctrlByte >> 5)
-	if typeNum == _Extended {
-		typeNum = dataType(d.buffer[newOffset] + 7)
+	typeNum := Kind(ctrlByte >> 5)
+	if typeNum == KindExtended {
+		if newOffset >= uint(len(d.buffer)) {
+			return 0, 0, 0, newInvalidDatabaseError("unexpected end of database at offset %d", newOffset)
+		}
+		typeNum = Kind(d.buffer[newOffset]) + 7
 		newOffset++
 	}
 
-	var size uint
-	size, newOffset = d.sizeFromCtrlByte(ctrlByte, newOffset, typeNum)
-	return typeNum, size, newOffset
+	size, newOffset, err := d.sizeFromCtrlByte(ctrlByte, newOffset, typeNum)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return typeNum, size, newOffset, nil
 }
 
-func (d *decoder) sizeFromCtrlByte(ctrlByte byte, offset uint, typeNum dataType) (uint, uint) {
+func (d *decoder) sizeFromCtrlByte(ctrlByte byte, offset uint, typeNum Kind) (uint, uint, error) {
 	size := uint(ctrlByte & 0x1f)
-	if typeNum == _Extended {
-		return size, offset
+	if typeNum == KindExtended {
+		return size, offset, nil
 	}
 
 	var bytesToRead uint
@@ -70,6 +211,9 @@ func (d *decoder) sizeFromCtrlByte(ctrlByte byte, offset uint, typeNum dataType)
 	}
 
 	newOffset := offset + bytesToRead
+	if newOffset > uint(len(d.buffer)) {
+		return 0, 0, newInvalidDatabaseError("unexpected end of database at offset %d while reading the size of a value", offset)
+	}
 	sizeBytes := d.buffer[offset:newOffset]
 
 	switch {
@@ -80,48 +224,64 @@ func (d *decoder) sizeFromCtrlByte(ctrlByte byte, offset uint, typeNum dataType)
 	case size > 30:
 		size = uint(uintFromBytes(0, sizeBytes)) + 65821
 	}
-	return size, newOffset
+	return size, newOffset, nil
 }
 
-func (d *decoder) decodeFromType(dtype dataType, size uint, offset uint, result reflect.Value) (uint, error) {
+func (d *decoder) decodeFromType(dtype Kind, size uint, offset uint, result reflect.Value) (uint, error) {
 	result = d.indirect(result)
 
+	if err := d.checkContainerSize(dtype, size, offset); err != nil {
+		return 0, err
+	}
+
 	switch dtype {
-	case _Bool:
+	case KindBool:
 		return d.unmarshalBool(size, offset, result)
-	case _Bytes:
+	case KindBytes:
 		return d.unmarshalBytes(size, offset, result)
-	case _Float32:
+	case KindFloat32:
 		return d.unmarshalFloat32(size, offset, result)
-	case _Float64:
+	case KindFloat64:
 		return d.unmarshalFloat64(size, offset, result)
-	case _Int32:
+	case KindInt32:
 		return d.unmarshalInt32(size, offset, result)
-	case _Map:
+	case KindMap:
 		return d.unmarshalMap(size, offset, result)
-	case _Pointer:
+	case KindPointer:
 		return d.unmarshalPointer(size, offset, result)
-	case _Slice:
+	case KindSlice:
 		return d.unmarshalSlice(size, offset, result)
-	case _String:
+	case KindString:
 		return d.unmarshalString(size, offset, result)
-	case _Uint16:
+	case KindUint16:
 		return d.unmarshalUint(size, offset, result, 16)
-	case _Uint32:
+	case KindUint32:
 		return d.unmarshalUint(size, offset, result, 32)
-	case _Uint64:
+	case KindUint64:
 		return d.unmarshalUint(size, offset, result, 64)
-	case _Uint128:
+	case KindUint128:
 		return d.unmarshalUint128(size, offset, result)
 	default:
-		return 0, newInvalidDatabaseError("unknown type: %d", dtype)
+		return 0, newInvalidDatabaseError("unknown type %d of the value before offset %d", dtype, offset)
 	}
 }
 
-func (d *decoder) unmarshalBool(size uint, offset uint, result reflect.Value) (uint, error) {
-	if size > 1 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (bool size of %v)", size)
+// checkContainerSize checks that the size of a map or an array, whose
+// entries start at offset, fits in the buffer. Each entry takes at least one
+// byte, so larger sizes are corrupt and must not be used to allocate the
+// result.
+func (d *decoder) checkContainerSize(dtype Kind, size uint, offset uint) error {
+	var err error
+	switch dtype {
+	case KindMap:
+		_, err = d.payloadEnd(2*size, offset)
+	case KindSlice:
+		_, err = d.payloadEnd(size, offset)
 	}
+	return err
+}
+
+func (d *decoder) unmarshalBool(size uint, offset uint, result reflect.Value) (uint, error) {
 	value, newOffset, err := d.decodeBool(size, offset)
 	if err != nil {
 		return 0, err
@@ -175,9 +335,6 @@ func (d *decoder) unmarshalBytes(size uint, offset uint, result reflect.Value) (
 }
 
 func (d *decoder) unmarshalFloat32(size uint, offset uint, result reflect.Value) (uint, error) {
-	if size != 4 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (float32 size of %v)", size)
-	}
 	value, newOffset, err := d.decodeFloat32(size, offset)
 	if err != nil {
 		return 0, err
@@ -197,10 +354,6 @@ func (d *decoder) unmarshalFloat32(size uint, offset uint, result reflect.Value)
 }
 
 func (d *decoder) unmarshalFloat64(size uint, offset uint, result reflect.Value) (uint, error) {
-
-	if size != 8 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (float 64 size of %v)", size)
-	}
 	value, newOffset, err := d.decodeFloat64(size, offset)
 	if err != nil {
 		return 0, err
@@ -222,9 +375,6 @@ func (d *decoder) unmarshalFloat64(size uint, offset uint, result reflect.Value)
 }
 
 func (d *decoder) unmarshalInt32(size uint, offset uint, result reflect.Value) (uint, error) {
-	if size > 4 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (int32 size of %v)", size)
-	}
 	value, newOffset, err := d.decodeInt(size, offset)
 	if err != nil {
 		return 0, err
@@ -273,8 +423,16 @@ func (d *decoder) unmarshalMap(size uint, offset uint, result reflect.Value) (ui
 }
 
 func (d *decoder) unmarshalPointer(size uint, offset uint, result reflect.Value) (uint, error) {
-	pointer, newOffset := d.decodePointer(size, offset)
-	_, err := d.decode(pointer, result)
+	pointer, newOffset, err := d.followPointer(size, offset)
+	if err != nil {
+		return 0, err
+	}
+	if d.pointers >= maxPointerDepth {
+		return 0, newPointerCycleError(pointer)
+	}
+	d.pointers++
+	_, err = d.decode(pointer, result)
+	d.pointers--
 	return newOffset, err
 }
 
@@ -282,6 +440,8 @@ func (d *decoder) unmarshalSlice(size uint, offset uint, result reflect.Value) (
 	switch result.Kind() {
 	case reflect.Slice:
 		return d.decodeSlice(size, offset, result)
+	case reflect.Array:
+		return d.decodeArray(size, offset, result)
 	case reflect.Interface:
 		if result.NumMethod() == 0 {
 			a := []interface{}{}
@@ -315,11 +475,7 @@ func (d *decoder) unmarshalString(size uint, offset uint, result reflect.Value)
 }
 
 func (d *decoder) unmarshalUint(size uint, offset uint, result reflect.Value, uintType uint) (uint, error) {
-	if size > uintType/8 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (uint%v size of %v)", uintType, size)
-	}
-
-	value, newOffset, err := d.decodeUint(size, offset)
+	value, newOffset, err := d.decodeUint(size, offset, uintType)
 	if err != nil {
 		return 0, err
 	}
@@ -346,9 +502,6 @@ func (d *decoder) unmarshalUint(size uint, offset uint, result reflect.Value, ui
 }
 
 func (d *decoder) unmarshalUint128(size uint, offset uint, result reflect.Value) (uint, error) {
-	if size > 16 {
-		return 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (uint128 size of %v)", size)
-	}
 	value, newOffset, err := d.decodeUint128(size, offset)
 	if err != nil {
 		return 0, err
@@ -367,31 +520,67 @@ func (d *decoder) unmarshalUint128(size uint, offset uint, result reflect.Value)
 	return newOffset, newUnmarshalTypeError(value, result.Type())
 }
 
+// decodeBool returns the boolean stored in size. Only sizes of 0 (false)
+// and 1 (true) are valid.
 func (d *decoder) decodeBool(size uint, offset uint) (bool, uint, error) {
+	if size > 1 {
+		return false, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (bool size of %v)", size)
+	}
 	return size != 0, offset, nil
 }
 
-func (d *decoder) decodeBytes(size uint, offset uint) ([]byte, uint, error) {
+// payloadEnd returns the offset following the size bytes of the payload of
+// a value at offset, or an error if the payload runs past the buffer.
+func (d *decoder) payloadEnd(size uint, offset uint) (uint, error) {
 	newOffset := offset + size
+	if newOffset > uint(len(d.buffer)) || newOffset < offset {
+		return 0, newInvalidDatabaseError("unexpected end of database at offset %d, reading a value of %d bytes", offset, size)
+	}
+	return newOffset, nil
+}
+
+func (d *decoder) decodeBytes(size uint, offset uint) ([]byte, uint, error) {
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return nil, 0, err
+	}
 	bytes := make([]byte, size)
 	copy(bytes, d.buffer[offset:newOffset])
 	return bytes, newOffset, nil
 }
 
 func (d *decoder) decodeFloat64(size uint, offset uint) (float64, uint, error) {
-	newOffset := offset + size
+	if size != 8 {
+		return 0, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (float 64 size of %v)", size)
+	}
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
 	bits := binary.BigEndian.Uint64(d.buffer[offset:newOffset])
 	return math.Float64frombits(bits), newOffset, nil
 }
 
 func (d *decoder) decodeFloat32(size uint, offset uint) (float32, uint, error) {
-	newOffset := offset + size
+	if size != 4 {
+		return 0, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (float32 size of %v)", size)
+	}
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
 	bits := binary.BigEndian.Uint32(d.buffer[offset:newOffset])
 	return math.Float32frombits(bits), newOffset, nil
 }
 
 func (d *decoder) decodeInt(size uint, offset uint) (int, uint, error) {
-	newOffset := offset + size
+	if size > 4 {
+		return 0, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (int32 size of %v)", size)
+	}
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
 	var val int32
 	for _, b := range d.buffer[offset:newOffset] {
 		val = (val << 8) | int32(b)
@@ -400,32 +589,63 @@ func (d *decoder) decodeInt(size uint, offset uint) (int, uint, error) {
 }
 
 func (d *decoder) decodeMap(size uint, offset uint, result reflect.Value) (uint, error) {
+	keyType := result.Type().Key()
+	if keyType.Kind() != reflect.String {
+		return 0, newUnmarshalTypeError("map", result.Type())
+	}
 	if result.IsNil() {
 		result.Set(reflect.MakeMap(result.Type()))
+	} else if d.reuse {
+		for iter := result.MapRange(); iter.Next(); {
+			result.SetMapIndex(iter.Key(), reflect.Value{})
+		}
 	}
 
 	for i := uint(0); i < size; i++ {
 		var key string
 		var err error
+		keyOffset := offset
 		key, offset, err = d.decodeKeyString(offset)
 
 		if err != nil {
-			return 0, err
+			return 0, d.locate(err, keyOffset)
+		}
+
+		parent, omitted := d.enterKey(key)
+		if omitted {
+			if offset, err = d.nextValueOffset(offset, 1); err != nil {
+				return 0, err
+			}
+			continue
 		}
 
 		value := reflect.New(result.Type().Elem())
+		if d.stats != nil {
+			d.path = append(d.path, key)
+		}
 		offset, err = d.decode(offset, value)
 		if err != nil {
 			return 0, err
 		}
-		result.SetMapIndex(reflect.ValueOf(key), value.Elem())
+		if d.stats != nil {
+			d.path = d.path[:len(d.path)-1]
+		}
+		d.node = parent
+		keyValue := reflect.ValueOf(key)
+		if keyValue.Type() != keyType {
+			keyValue = keyValue.Convert(keyType)
+		}
+		result.SetMapIndex(keyValue, value.Elem())
 	}
 	return offset, nil
 }
 
-func (d *decoder) decodePointer(size uint, offset uint) (uint, uint) {
+func (d *decoder) decodePointer(size uint, offset uint) (uint, uint, error) {
 	pointerSize := ((size >> 3) & 0x3) + 1
 	newOffset := offset + pointerSize
+	if newOffset > uint(len(d.buffer)) {
+		return 0, 0, newInvalidDatabaseError("unexpected end of database at offset %d while reading a pointer", offset)
+	}
 	pointerBytes := d.buffer[offset:newOffset]
 	var prefix uint64
 	if pointerSize == 4 {
@@ -449,11 +669,40 @@ func (d *decoder) decodePointer(size uint, offset uint) (uint, uint) {
 
 	pointer := unpacked + pointerValueOffset
 
-	return pointer, newOffset
+	return pointer, newOffset, nil
+}
+
+// followPointer is like decodePointer but returns an error if the pointer
+// points to another pointer, which the MaxMind DB format does not allow.
+// Values reached through a pointer can thus be decoded without following
+// chains of pointers, which may be cyclic.
+func (d *decoder) followPointer(size uint, offset uint) (uint, uint, error) {
+	pointer, newOffset, err := d.decodePointer(size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pointer < uint(len(d.buffer)) && Kind(d.buffer[pointer]>>5) == KindPointer {
+		return 0, 0, newInvalidDatabaseError("the pointer before offset %d points to another pointer at offset %d", newOffset, pointer)
+	}
+	return pointer, newOffset, nil
+}
+
+func newPointerCycleError(pointer uint) InvalidDatabaseError {
+	return newInvalidDatabaseError("more than %d nested pointers to reach offset %d, the pointers form a cycle", maxPointerDepth, pointer)
 }
 
 func (d *decoder) decodeSlice(size uint, offset uint, result reflect.Value) (uint, error) {
-	result.Set(reflect.MakeSlice(result.Type(), int(size), int(size)))
+	if d.reuse && result.Cap() >= int(size) {
+		result.SetLen(int(size))
+		// Values the record does not set must not keep those of the
+		// previous record.
+		zero := reflect.Zero(result.Type().Elem())
+		for i := 0; i < int(size); i++ {
+			result.Index(i).Set(zero)
+		}
+	} else {
+		result.Set(reflect.MakeSlice(result.Type(), int(size), int(size)))
+	}
 	for i := 0; i < int(size); i++ {
 		var err error
 		offset, err = d.decode(offset, result.Index(i))
@@ -464,14 +713,52 @@ func (d *decoder) decodeSlice(size uint, offset uint, result reflect.Value) (uin
 	return offset, nil
 }
 
+// decodeArray decodes an array into a fixed-size Go array. Elements beyond
+// the length of the Go array are skipped, and missing ones are zeroed.
+func (d *decoder) decodeArray(size uint, offset uint, result reflect.Value) (uint, error) {
+	n := result.Len()
+	for i := 0; i < n; i++ {
+		if uint(i) >= size {
+			result.Index(i).Set(reflect.Zero(result.Type().Elem()))
+			continue
+		}
+		var err error
+		offset, err = d.decode(offset, result.Index(i))
+		if err != nil {
+			return 0, err
+		}
+	}
+	if size > uint(n) {
+		return d.nextValueOffset(offset, size-uint(n))
+	}
+	return offset, nil
+}
+
 func (d *decoder) decodeString(size uint, offset uint) (string, uint, error) {
-	newOffset := offset + size
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := d.checkUTF8(offset, newOffset); err != nil {
+		return "", 0, err
+	}
 	return string(d.buffer[offset:newOffset]), newOffset, nil
 }
 
+// checkUTF8 returns an error if the decoder validates strings and the bytes
+// of the buffer from offset to end are not valid UTF-8.
+func (d *decoder) checkUTF8(offset uint, end uint) error {
+	if d.validateUTF8 && !utf8.Valid(d.buffer[offset:end]) {
+		return newInvalidDatabaseError("the string at offset %d is not valid UTF-8", offset)
+	}
+	return nil
+}
+
 type fieldsType struct {
 	namedFields     map[string]int
 	anonymousFields []int
+	// ipFields holds the indexes of the fields with the ip tag option.
+	ipFields map[int]bool
 }
 
 var (
@@ -489,6 +776,7 @@ func (d *decoder) decodeStruct(size uint, offset uint, result reflect.Value) (ui
 		numFields := resultType.NumField()
 		namedFields := make(map[string]int, numFields)
 		var anonymous []int
+		var ipFields map[int]bool
 		for i := 0; i < numFields; i++ {
 			field := resultType.Field(i)
 
@@ -497,7 +785,16 @@ func (d *decoder) decodeStruct(size uint, offset uint, result reflect.Value) (ui
 				if tag == "-" {
 					continue
 				}
-				fieldName = tag
+				name, option, _ := strings.Cut(tag, ",")
+				if name != "" {
+					fieldName = name
+				}
+				if option == "ip" && field.Type == addrType {
+					if ipFields == nil {
+						ipFields = map[int]bool{}
+					}
+					ipFields[i] = true
+				}
 			}
 			if field.Anonymous {
 				anonymous = append(anonymous, i)
@@ -506,11 +803,17 @@ func (d *decoder) decodeStruct(size uint, offset uint, result reflect.Value) (ui
 			namedFields[fieldName] = i
 		}
 		fieldMapMu.Lock()
-		fields = &fieldsType{namedFields, anonymous}
+		fields = &fieldsType{namedFields, anonymous, ipFields}
 		fieldMap[resultType] = fields
 		fieldMapMu.Unlock()
 	}
 
+	// Keys of embedded structs cannot be attributed to a single struct, so
+	// mismatches are not recorded for structs that have any.
+	stats := d.stats
+	track := stats != nil && len(fields.anonymousFields) == 0
+	d.stats = nil
+
 	// This fills in embedded structs
 	for i := range fields.anonymousFields {
 		_, err := d.unmarshalMap(size, offset, result.Field(i))
@@ -518,6 +821,12 @@ func (d *decoder) decodeStruct(size uint, offset uint, result reflect.Value) (ui
 			return 0, err
 		}
 	}
+	d.stats = stats
+
+	var seen []bool
+	if track {
+		seen = make([]bool, resultType.NumField())
+	}
 
 	// This handles named fields
 	for i := uint(0); i < size; i++ {
@@ -525,33 +834,121 @@ func (d *decoder) decodeStruct(size uint, offset uint, result reflect.Value) (ui
 			err error
 			key string
 		)
+		keyOffset := offset
 		key, offset, err = d.decodeStructKey(offset)
 		if err != nil {
-			return 0, err
+			return 0, d.locate(err, keyOffset)
 		}
 		j, ok := fields.namedFields[key]
 		if !ok {
-			offset = d.nextValueOffset(offset, 1)
+			if track {
+				stats.add(false, d.path, key)
+			}
+			if offset, err = d.nextValueOffset(offset, 1); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		if track {
+			seen[j] = true
+		}
+
+		parent, omitted := d.enterKey(key)
+		if omitted {
+			if offset, err = d.nextValueOffset(offset, 1); err != nil {
+				return 0, err
+			}
 			continue
 		}
 
-		offset, err = d.decode(offset, result.Field(j))
+		if stats != nil {
+			d.path = append(d.path, key)
+		}
+		if fields.ipFields[j] {
+			offset, err = d.decodeIP(offset, result.Field(j))
+		} else {
+			offset, err = d.decode(offset, result.Field(j))
+		}
 		if err != nil {
 			return 0, err
 		}
+		if stats != nil {
+			d.path = d.path[:len(d.path)-1]
+		}
+		d.node = parent
+	}
+
+	if track {
+		for key, j := range fields.namedFields {
+			if !seen[j] {
+				stats.add(true, d.path, key)
+			}
+		}
 	}
 	return offset, nil
 }
 
-func (d *decoder) decodeUint(size uint, offset uint) (uint64, uint, error) {
-	newOffset := offset + size
+var addrType = reflect.TypeOf(netip.Addr{})
+
+// decodeIP decodes the value at offset into a netip.Addr field with the ip
+// tag option. The value may be a uint128, which is read as an IPv6 address,
+// or a byte array of 4 or 16 bytes.
+func (d *decoder) decodeIP(offset uint, result reflect.Value) (uint, error) {
+	typeNum, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		return 0, err
+	}
+	if typeNum == KindPointer {
+		pointer, newOffset, err := d.followPointer(size, newOffset)
+		if err != nil {
+			return 0, err
+		}
+		_, err = d.decodeIP(pointer, result)
+		return newOffset, err
+	}
+	end, err := d.payloadEnd(size, newOffset)
+	if err != nil {
+		return 0, err
+	}
+
+	var addr netip.Addr
+	switch {
+	case typeNum == KindUint128 && size <= 16:
+		var bytes [16]byte
+		copy(bytes[16-size:], d.buffer[newOffset:newOffset+size])
+		addr = netip.AddrFrom16(bytes)
+	case typeNum == KindBytes && (size == 4 || size == 16):
+		addr, _ = netip.AddrFromSlice(d.buffer[newOffset : newOffset+size])
+	default:
+		return 0, newUnmarshalTypeError(typeNum, result.Type())
+	}
+	result.Set(reflect.ValueOf(addr))
+	return end, nil
+}
+
+// decodeUint decodes an unsigned integer of uintType bits, i.e., 16, 32
+// or 64.
+func (d *decoder) decodeUint(size uint, offset uint, uintType uint) (uint64, uint, error) {
+	if size > uintType/8 {
+		return 0, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (uint%v size of %v)", uintType, size)
+	}
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return 0, 0, err
+	}
 	val := uintFromBytes(0, d.buffer[offset:newOffset])
 
 	return val, newOffset, nil
 }
 
 func (d *decoder) decodeUint128(size uint, offset uint) (*big.Int, uint, error) {
-	newOffset := offset + size
+	if size > 16 {
+		return nil, 0, newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (uint128 size of %v)", size)
+	}
+	newOffset, err := d.payloadEnd(size, offset)
+	if err != nil {
+		return nil, 0, err
+	}
 	val := new(big.Int)
 	val.SetBytes(d.buffer[offset:newOffset])
 
@@ -566,37 +963,56 @@ func uintFromBytes(prefix uint64, uintBytes []byte) uint64 {
 	return val
 }
 
+// decodeKeyString decodes a map key. Keys should be strings, but bytes are
+// accepted as well.
 func (d *decoder) decodeKeyString(offset uint) (string, uint, error) {
-	typeNum, size, newOffset := d.decodeCtrlData(offset)
-	if typeNum == _Pointer {
-		pointer, ptrOffset := d.decodePointer(size, newOffset)
+	typeNum, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		return "", 0, err
+	}
+	switch typeNum {
+	case KindPointer:
+		pointer, ptrOffset, err := d.followPointer(size, newOffset)
+		if err != nil {
+			return "", 0, err
+		}
 		key, _, err := d.decodeKeyString(pointer)
 		return key, ptrOffset, err
-	}
-	if typeNum != _String {
+	case KindString, KindBytes:
+		return d.decodeString(size, newOffset)
+	default:
 		return "", 0, newInvalidDatabaseError("unexpected type when decoding string: %v", typeNum)
 	}
-	return d.decodeString(size, newOffset)
 }
 
 // This function is used to skip ahead to the next value without decoding
 // the one at the offset passed in. The size bits have different meanings for
 // different data types
-func (d *decoder) nextValueOffset(offset uint, numberToSkip uint) uint {
-	if numberToSkip == 0 {
-		return offset
-	}
-	typeNum, size, offset := d.decodeCtrlData(offset)
-	switch typeNum {
-	case _Pointer:
-		_, offset = d.decodePointer(size, offset)
-	case _Map:
-		numberToSkip += 2 * size
-	case _Slice:
-		numberToSkip += size
-	case _Bool:
-	default:
-		offset += size
+func (d *decoder) nextValueOffset(offset uint, numberToSkip uint) (uint, error) {
+	for ; numberToSkip > 0; numberToSkip-- {
+		start := offset
+		typeNum, size, newOffset, err := d.decodeCtrlData(offset)
+		if err != nil {
+			return 0, d.locate(err, start)
+		}
+		offset = newOffset
+		switch typeNum {
+		case KindPointer:
+			_, offset, err = d.decodePointer(size, offset)
+		case KindMap:
+			numberToSkip += 2 * size
+		case KindSlice:
+			numberToSkip += size
+		case KindBool:
+		case KindString, KindFloat64, KindBytes, KindUint16, KindUint32, KindInt32,
+			KindUint64, KindUint128, KindFloat32:
+			offset, err = d.payloadEnd(size, offset)
+		default:
+			err = newInvalidDatabaseError("unknown type %d of the value before offset %d", typeNum, offset)
+		}
+		if err != nil {
+			return 0, d.locate(err, start)
+		}
 	}
-	return d.nextValueOffset(offset, numberToSkip-1)
+	return offset, nil
 }
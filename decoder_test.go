@@ -1,14 +1,39 @@
 package maxminddb
 
 import (
+	"bytes"
 	"encoding/hex"
 	"io/ioutil"
 	"math/big"
+	"net"
+	"net/netip"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 )
 
+func TestKindString(t *testing.T) {
+	kinds := map[Kind]string{
+		KindPointer:  "pointer",
+		KindString:   "utf8_string",
+		KindFloat64:  "double",
+		KindMap:      "map",
+		KindUint128:  "uint128",
+		KindSlice:    "array",
+		KindBool:     "boolean",
+		KindFloat32:  "float",
+		Kind(16):     "Kind(16)",
+		Kind(-1):     "Kind(-1)",
+		KindExtended: "extended",
+	}
+	for kind, expected := range kinds {
+		if kind.String() != expected {
+			t.Errorf("expected %q for kind %d, got %q", expected, int(kind), kind.String())
+		}
+	}
+}
+
 func TestBool(t *testing.T) {
 	bools := map[string]interface{}{
 		"0007": false,
@@ -18,6 +43,19 @@ func TestBool(t *testing.T) {
 	validateDecoding(t, bools)
 }
 
+func TestInvalidBool(t *testing.T) {
+	for _, input := range []string{"0207", "1c07"} {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		var result interface{}
+		_, err := d.decode(0, reflect.ValueOf(&result))
+		if _, ok := err.(InvalidDatabaseError); !ok {
+			t.Errorf("expected an InvalidDatabaseError for %s, got %v", input, err)
+		}
+	}
+}
+
 func TestDouble(t *testing.T) {
 	doubles := map[string]interface{}{
 		"680000000000000000": 0.0,
@@ -47,6 +85,28 @@ func TestFloat(t *testing.T) {
 	validateDecoding(t, floats)
 }
 
+func TestInvalidFloatSize(t *testing.T) {
+	inputs := []string{
+		// float32 of size 3 and 8
+		"0308000000",
+		"08080000000000000000",
+		// float64 of size 2 and 4
+		"620000",
+		"6400000000",
+	}
+	for _, input := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		for _, result := range []interface{}{new(interface{}), new(float64)} {
+			_, err := d.decode(0, reflect.ValueOf(result))
+			if _, ok := err.(InvalidDatabaseError); !ok {
+				t.Errorf("expected an InvalidDatabaseError for %s, got %v", input, err)
+			}
+		}
+	}
+}
+
 func TestInt32(t *testing.T) {
 	int32 := map[string]interface{}{
 		"0001":         0,
@@ -199,7 +259,13 @@ func powBigInt(bi *big.Int, pow uint) *big.Int {
 func validateDecoding(t *testing.T, tests map[string]interface{}) {
 	for inputStr, expected := range tests {
 		inputBytes, _ := hex.DecodeString(inputStr)
-		d := decoder{inputBytes}
+
+		// The buffer is at full capacity and followed by guard bytes so
+		// that any append or write through the buffer is detected.
+		backing := bytes.Repeat([]byte{0xAA}, len(inputBytes)+8)
+		copy(backing, inputBytes)
+		original := append([]byte(nil), backing...)
+		d := decoder{buffer: backing[:len(inputBytes):len(inputBytes)]}
 
 		var result interface{}
 		_, err := d.decode(0, reflect.ValueOf(&result))
@@ -210,15 +276,493 @@ func validateDecoding(t *testing.T, tests map[string]interface{}) {
 			// A big case statement would produce nicer errors
 			t.Errorf("Output was incorrect: %s  %s", inputStr, expected)
 		}
+		if !bytes.Equal(backing, original) {
+			t.Errorf("Decoding %s modified the buffer", inputStr)
+		}
+	}
+}
+
+func TestInvalidIntegerSize(t *testing.T) {
+	inputs := map[string]string{
+		"uint16":  "a3" + strings.Repeat("01", 3),
+		"uint32":  "c5" + strings.Repeat("01", 5),
+		"int32":   "0501" + strings.Repeat("01", 5),
+		"uint64":  "0902" + strings.Repeat("01", 9),
+		"uint128": "1103" + strings.Repeat("01", 17),
+	}
+	for name, input := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		var result interface{}
+		_, err := d.decode(0, reflect.ValueOf(&result))
+		if _, ok := err.(InvalidDatabaseError); !ok {
+			t.Errorf("expected an InvalidDatabaseError for an oversized %s, got %v", name, err)
+		}
+	}
+}
+
+func TestTruncatedStructKey(t *testing.T) {
+	// A map with a single key claiming 5 bytes when only 2 remain.
+	inputBytes, _ := hex.DecodeString("e1456162")
+	d := decoder{buffer: inputBytes}
+
+	var result struct {
+		Key string `maxminddb:"abcde"`
+	}
+	_, err := d.decode(0, reflect.ValueOf(&result))
+	if _, ok := err.(InvalidDatabaseError); !ok {
+		t.Errorf("expected an InvalidDatabaseError for a truncated key, got %v", err)
+	}
+}
+
+func TestReuseSliceElements(t *testing.T) {
+	// Two arrays of a map each, with different keys.
+	inputBytes, _ := hex.DecodeString("0104e141614178" + "0104e141624179")
+	d := decoder{buffer: inputBytes, reuse: true}
+
+	var result []struct {
+		A string `maxminddb:"a"`
+		B string `maxminddb:"b"`
+	}
+	if _, err := d.decode(0, reflect.ValueOf(&result)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.decode(7, reflect.ValueOf(&result)); err != nil {
+		t.Fatal(err)
+	}
+	if len(result) != 1 || result[0].A != "" || result[0].B != "y" {
+		t.Errorf("expected the second record only, got %+v", result)
+	}
+}
+
+func TestEmptyStructKeyAtEnd(t *testing.T) {
+	// A map whose key is a pointer to an empty string ending the buffer.
+	inputBytes, _ := hex.DecodeString("e120044040")
+	d := decoder{buffer: inputBytes}
+
+	var result struct {
+		Key string `maxminddb:"key"`
+	}
+	if _, err := d.decode(0, reflect.ValueOf(&result)); err != nil {
+		t.Errorf("unexpected error decoding an empty key: %v", err)
+	}
+}
+
+func TestInvalidDatabaseErrorLocation(t *testing.T) {
+	inputs := map[string]InvalidDatabaseError{
+		// A map whose value is a double of 2 bytes.
+		"e14161620000": {Offset: 3, ControlByte: 0x62, Kind: KindFloat64},
+		// An array whose element has the unknown type 16.
+		"01040009": {Offset: 2, ControlByte: 0x00, Kind: Kind(16)},
+		// An extended type whose type byte is missing.
+		"00": {Offset: 0, ControlByte: 0x00, Kind: KindExtended},
+	}
+	for input, expected := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		var result interface{}
+		_, err := d.decode(0, reflect.ValueOf(&result))
+		invalid, ok := err.(InvalidDatabaseError)
+		if !ok {
+			t.Errorf("expected an InvalidDatabaseError for %s, got %v", input, err)
+			continue
+		}
+		if invalid.Offset != expected.Offset || invalid.ControlByte != expected.ControlByte || invalid.Kind != expected.Kind {
+			t.Errorf("%s: expected offset %d, control byte %#02x and %s, got %d, %#02x and %s",
+				input, expected.Offset, expected.ControlByte, expected.Kind,
+				invalid.Offset, invalid.ControlByte, invalid.Kind)
+		}
+	}
+
+	if err := newInvalidDatabaseError("invalid node in search tree"); err.Offset != -1 {
+		t.Errorf("expected offset -1 for an error outside of the data section, got %d", err.Offset)
+	}
+}
+
+func TestPointerCycle(t *testing.T) {
+	inputs := map[string]string{
+		"pointer to itself":           "2000",
+		"pointers to each other":      "20022000",
+		"map containing itself":       "e141612000",
+		"array containing itself":     "01042000",
+		"map key pointing to itself":  "e1200141",
+		"maps containing each other":  "e141612005e141622000",
+		"ip pointing to itself":       "e14269702004",
+		"typed map containing itself": "e141612000",
+	}
+	for name, input := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		var err error
+		switch {
+		case strings.HasPrefix(name, "ip"):
+			var result struct {
+				IP netip.Addr `maxminddb:"ip,ip"`
+			}
+			_, err = d.decode(0, reflect.ValueOf(&result))
+		case strings.HasPrefix(name, "typed"):
+			_, _, err = d.decodeTyped(0)
+		default:
+			var result interface{}
+			_, err = d.decode(0, reflect.ValueOf(&result))
+		}
+		if _, ok := err.(InvalidDatabaseError); !ok {
+			t.Errorf("%s: expected an InvalidDatabaseError, got %v", name, err)
+		}
+		if d.pointers != 0 {
+			t.Errorf("%s: expected the pointer count to be restored, got %d", name, d.pointers)
+		}
+	}
+
+	inputBytes, _ := hex.DecodeString("20022000")
+	d := decoder{buffer: inputBytes}
+	if _, _, _, ok := d.resolve(0); ok {
+		t.Error("expected resolving pointers to pointers to fail")
 	}
 }
 
+func TestMaxDepth(t *testing.T) {
+	// nestedArrays returns levels arrays, each containing the next one.
+	nestedArrays := func(levels int) []byte {
+		input, _ := hex.DecodeString(strings.Repeat("0104", levels-1) + "0004")
+		return input
+	}
+	tests := []struct {
+		levels   int
+		maxDepth int
+		valid    bool
+	}{
+		{levels: 3, maxDepth: 3, valid: true},
+		{levels: 4, maxDepth: 3, valid: false},
+		{levels: defaultMaxDepth, valid: true},
+		{levels: defaultMaxDepth + 1, valid: false},
+		{levels: defaultMaxDepth + 1, maxDepth: -1, valid: false},
+	}
+	for _, test := range tests {
+		d := decoder{buffer: nestedArrays(test.levels), maxDepth: test.maxDepth}
+
+		var result interface{}
+		_, err := d.decode(0, reflect.ValueOf(&result))
+		_, _, typedErr := d.decodeTyped(0)
+		for _, err := range []error{err, typedErr} {
+			if test.valid && err != nil {
+				t.Errorf("%d levels, limit %d: unexpected error %v", test.levels, test.maxDepth, err)
+			}
+			if _, ok := err.(InvalidDatabaseError); !test.valid && !ok {
+				t.Errorf("%d levels, limit %d: expected an InvalidDatabaseError, got %v", test.levels, test.maxDepth, err)
+			}
+		}
+		if d.depth != 0 {
+			t.Errorf("%d levels, limit %d: expected the depth to be restored, got %d", test.levels, test.maxDepth, d.depth)
+		}
+	}
+
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb", WithMaxDecodeDepth(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var record interface{}
+	err = reader.Lookup(net.ParseIP("81.2.69.160"), &record)
+	if _, ok := err.(InvalidDatabaseError); !ok {
+		t.Errorf("expected an InvalidDatabaseError decoding names three levels deep, got %v", err)
+	}
+	var country struct {
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &country); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUTF8Validation(t *testing.T) {
+	inputs := map[string]bool{
+		"43e4baba":       true,
+		"4341ff42":       false,
+		"e142ff614161":   false,
+		"e14261614241ff": false,
+	}
+	for input, valid := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		for _, validate := range []bool{false, true} {
+			d := decoder{buffer: inputBytes, validateUTF8: validate}
+
+			var result interface{}
+			_, err := d.decode(0, reflect.ValueOf(&result))
+			errs := []error{err}
+			if strings.HasPrefix(input, "e1") {
+				var record struct {
+					A string `maxminddb:"aa"`
+				}
+				_, err = d.decode(0, reflect.ValueOf(&record))
+				errs = append(errs, err)
+			} else if _, ok := d.stringAt(0); ok != (valid || !validate) {
+				t.Errorf("%s, validation %v: expected stringAt to return %v", input, validate, !ok)
+			}
+
+			for _, err := range errs {
+				if (valid || !validate) && err != nil {
+					t.Errorf("%s, validation %v: unexpected error %v", input, validate, err)
+				}
+				if _, ok := err.(InvalidDatabaseError); !valid && validate && !ok {
+					t.Errorf("%s, validation %v: expected an InvalidDatabaseError, got %v", input, validate, err)
+				}
+			}
+		}
+	}
+
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb", WithUTF8Validation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var record interface{}
+	if err := reader.Lookup(net.ParseIP("2001:218::1"), &record); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMalformedControlData(t *testing.T) {
+	inputs := map[string]string{
+		"empty":                   "",
+		"missing extended type":   "01",
+		"unknown extended type":   "00f9",
+		"missing size byte":       "5d",
+		"missing size bytes":      "5e01",
+		"missing pointer bytes":   "28",
+		"missing map key":         "e1",
+		"pointer past the buffer": "e120ff",
+	}
+	for name, input := range inputs {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+
+		var result interface{}
+		if _, err := d.decode(0, reflect.ValueOf(&result)); err == nil {
+			t.Errorf("%s: expected an error decoding %q", name, input)
+		} else if _, ok := err.(InvalidDatabaseError); !ok {
+			t.Errorf("%s: expected an InvalidDatabaseError, got %v", name, err)
+		}
+	}
+
+	d := decoder{buffer: []byte{0x00, 0xf9}}
+	if _, err := d.nextValueOffset(0, 1); err == nil {
+		t.Error("expected an error skipping a value of an unknown type")
+	}
+}
+
+func TestTruncatedRecord(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	offset, err := reader.LookupOffset(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := reader.RawValue(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Cutting the data section anywhere in the record must fail cleanly.
+	for end := uint(offset); end < uint(offset)+uint(len(raw)); end++ {
+		d := decoder{buffer: reader.decoder.buffer[:end]}
+
+		var record interface{}
+		if _, err := d.decode(uint(offset), reflect.ValueOf(&record)); err == nil {
+			t.Fatalf("expected an error for the record cut at %d", end)
+		}
+		var city City
+		if _, err := d.decode(uint(offset), reflect.ValueOf(&city)); err == nil {
+			t.Fatalf("expected an error for the city record cut at %d", end)
+		}
+	}
+}
+
+func TestOversizedContainer(t *testing.T) {
+	// An array claiming 16 million elements in a buffer of 5 bytes.
+	inputBytes, _ := hex.DecodeString("1f04ffffff")
+	d := decoder{buffer: inputBytes}
+
+	var result interface{}
+	_, err := d.decode(0, reflect.ValueOf(&result))
+	if _, ok := err.(InvalidDatabaseError); !ok {
+		t.Errorf("expected an InvalidDatabaseError for an oversized array, got %v", err)
+	}
+}
+
+func TestMapKeys(t *testing.T) {
+	// A map whose key is stored as bytes rather than as a string
+	d := decoder{buffer: []byte{0xe1, 0x82, 'e', 'n', 0x43, 'F', 'o', 'o'}}
+
+	var result map[string]string
+	if _, err := d.decode(0, reflect.ValueOf(&result)); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, map[string]string{"en": "Foo"}) {
+		t.Errorf("unexpected map: %v", result)
+	}
+
+	var structResult struct {
+		En string `maxminddb:"en"`
+	}
+	if _, err := d.decode(0, reflect.ValueOf(&structResult)); err != nil {
+		t.Fatal(err)
+	}
+	if structResult.En != "Foo" {
+		t.Errorf("unexpected struct: %v", structResult)
+	}
+
+	type language string
+	var namedResult map[language]string
+	if _, err := d.decode(0, reflect.ValueOf(&namedResult)); err != nil {
+		t.Fatal(err)
+	}
+	if namedResult["en"] != "Foo" {
+		t.Errorf("unexpected map: %v", namedResult)
+	}
+
+	var intKeys map[int]string
+	_, err := d.decode(0, reflect.ValueOf(&intKeys))
+	if _, ok := err.(UnmarshalTypeError); !ok {
+		t.Errorf("expected an UnmarshalTypeError, got %v", err)
+	}
+
+	// A map whose key is a uint16
+	d = decoder{buffer: []byte{0xe1, 0xa1, 0xff, 0x43, 'F', 'o', 'o'}}
+	var interfaceResult interface{}
+	_, err = d.decode(0, reflect.ValueOf(&interfaceResult))
+	if _, ok := err.(InvalidDatabaseError); !ok {
+		t.Errorf("expected an InvalidDatabaseError, got %v", err)
+	}
+}
+
+func TestPointerSizes(t *testing.T) {
+	// The official databases only hold pointers of one byte, so each of
+	// their targets is pointed to again with pointers of every size. Each
+	// size adds a different base value to the value encoded in its bytes:
+	// 0, 2048, 526336 and 0 respectively. The value a pointer of size 2 or
+	// 3 points to is copied to the base value of the size, after the data
+	// section, so that the pointers it holds still resolve.
+	bases := []uint{0, 2048, 526336, 0}
+
+	files, err := filepath.Glob("test-data/test-data/GeoIP2-*-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var pointers int
+	for _, file := range files {
+		reader, err := Open(file)
+		if err != nil {
+			t.Fatalf("unexpected error while opening %s: %v", file, err)
+		}
+		data := reader.decoder.buffer
+		targets := map[uint]bool{}
+		for offset := uint(0); offset < uint(len(data)); {
+			offset = pointerTargets(t, &reader.decoder, offset, targets)
+		}
+		pointers += len(targets)
+
+		for target := range targets {
+			end, err := reader.decoder.nextValueOffset(target, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expected interface{}
+			if _, err := reader.decoder.decode(target, reflect.ValueOf(&expected)); err != nil {
+				t.Fatal(err)
+			}
+
+			for size, base := range bases {
+				buffer := append([]byte{}, data...)
+				value := target
+				if base > 0 {
+					buffer = append(buffer, make([]byte, base-uint(len(buffer)))...)
+					buffer = append(buffer, data[target:end]...)
+					value = 0
+				}
+				offset := uint(len(buffer))
+				buffer = append(buffer, encodePointer(size+1, value)...)
+
+				d := decoder{buffer: buffer}
+				var result interface{}
+				if _, err := d.decode(offset, reflect.ValueOf(&result)); err != nil {
+					t.Fatalf("%s: pointer of size %d to %d: %v", file, size+1, target, err)
+				}
+				if !reflect.DeepEqual(result, expected) {
+					t.Errorf("%s: pointer of size %d to %d decoded to %v, expected %v", file, size+1, target, result, expected)
+				}
+				if next, err := d.nextValueOffset(offset, 1); err != nil || next != uint(len(buffer)) {
+					t.Errorf("%s: skipping pointer of size %d ended at %d, expected %d (%v)", file, size+1, next, len(buffer), err)
+				}
+			}
+		}
+		reader.Close()
+	}
+	if pointers == 0 {
+		t.Fatal("found no pointers in the test databases")
+	}
+}
+
+// pointerTargets adds the targets of the pointers in the value at offset to
+// targets, and returns the offset of the next value.
+func pointerTargets(t *testing.T, d *decoder, offset uint, targets map[uint]bool) uint {
+	kind, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	switch kind {
+	case KindPointer:
+		pointer, next, err := d.decodePointer(size, newOffset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		targets[pointer] = true
+		return next
+	case KindMap:
+		size *= 2
+		fallthrough
+	case KindSlice:
+		for i := uint(0); i < size; i++ {
+			newOffset = pointerTargets(t, d, newOffset, targets)
+		}
+		return newOffset
+	}
+	next, err := d.nextValueOffset(offset, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return next
+}
+
+// encodePointer returns a pointer of size bytes with value, which excludes
+// the base value of the size.
+func encodePointer(size int, value uint) []byte {
+	ctrl := byte(KindPointer<<5) | byte(size-1)<<3
+	if size < 4 {
+		ctrl |= byte(value>>(8*size)) & 0x7
+	}
+	pointer := []byte{ctrl}
+	for i := size - 1; i >= 0; i-- {
+		pointer = append(pointer, byte(value>>(8*i)))
+	}
+	return pointer
+}
+
 func TestPointers(t *testing.T) {
 	bytes, err := ioutil.ReadFile("test-data/test-data/maps-with-pointers.raw")
 	if err != nil {
 		t.Error(err)
 	}
-	d := decoder{bytes}
+	d := decoder{buffer: bytes}
 
 	expected := map[uint]map[string]string{
 		0:  {"long_key": "long_value1"},
@@ -241,3 +785,32 @@ func TestPointers(t *testing.T) {
 	}
 
 }
+
+func TestDecodeIPTagOption(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var result struct {
+		Uint128 netip.Addr `maxminddb:"uint128,ip"`
+		Bytes   netip.Addr `maxminddb:"bytes,ip"`
+	}
+	if err := reader.Lookup(net.ParseIP("::1.1.1.0"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if expected := netip.MustParseAddr("100::"); result.Uint128 != expected {
+		t.Errorf("expected %v, got %v", expected, result.Uint128)
+	}
+	if expected := netip.MustParseAddr("0.0.0.42"); result.Bytes != expected {
+		t.Errorf("expected %v, got %v", expected, result.Bytes)
+	}
+
+	var invalid struct {
+		String netip.Addr `maxminddb:"utf8_string,ip"`
+	}
+	if err := reader.Lookup(net.ParseIP("::1.1.1.0"), &invalid); err == nil {
+		t.Error("expected an error when decoding a string as an IP address")
+	}
+}
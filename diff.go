@@ -0,0 +1,79 @@
+package maxminddb
+
+import (
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// RecordDiff is a difference between two records at a single path. Paths
+// use the same format as FlatRecord, e.g. "country.iso_code" or
+// "subdivisions.0.names.en". A value that is absent from a record is nil.
+type RecordDiff struct {
+	Path string
+	A    interface{}
+	B    interface{}
+}
+
+// Equal reports whether two records decoded into interface{} values are
+// equal. Unlike reflect.DeepEqual, uint128 values are compared by value.
+func Equal(a, b interface{}) bool {
+	return len(DiffRecords(a, b)) == 0
+}
+
+// DiffRecords returns the differences between two records decoded into
+// interface{} values, sorted by path. Maps are compared key by key and
+// arrays element by element; other values are compared as a whole.
+func DiffRecords(a, b interface{}) []RecordDiff {
+	var diffs []RecordDiff
+	diffValues(&diffs, "", a, b)
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+func diffValues(diffs *[]RecordDiff, path string, a, b interface{}) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch av := a.(type) {
+	case map[string]interface{}:
+		if bv, ok := b.(map[string]interface{}); ok {
+			for key, element := range av {
+				diffValues(diffs, join(key), element, bv[key])
+			}
+			for key, element := range bv {
+				if _, ok := av[key]; !ok {
+					*diffs = append(*diffs, RecordDiff{Path: join(key), B: element})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if bv, ok := b.([]interface{}); ok {
+			for i := 0; i < len(av) || i < len(bv); i++ {
+				var ae, be interface{}
+				if i < len(av) {
+					ae = av[i]
+				}
+				if i < len(bv) {
+					be = bv[i]
+				}
+				diffValues(diffs, join(strconv.Itoa(i)), ae, be)
+			}
+			return
+		}
+	case *big.Int:
+		if bv, ok := b.(*big.Int); ok && av != nil && bv != nil && av.Cmp(bv) == 0 {
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(a, b) {
+		*diffs = append(*diffs, RecordDiff{Path: path, A: a, B: b})
+	}
+}
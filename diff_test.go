@@ -0,0 +1,48 @@
+package maxminddb
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+)
+
+func TestDiffRecords(t *testing.T) {
+	a := map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "GB", "geoname_id": uint64(2635167)},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "ENG"},
+		},
+		"uint128": big.NewInt(42),
+		"removed": true,
+	}
+	b := map[string]interface{}{
+		"country": map[string]interface{}{"iso_code": "IE", "geoname_id": uint64(2635167)},
+		"subdivisions": []interface{}{
+			map[string]interface{}{"iso_code": "ENG"},
+			map[string]interface{}{"iso_code": "LND"},
+		},
+		"uint128": big.NewInt(42),
+		"added":   "x",
+	}
+
+	expected := []RecordDiff{
+		{Path: "added", B: "x"},
+		{Path: "country.iso_code", A: "GB", B: "IE"},
+		{Path: "removed", A: true},
+		{Path: "subdivisions.1", B: map[string]interface{}{"iso_code": "LND"}},
+	}
+	diffs := DiffRecords(a, b)
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("unexpected diffs: %v", diffs)
+	}
+
+	if Equal(a, b) {
+		t.Error("expected the records to differ")
+	}
+	if !Equal(a, a) || !Equal(nil, nil) {
+		t.Error("expected the records to be equal")
+	}
+	if diffs := DiffRecords(nil, a); len(diffs) != 1 || diffs[0].Path != "" {
+		t.Errorf("unexpected diffs with a missing record: %v", diffs)
+	}
+}
@@ -0,0 +1,166 @@
+package maxminddb
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+)
+
+// DOTOptions configures WriteDOT.
+type DOTOptions struct {
+	// Network limits the graph to the subtree of the network. The whole
+	// tree is written if it is nil.
+	Network *net.IPNet
+	// MaxDepth limits the number of levels of the tree below the root of
+	// the graph. Subtrees below the limit are replaced by a "..." node. The
+	// depth is not limited if it is 0.
+	MaxDepth int
+	// LabelPath is the path of map keys of the value that labels data
+	// leaves, e.g., []string{"country", "iso_code"}. Leaves are labeled
+	// with the offset of their record if it is empty or if the record has
+	// no value at the path.
+	LabelPath []string
+}
+
+// WriteDOT writes the search tree as a Graphviz DOT graph to w. Nodes are
+// labeled with their number and network, and edges with the bit they
+// follow. Records that are shared by several networks appear as a single
+// leaf, which shows how the data section is deduplicated, and networks
+// aliased to the IPv4 subtree point to its root. Without a depth or
+// network limit the graph has a vertex for every node of the tree, so it is
+// only practical for small databases.
+func (r *Reader) WriteDOT(w io.Writer, options DOTOptions) error {
+	if r.buffer == nil {
+		return ErrClosed
+	}
+	start := netNode{}
+	if options.Network != nil {
+		var err error
+		start, err = r.subtree(options.Network)
+		if err != nil {
+			return err
+		}
+	}
+	ipLen := 4
+	if r.Metadata.IPVersion == 6 {
+		ipLen = 16
+	}
+	labelPath := make([]interface{}, len(options.LabelPath))
+	for i, key := range options.LabelPath {
+		labelPath[i] = key
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph mmdb {")
+	fmt.Fprintln(bw, "\tnode [shape=ellipse];")
+
+	nodeCount := r.Metadata.NodeCount
+	// written holds the vertices already declared. Internal nodes may be
+	// reached twice when a database aliases networks to the IPv4 subtree.
+	written := map[string]bool{}
+	truncated := 0
+	// vertex returns the name of the vertex for node, declaring it if
+	// needed, and whether its children remain to be written.
+	vertex := func(node netNode) (string, bool, error) {
+		switch {
+		case node.pointer == nodeCount:
+			if !written["empty"] {
+				fmt.Fprintln(bw, "\tempty [shape=point];")
+				written["empty"] = true
+			}
+			return "empty", false, nil
+		case node.pointer > nodeCount:
+			name := fmt.Sprintf("d%d", node.pointer)
+			if !written[name] {
+				label, err := r.dotLabel(node.pointer, labelPath)
+				if err != nil {
+					return "", false, err
+				}
+				fmt.Fprintf(bw, "\t%s [shape=box,label=%q];\n", name, label)
+				written[name] = true
+			}
+			return name, false, nil
+		case options.MaxDepth > 0 && int(node.bit-start.bit) >= options.MaxDepth:
+			truncated++
+			name := fmt.Sprintf("t%d", truncated)
+			fmt.Fprintf(bw, "\t%s [shape=plaintext,label=\"...\"];\n", name)
+			return name, false, nil
+		}
+		name := fmt.Sprintf("n%d", node.pointer)
+		if written[name] {
+			return name, false, nil
+		}
+		if ipLen <= int(node.bit>>3) {
+			return "", false, newInvalidDatabaseError(
+				"invalid search tree at %v/%v", net.IP(node.ip[:ipLen]), node.bit)
+		}
+		network := &net.IPNet{
+			IP:   net.IP(node.ip[:ipLen]),
+			Mask: net.CIDRMask(int(node.bit), ipLen*8),
+		}
+		fmt.Fprintf(bw, "\t%s [label=\"%d\\n%s\"];\n", name, node.pointer, network)
+		written[name] = true
+		return name, true, nil
+	}
+
+	root, expand, err := vertex(start)
+	if err != nil {
+		return err
+	}
+	var nodes []netNode
+	var names []string
+	if expand {
+		nodes = append(nodes, start)
+		names = append(names, root)
+	}
+	for len(nodes) > 0 {
+		node, name := nodes[len(nodes)-1], names[len(names)-1]
+		nodes, names = nodes[:len(nodes)-1], names[:len(names)-1]
+
+		for bit := uint(0); bit < 2; bit++ {
+			child := netNode{ip: node.ip, bit: node.bit + 1}
+			if bit == 1 {
+				child.ip[node.bit>>3] |= 1 << (7 - (node.bit % 8))
+			}
+			child.pointer, err = r.readNode(node.pointer, bit)
+			if err != nil {
+				return err
+			}
+			childName, expand, err := vertex(child)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(bw, "\t%s -> %s [label=\"%d\"];\n", name, childName, bit)
+			if expand {
+				nodes = append(nodes, child)
+				names = append(names, childName)
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotLabel returns the label of the data leaf for pointer.
+func (r *Reader) dotLabel(pointer uint, path []interface{}) (string, error) {
+	offset, err := r.resolveDataPointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	label := fmt.Sprintf("@%d", offset)
+	if len(path) == 0 {
+		return label, nil
+	}
+	d := r.decoder
+	valueOffset, ok, err := d.findPath(uint(offset), path...)
+	if !ok || err != nil {
+		return label, nil
+	}
+	var value interface{}
+	if _, err := d.decode(valueOffset, reflect.ValueOf(&value)); err != nil {
+		return "", err
+	}
+	return fmt.Sprint(value), nil
+}
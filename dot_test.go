@@ -0,0 +1,92 @@
+package maxminddb
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestWriteDOT(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	_, network, _ := net.ParseCIDR("1.1.1.0/29")
+	var buf bytes.Buffer
+	err = reader.WriteDOT(&buf, DOTOptions{Network: network, LabelPath: []string{"ip"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := buf.String()
+	if !strings.HasPrefix(graph, "digraph mmdb {\n") || !strings.HasSuffix(graph, "}\n") {
+		t.Errorf("unexpected graph:\n%s", graph)
+	}
+	for _, expected := range []string{`label="1.1.1.1"`, `label="1.1.1.2"`, `label="1.1.1.4"`, `\n1.1.1.0/29"`, "empty [shape=point]"} {
+		if !strings.Contains(graph, expected) {
+			t.Errorf("expected %s in graph:\n%s", expected, graph)
+		}
+	}
+	if strings.Contains(graph, `label="1.1.1.8"`) {
+		t.Errorf("expected the graph to be limited to the network:\n%s", graph)
+	}
+}
+
+func TestWriteDOTMaxDepth(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-mixed-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	if err := reader.WriteDOT(&buf, DOTOptions{MaxDepth: 3}); err != nil {
+		t.Fatal(err)
+	}
+	graph := buf.String()
+	if !strings.Contains(graph, `label="..."`) {
+		t.Errorf("expected truncated subtrees in graph:\n%s", graph)
+	}
+	internal := 0
+	for _, line := range strings.Split(graph, "\n") {
+		if strings.HasPrefix(line, "\tn") && strings.Contains(line, " [label=") && !strings.Contains(line, " -> ") {
+			internal++
+		}
+	}
+	// The right halves of ::/0 and ::/1 are empty.
+	if internal != 3 {
+		t.Errorf("expected 3 internal nodes in the first 3 levels, got %d:\n%s", internal, graph)
+	}
+	if edges := strings.Count(graph, " -> "); edges != 2*internal {
+		t.Errorf("expected 2 edges per internal node, got %d:\n%s", edges, graph)
+	}
+
+	// The whole tree has aliases of the IPv4 subtree, whose root must be
+	// declared once.
+	buf.Reset()
+	if err := reader.WriteDOT(&buf, DOTOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	declaration := fmt.Sprintf("\tn%d [label=", reader.ipv4Start)
+	if count := strings.Count(buf.String(), declaration); count != 1 {
+		t.Errorf("expected the IPv4 subtree to be declared once, got %d", count)
+	}
+}
+
+func TestWriteDOTClosed(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := reader.WriteDOT(&buf, DOTOptions{}); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
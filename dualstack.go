@@ -0,0 +1,56 @@
+package maxminddb
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+)
+
+// LookupBoth looks up the IPv4 and IPv6 addresses of a dual-stack client in
+// one call, decoding their records into v4Result and v6Result. An invalid
+// (zero) address is skipped, leaving its result untouched. Zoned addresses
+// are handled according to the ZonePolicy of the Reader.
+//
+// When both addresses map to the same record, as is common for clients of
+// a single network, the record is decoded only once and v6Result is set to
+// a shallow copy of v4Result: they then share any maps and slices.
+func (r *Reader) LookupBoth(v4, v6 netip.Addr, v4Result, v6Result interface{}) error {
+	v4Offset, err := r.optionalAddrOffset(v4)
+	if err != nil {
+		return err
+	}
+	v6Offset, err := r.optionalAddrOffset(v6)
+	if err != nil {
+		return err
+	}
+
+	if v4Offset != NotFound {
+		if err := r.Decode(v4Offset, v4Result); err != nil {
+			return err
+		}
+	}
+	if v6Offset == NotFound {
+		return nil
+	}
+
+	if v6Offset == v4Offset {
+		src, dst := reflect.ValueOf(v4Result), reflect.ValueOf(v6Result)
+		if dst.Kind() != reflect.Ptr || dst.IsNil() {
+			return errors.New("result param must be a pointer")
+		}
+		if src.Type() == dst.Type() {
+			dst.Elem().Set(src.Elem())
+			return nil
+		}
+	}
+	return r.Decode(v6Offset, v6Result)
+}
+
+// optionalAddrOffset is like LookupAddrOffset but returns NotFound for the
+// zero address.
+func (r *Reader) optionalAddrOffset(addr netip.Addr) (uintptr, error) {
+	if !addr.IsValid() {
+		return NotFound, nil
+	}
+	return r.LookupAddrOffset(addr)
+}
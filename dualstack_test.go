@@ -0,0 +1,55 @@
+package maxminddb
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestLookupBoth(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv6-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var v4Result, v6Result map[string]string
+	err = reader.LookupBoth(
+		netip.MustParseAddr("1.1.1.1"),
+		netip.MustParseAddr("::2:0:1"),
+		&v4Result,
+		&v6Result,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v4Result != nil {
+		t.Errorf("expected no IPv4 record, got %v", v4Result)
+	}
+	if v6Result["ip"] != "::2:0:0" {
+		t.Errorf("unexpected IPv6 record: %v", v6Result)
+	}
+
+	// Both addresses in the same network share the record.
+	var first, second map[string]string
+	err = reader.LookupBoth(
+		netip.MustParseAddr("::1:ffff:ffff"),
+		netip.MustParseAddr("::1:ffff:ffff"),
+		&first,
+		&second,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first["ip"] != "::1:ffff:ffff" || second["ip"] != "::1:ffff:ffff" {
+		t.Errorf("unexpected records: %v, %v", first, second)
+	}
+
+	// Invalid addresses are skipped.
+	var skipped map[string]string
+	if err := reader.LookupBoth(netip.Addr{}, netip.Addr{}, &skipped, &skipped); err != nil {
+		t.Fatal(err)
+	}
+	if skipped != nil {
+		t.Errorf("expected no record, got %v", skipped)
+	}
+}
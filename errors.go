@@ -1,22 +1,84 @@
 package maxminddb
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 )
 
+// ErrLinkLocal is returned when looking up a zoned IPv6 address with the
+// ZoneLinkLocal policy.
+var ErrLinkLocal = errors.New("maxminddb: zoned address is link-local")
+
+// ErrClosed is returned when using a Reader after it was closed.
+var ErrClosed = errors.New("maxminddb: reader is closed")
+
 // InvalidDatabaseError is returned when the database contains invalid data
-// and cannot be parsed.
+// and cannot be parsed. Errors found while decoding a value locate it, so
+// that the corrupt bytes can be reported; errors in the search tree or the
+// file layout do not.
 type InvalidDatabaseError struct {
-	message string
+	// Offset is the offset of the innermost value being decoded when the
+	// error was found, relative to the start of the data section, or of the
+	// metadata if the metadata is invalid. It is -1 if the error is not
+	// about a value.
+	Offset int
+
+	// ControlByte is the control byte of the value at Offset and Kind its
+	// type. Kind is KindExtended if the extended type byte is past the end
+	// of the data, and both are zero if the control byte itself is.
+	ControlByte byte
+	Kind        Kind
+
+	// Message describes the problem. It is also the text of Error.
+	Message string
 }
 
 func newInvalidDatabaseError(format string, args ...interface{}) InvalidDatabaseError {
-	return InvalidDatabaseError{fmt.Sprintf(format, args...)}
+	return InvalidDatabaseError{Offset: -1, Message: fmt.Sprintf(format, args...)}
 }
 
 func (e InvalidDatabaseError) Error() string {
-	return e.message
+	return e.Message
+}
+
+// locate adds the position of the value at offset to err if it is an
+// InvalidDatabaseError without one. Since values are located as the error
+// returns through the decoder, the innermost value is kept.
+func (d *decoder) locate(err error, offset uint) error {
+	invalid, ok := err.(InvalidDatabaseError)
+	if !ok || invalid.Offset >= 0 {
+		return err
+	}
+	invalid.Offset = int(offset)
+	if offset < uint(len(d.buffer)) {
+		invalid.ControlByte = d.buffer[offset]
+		invalid.Kind = Kind(invalid.ControlByte >> 5)
+		if invalid.Kind == KindExtended && offset+1 < uint(len(d.buffer)) {
+			invalid.Kind = Kind(d.buffer[offset+1]) + 7
+		}
+	}
+	return invalid
+}
+
+// AddressParseError is returned when a textual IP address passed to the
+// Reader cannot be parsed.
+type AddressParseError struct {
+	Address string // the address that could not be parsed
+}
+
+func (e AddressParseError) Error() string {
+	return fmt.Sprintf("maxminddb: invalid IP address %q", e.Address)
+}
+
+// ZonedAddressError is returned when looking up a zoned IPv6 address with
+// the ZoneReject policy.
+type ZonedAddressError struct {
+	Address string // the address, including its zone
+}
+
+func (e ZonedAddressError) Error() string {
+	return fmt.Sprintf("maxminddb: IP address %q has a zone", e.Address)
 }
 
 // UnmarshalTypeError is returned when the value in the database cannot be
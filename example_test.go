@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/netip"
 
 	"github.com/oschwald/maxminddb-golang"
 )
@@ -155,3 +156,96 @@ func ExampleReader_Networks() {
 	// 2003::/24: Cable/DSL
 
 }
+
+// This example shows how to cache results by network: every address of the
+// returned network has the same record.
+func ExampleReader_LookupNetwork() {
+	db, err := maxminddb.Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	var city maxminddb.City
+	network, ok, err := db.LookupNetwork(net.ParseIP("81.2.69.142"), &city)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(network, ok, city.Country.IsoCode, city.City.Names["en"])
+	// Output:
+	// 81.2.69.142/31 true GB London
+}
+
+// This example demonstrates how to iterate over the networks of the
+// database within a network
+func ExampleReader_NetworksWithin() {
+	db, err := maxminddb.Open("test-data/test-data/GeoIP2-Country-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	_, within, err := net.ParseCIDR("81.2.69.0/24")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var record struct {
+		Country struct {
+			ISOCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	networks := db.NetworksWithin(within)
+	for networks.Next() {
+		network, err := networks.Network(&record)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: %s\n", network, record.Country.ISOCode)
+	}
+	if networks.Err() != nil {
+		log.Fatal(networks.Err())
+	}
+	// Output:
+	// ::5102:45a0/123: GB
+}
+
+// This example shows how to read a single value of a record without
+// decoding the rest of it
+func ExampleReader_DecodePath() {
+	db, err := maxminddb.Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	offset, err := db.LookupOffset(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	var subdivision string
+	err = db.DecodePath(offset, &subdivision, "subdivisions", -1, "iso_code")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Print(subdivision)
+	// Output:
+	// ENG
+}
+
+// This example shows how to look up a record of a known type
+func ExampleLookup() {
+	db, err := maxminddb.Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	city, err := maxminddb.Lookup[maxminddb.City](db, netip.MustParseAddr("2001:218::1"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(city.Country.IsoCode, city.Location.TimeZoneName)
+	// Output:
+	// JP Asia/Tokyo
+}
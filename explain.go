@@ -0,0 +1,152 @@
+package maxminddb
+
+import (
+	"net"
+	"reflect"
+)
+
+// TraceStep is a step of the search tree walk of a lookup.
+type TraceStep struct {
+	Node uint // the node read
+	Bit  uint // the bit of the address, selecting the left (0) or right record
+	Next uint // the value of the record: a node, the node count or a data pointer
+}
+
+// LookupTrace describes how a lookup went through the search tree.
+type LookupTrace struct {
+	Steps []TraceStep
+	// Network is the network of the search tree containing the address.
+	Network *net.IPNet
+	// Offset is the offset of the record in the data section, or NotFound.
+	Offset uintptr
+}
+
+// TraceLookup looks up ipAddress like LookupOffset, recording each node of
+// the search tree it goes through. It is meant for debugging and support
+// tools, and is slower than a regular lookup.
+func (r *Reader) TraceLookup(ipAddress net.IP) (LookupTrace, error) {
+	ipAddress, err := r.normalizeAddress(ipAddress)
+	if err != nil {
+		return LookupTrace{}, err
+	}
+
+	trace := LookupTrace{Offset: NotFound}
+	pointer, prefixLength, err := r.findAddressInTree(ipAddress, &trace.Steps)
+	if err != nil {
+		return LookupTrace{}, err
+	}
+	mask := net.CIDRMask(int(prefixLength), len(ipAddress)*8)
+	trace.Network = &net.IPNet{IP: ipAddress.Mask(mask), Mask: mask}
+	if pointer != 0 {
+		if trace.Offset, err = r.resolveDataPointer(pointer); err != nil {
+			return LookupTrace{}, err
+		}
+	}
+	return trace, nil
+}
+
+// RawValue returns the bytes encoding the value at offset in the data
+// section. Pointers within the value are included as such, not the values
+// they point to.
+func (r *Reader) RawValue(offset uintptr) ([]byte, error) {
+	if r.buffer == nil {
+		return nil, ErrClosed
+	}
+	if offset >= uintptr(len(r.decoder.buffer)) {
+		return nil, newInvalidDatabaseError("offset %d is outside of the data section", offset)
+	}
+	end, err := r.decoder.nextValueOffset(uint(offset), 1)
+	if err != nil {
+		return nil, err
+	}
+	if end > uint(len(r.decoder.buffer)) {
+		return nil, newInvalidDatabaseError("the value at offset %d ends outside of the data section", offset)
+	}
+	return r.decoder.buffer[offset:end], nil
+}
+
+// TypedValue is a value of the data section along with its type. The Value
+// of maps is a map[string]TypedValue, that of arrays a []TypedValue, and
+// that of other types is decoded as when decoding into an interface{}.
+type TypedValue struct {
+	Kind  Kind
+	Value interface{}
+}
+
+// DecodeTyped decodes the value at offset, keeping the type of each value,
+// e.g., to tell a uint16 from a uint32. Pointers are followed.
+func (r *Reader) DecodeTyped(offset uintptr) (TypedValue, error) {
+	if r.buffer == nil {
+		return TypedValue{}, ErrClosed
+	}
+	// The decoder counts the pointers it follows, so use a copy.
+	d := r.decoder
+	value, _, err := d.decodeTyped(uint(offset))
+	return value, err
+}
+
+func (d *decoder) decodeTyped(offset uint) (TypedValue, uint, error) {
+	kind, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		return TypedValue{}, 0, err
+	}
+	if kind == KindPointer {
+		pointer, ptrOffset, err := d.followPointer(size, newOffset)
+		if err != nil {
+			return TypedValue{}, 0, err
+		}
+		if d.pointers >= maxPointerDepth {
+			return TypedValue{}, 0, newPointerCycleError(pointer)
+		}
+		d.pointers++
+		value, _, err := d.decodeTyped(pointer)
+		d.pointers--
+		return value, ptrOffset, err
+	}
+
+	if err := d.checkContainerSize(kind, size, newOffset); err != nil {
+		return TypedValue{}, 0, err
+	}
+	switch kind {
+	case KindMap:
+		if err := d.enter(); err != nil {
+			return TypedValue{}, 0, err
+		}
+		defer func() { d.depth-- }()
+		values := make(map[string]TypedValue, size)
+		for i := uint(0); i < size; i++ {
+			key, valueOffset, err := d.decodeKeyString(newOffset)
+			if err != nil {
+				return TypedValue{}, 0, err
+			}
+			var value TypedValue
+			value, newOffset, err = d.decodeTyped(valueOffset)
+			if err != nil {
+				return TypedValue{}, 0, err
+			}
+			values[key] = value
+		}
+		return TypedValue{Kind: kind, Value: values}, newOffset, nil
+	case KindSlice:
+		if err := d.enter(); err != nil {
+			return TypedValue{}, 0, err
+		}
+		defer func() { d.depth-- }()
+		values := make([]TypedValue, size)
+		for i := range values {
+			var err error
+			values[i], newOffset, err = d.decodeTyped(newOffset)
+			if err != nil {
+				return TypedValue{}, 0, err
+			}
+		}
+		return TypedValue{Kind: kind, Value: values}, newOffset, nil
+	default:
+		var value interface{}
+		newOffset, err := d.decodeFromType(kind, size, newOffset, reflect.ValueOf(&value).Elem())
+		if err != nil {
+			return TypedValue{}, 0, err
+		}
+		return TypedValue{Kind: kind, Value: value}, newOffset, nil
+	}
+}
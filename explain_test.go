@@ -0,0 +1,107 @@
+package maxminddb
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+func TestTraceLookup(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	trace, err := reader.TraceLookup(net.ParseIP("1.1.1.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Network.String() != "1.1.1.2/31" || len(trace.Steps) != 31 {
+		t.Errorf("unexpected trace: %+v", trace)
+	}
+	last := trace.Steps[len(trace.Steps)-1]
+	if last.Next <= reader.Metadata.NodeCount {
+		t.Errorf("expected the last step to point to data, got %+v", last)
+	}
+	offset, err := reader.LookupOffset(net.ParseIP("1.1.1.3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Offset != offset {
+		t.Errorf("expected offset %d, got %d", offset, trace.Offset)
+	}
+
+	trace, err = reader.TraceLookup(net.ParseIP("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trace.Offset != NotFound {
+		t.Errorf("expected no record, got %+v", trace)
+	}
+}
+
+func TestDecodeTyped(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	offset, err := reader.LookupOffset(net.ParseIP("::1.1.1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := reader.DecodeTyped(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value.Kind != KindMap {
+		t.Fatalf("expected a map, got %v", value.Kind)
+	}
+	record := value.Value.(map[string]TypedValue)
+
+	kinds := map[string]Kind{
+		"array":       KindSlice,
+		"boolean":     KindBool,
+		"bytes":       KindBytes,
+		"double":      KindFloat64,
+		"float":       KindFloat32,
+		"int32":       KindInt32,
+		"map":         KindMap,
+		"uint16":      KindUint16,
+		"uint32":      KindUint32,
+		"uint64":      KindUint64,
+		"uint128":     KindUint128,
+		"utf8_string": KindString,
+	}
+	for key, kind := range kinds {
+		if record[key].Kind != kind {
+			t.Errorf("expected %s to be a %v, got %v", key, kind, record[key].Kind)
+		}
+	}
+	if record["uint16"].Value != uint64(100) {
+		t.Errorf("unexpected uint16 value %v", record["uint16"].Value)
+	}
+
+	raw, err := reader.RawValue(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(raw) == 0 || Kind(raw[0]>>5) != KindMap {
+		t.Errorf("unexpected raw value %x", raw)
+	}
+}
+
+func TestDecodeTypedOversizedContainer(t *testing.T) {
+	// A map and an array claiming about 16.8 million entries.
+	for _, input := range []string{"ffffffff", "1f04ffffff"} {
+		inputBytes, _ := hex.DecodeString(input)
+		d := decoder{buffer: inputBytes}
+		if _, _, err := d.decodeTyped(0); err == nil {
+			t.Errorf("%s: expected an error", input)
+		} else if _, ok := err.(InvalidDatabaseError); !ok {
+			t.Errorf("%s: expected an InvalidDatabaseError, got %v", input, err)
+		}
+	}
+}
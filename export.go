@@ -0,0 +1,120 @@
+package maxminddb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+)
+
+// defaultExportBufferSize is the buffer size of Export if ExportOptions sets
+// none.
+const defaultExportBufferSize = 64 << 10
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// Network limits the export to the networks within it. The whole
+	// database is exported if it is nil.
+	Network *net.IPNet
+
+	// BufferSize is the number of bytes buffered before they are written
+	// to the writer, 64 KiB if it is 0. A single line longer than the
+	// buffer is written as it is encoded, so memory stays bounded by the
+	// buffer and the largest record, however large the database.
+	BufferSize int
+
+	// FlushEvery, if positive, flushes the buffer every FlushEvery
+	// networks, so that a slow consumer receives data at a steady pace
+	// rather than in bursts of BufferSize bytes.
+	FlushEvery int
+
+	// Flush, if set, is called after each flush of the buffer, e.g., with
+	// the Flush method of an http.Flusher or a compressing writer, so that
+	// the data buffered downstream is pushed out as well.
+	Flush func() error
+}
+
+// exportLine is a line written by Export.
+type exportLine struct {
+	Network string      `json:"network"`
+	Record  interface{} `json:"record"`
+}
+
+// Export writes the networks of the database and their records to w as
+// JSON lines of the form {"network":"1.0.0.0/24","record":{...}}. Networks
+// aliased to the IPv4 subtree of an IPv6 database are left out, and those
+// of the subtree, ::/96, are written as IPv4 networks.
+//
+// Networks are read, decoded and written one at a time and writes to w
+// block the export, so a slow writer such as a network connection slows it
+// down instead of making it buffer the database in memory. Export stops and
+// returns the error of ctx once it is canceled. Data already buffered is
+// flushed before Export returns, even on error. With WithProfilerLabels,
+// the export is labeled as a scan on top of the labels of ctx.
+func (r *Reader) Export(ctx context.Context, w io.Writer, options ExportOptions) error {
+	if r.labels != nil {
+		return r.labeled(ctx, "scan", func() error {
+			return r.export(ctx, w, options)
+		})
+	}
+	return r.export(ctx, w, options)
+}
+
+func (r *Reader) export(ctx context.Context, w io.Writer, options ExportOptions) (err error) {
+	size := options.BufferSize
+	if size <= 0 {
+		size = defaultExportBufferSize
+	}
+	bw := bufio.NewWriterSize(w, size)
+	flush := func() error {
+		if err := bw.Flush(); err != nil {
+			return err
+		}
+		if options.Flush != nil {
+			return options.Flush()
+		}
+		return nil
+	}
+	defer func() {
+		if flushErr := flush(); err == nil {
+			err = flushErr
+		}
+	}()
+
+	encoder := json.NewEncoder(bw)
+	encoder.SetEscapeHTML(false)
+
+	var networks *Networks
+	if options.Network != nil {
+		networks = r.NetworksWithin(options.Network, SkipAliasedNetworks())
+	} else {
+		networks = r.Networks(SkipAliasedNetworks())
+	}
+	for count := 1; networks.Next(); count++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var line exportLine
+		network, err := networks.Network(&line.Record)
+		if err != nil {
+			return err
+		}
+		prefix, err := NetworkToPrefix(network)
+		if err != nil {
+			return err
+		}
+		line.Network = ipv4SubtreePrefix(prefix).String()
+		if err := encoder.Encode(line); err != nil {
+			return err
+		}
+
+		if options.FlushEvery > 0 && count%options.FlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return networks.Err()
+}
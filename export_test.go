@@ -0,0 +1,95 @@
+package maxminddb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	flushes := 0
+	err = reader.Export(context.Background(), &buf, ExportOptions{
+		FlushEvery: 2,
+		Flush: func() error {
+			flushes++
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	expected := []string{
+		`{"network":"1.1.1.1/32","record":{"ip":"1.1.1.1"}}`,
+		`{"network":"1.1.1.2/31","record":{"ip":"1.1.1.2"}}`,
+		`{"network":"1.1.1.4/30","record":{"ip":"1.1.1.4"}}`,
+		`{"network":"1.1.1.8/29","record":{"ip":"1.1.1.8"}}`,
+		`{"network":"1.1.1.16/28","record":{"ip":"1.1.1.16"}}`,
+		`{"network":"1.1.1.32/32","record":{"ip":"1.1.1.32"}}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), buf.String())
+	}
+	// Three flushes every two networks and the final one.
+	if flushes != 4 {
+		t.Errorf("expected 4 flushes, got %d", flushes)
+	}
+}
+
+func TestExportWithin(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-Country-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	_, network, _ := net.ParseCIDR("81.2.69.0/24")
+	var buf bytes.Buffer
+	if err := reader.Export(context.Background(), &buf, ExportOptions{Network: network}); err != nil {
+		t.Fatal(err)
+	}
+
+	var line struct {
+		Network string `json:"network"`
+		Record  struct {
+			Country struct {
+				IsoCode string `json:"iso_code"`
+			} `json:"country"`
+		} `json:"record"`
+	}
+	if err := json.NewDecoder(&buf).Decode(&line); err != nil {
+		t.Fatal(err)
+	}
+	if line.Network != "81.2.69.160/27" || line.Record.Country.IsoCode != "GB" {
+		t.Errorf("expected 81.2.69.160/27 in GB, got %s in %q", line.Network, line.Record.Country.IsoCode)
+	}
+}
+
+func TestExportCanceled(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var buf bytes.Buffer
+	if err := reader.Export(ctx, &buf, ExportOptions{}); err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %q", buf.String())
+	}
+}
@@ -0,0 +1,232 @@
+package maxminddb
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// failoverRetryInterval is how long a FailoverReader waits before trying to
+// open a database file again after it failed to.
+const failoverRetryInterval = 10 * time.Second
+
+// FailoverEvent describes a database file of a FailoverReader that started
+// failing.
+type FailoverEvent struct {
+	// From is the path of the database file that failed.
+	From string
+	// To is the path of the database file tried next, or "" if there is
+	// none left.
+	To string
+	// Err is the error that caused the failover.
+	Err error
+}
+
+// FailoverReader serves lookups from the first of several copies of a
+// database that works, e.g., a copy on a network file system and a local
+// one. Files that cannot be read are tried again after a while, and
+// lookups failing because of an invalid database are retried on the next
+// file. It is safe for concurrent use.
+//
+// The files are read into memory rather than memory-mapped, so that I/O
+// errors of unreliable storage are returned when reading a file, and fail
+// over to the next one, instead of crashing the process in a lookup.
+type FailoverReader struct {
+	sources    []failoverSource
+	onFailover func(FailoverEvent)
+	options    []ReaderOption
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+type failoverSource struct {
+	path     string
+	reader   *Reader
+	err      error
+	failedAt time.Time
+	// failing is set when the file fails and cleared when it serves a
+	// lookup, so that a failover is reported once per outage.
+	failing bool
+}
+
+// NewFailoverReader returns a FailoverReader for the database files at
+// paths, in order of preference, opened with options. onFailover, if not
+// nil, is called synchronously when a file starts failing, and not again
+// for that file until it has served a lookup. An error is returned only if
+// no file can be opened.
+func NewFailoverReader(paths []string, onFailover func(FailoverEvent), options ...ReaderOption) (*FailoverReader, error) {
+	f := &FailoverReader{onFailover: onFailover, options: options}
+	for _, path := range paths {
+		f.sources = append(f.sources, failoverSource{path: path})
+	}
+
+	err := errors.New("maxminddb: no database file to fail over to")
+	opened := false
+	for i := range f.sources {
+		if _, openErr := f.reader(i); openErr != nil {
+			err = openErr
+		} else {
+			opened = true
+		}
+	}
+	if !opened {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Lookup looks up ipAddress like Reader.Lookup in the first database file
+// that can serve it. Errors that do not come from the database, such as
+// looking up an IPv6 address in an IPv4 database or decoding into a result
+// of the wrong type, are returned without failing over. Values decoded from
+// a failing file may be left in result.
+func (f *FailoverReader) Lookup(ipAddress net.IP, result interface{}) error {
+	return f.withReader(func(reader *Reader) error {
+		return reader.Lookup(ipAddress, result)
+	})
+}
+
+// LookupNetwork is like Lookup but also returns the network of the record,
+// as Reader.LookupNetwork does.
+func (f *FailoverReader) LookupNetwork(ipAddress net.IP, result interface{}) (network *net.IPNet, ok bool, err error) {
+	err = f.withReader(func(reader *Reader) error {
+		var err error
+		network, ok, err = reader.LookupNetwork(ipAddress, result)
+		return err
+	})
+	return network, ok, err
+}
+
+// DatabaseMetadata returns the metadata of the first database file that is
+// open, or the zero Metadata if none is.
+func (f *FailoverReader) DatabaseMetadata() Metadata {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	for _, source := range f.sources {
+		if source.reader != nil {
+			return source.reader.Metadata
+		}
+	}
+	return Metadata{}
+}
+
+// withReader calls lookup with the Reader of each database file in turn,
+// until it returns nil or an error that does not come from the database.
+func (f *FailoverReader) withReader(lookup func(*Reader) error) error {
+	var err error
+	for i := range f.sources {
+		var reader *Reader
+		reader, err = f.reader(i)
+		if errors.Is(err, ErrClosed) {
+			return err
+		}
+		if err == nil {
+			err = lookup(reader)
+			var invalid InvalidDatabaseError
+			if err == nil || !errors.As(err, &invalid) {
+				f.setFailing(i, false)
+				return err
+			}
+		}
+		if f.setFailing(i, true) {
+			f.failover(i, err)
+		}
+	}
+	return err
+}
+
+// reader returns the Reader of the i-th source, reading its file if
+// needed, or ErrClosed once the FailoverReader is closed.
+func (f *FailoverReader) reader(i int) (*Reader, error) {
+	f.mu.RLock()
+	source, closed := f.sources[i], f.closed
+	f.mu.RUnlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	if source.reader != nil {
+		return source.reader, nil
+	}
+	if !source.failedAt.IsZero() && time.Since(source.failedAt) < failoverRetryInterval {
+		return nil, source.err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return nil, ErrClosed
+	}
+	s := &f.sources[i]
+	if s.reader != nil {
+		return s.reader, nil
+	}
+	reader, err := f.open(s.path)
+	if err != nil {
+		s.err = err
+		s.failedAt = time.Now()
+		return nil, err
+	}
+	s.reader = reader
+	return reader, nil
+}
+
+func (f *FailoverReader) open(path string) (*Reader, error) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return FromBytes(buffer, f.options...)
+}
+
+// setFailing sets whether the i-th source is failing and reports whether it
+// started failing.
+func (f *FailoverReader) setFailing(i int, failing bool) bool {
+	f.mu.RLock()
+	unchanged := f.sources[i].failing == failing
+	f.mu.RUnlock()
+	if unchanged {
+		return false
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s := &f.sources[i]
+	if s.failing == failing {
+		return false
+	}
+	s.failing = failing
+	return failing
+}
+
+func (f *FailoverReader) failover(i int, err error) {
+	if f.onFailover == nil {
+		return
+	}
+	event := FailoverEvent{From: f.sources[i].path, Err: err}
+	if i+1 < len(f.sources) {
+		event.To = f.sources[i+1].path
+	}
+	f.onFailover(event)
+}
+
+// Close closes the database files. Lookups then return ErrClosed. It must
+// not be called concurrently with Lookup.
+func (f *FailoverReader) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	var err error
+	for i := range f.sources {
+		if f.sources[i].reader == nil {
+			continue
+		}
+		if closeErr := f.sources[i].reader.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		f.sources[i].reader = nil
+	}
+	return err
+}
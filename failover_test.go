@@ -0,0 +1,109 @@
+package maxminddb
+
+import (
+	"errors"
+	"net"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFailoverReader(t *testing.T) {
+	paths := []string{
+		"test-data/test-data/missing.mmdb",
+		"test-data/test-data/MaxMind-DB-test-broken-search-tree-24.mmdb",
+		"test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb",
+	}
+	var events []FailoverEvent
+	reader, err := NewFailoverReader(paths, func(event FailoverEvent) {
+		events = append(events, event)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The search tree of the second file is broken for this address.
+	var result map[string]string
+	if err := reader.Lookup(net.ParseIP("128.128.128.128"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 failovers, got %v", events)
+	}
+	if events[0].From != paths[0] || events[0].To != paths[1] || !errors.Is(events[0].Err, os.ErrNotExist) {
+		t.Errorf("unexpected first failover: %+v", events[0])
+	}
+	var invalid InvalidDatabaseError
+	if events[1].From != paths[1] || events[1].To != paths[2] || !errors.As(events[1].Err, &invalid) {
+		t.Errorf("unexpected second failover: %+v", events[1])
+	}
+
+	// Files that keep failing are not reported again.
+	events = nil
+	if err := reader.Lookup(net.ParseIP("128.128.128.128"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Lookup(net.ParseIP("1.1.1.1"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(result, map[string]string{"ip": "1.1.1.1"}) {
+		t.Errorf("unexpected result: %v", result)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no failover while the files keep failing, got %v", events)
+	}
+
+	// Errors that do not come from the database are not retried.
+	if err := reader.Lookup(net.ParseIP("2001:db8::1"), &result); err == nil {
+		t.Error("expected an error looking up an IPv6 address in an IPv4 database")
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no failover, got %v", events)
+	}
+
+	if err := reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Lookup(net.ParseIP("1.1.1.1"), &result); err != ErrClosed {
+		t.Errorf("expected ErrClosed after Close, got %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("expected no failover after Close, got %v", events)
+	}
+}
+
+func TestFailoverReaderWithoutDatabase(t *testing.T) {
+	_, err := NewFailoverReader([]string{"test-data/test-data/missing.mmdb"}, nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+	if _, err := NewFailoverReader(nil, nil); err == nil {
+		t.Error("expected an error without paths")
+	}
+}
+
+func TestFailoverReaderGeolocator(t *testing.T) {
+	var geolocator Geolocator
+	geolocator, err := NewFailoverReader([]string{
+		"test-data/test-data/MaxMind-DB-test-broken-search-tree-24.mmdb",
+		"test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer geolocator.Close()
+
+	if databaseType := geolocator.DatabaseMetadata().DatabaseType; databaseType != "Test" {
+		t.Errorf("unexpected database type %q", databaseType)
+	}
+
+	var result map[string]string
+	network, ok, err := geolocator.LookupNetwork(net.ParseIP("128.128.128.128"), &result)
+	if err != nil || ok {
+		t.Errorf("expected no record after failing over, got %v, %v, %v", network, ok, err)
+	}
+	network, ok, err = geolocator.LookupNetwork(net.ParseIP("1.1.1.3"), &result)
+	if err != nil || !ok || network.String() != "1.1.1.2/31" {
+		t.Errorf("unexpected network %v, %v, %v", network, ok, err)
+	}
+}
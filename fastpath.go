@@ -0,0 +1,250 @@
+package maxminddb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+)
+
+// CountryCode returns the country.iso_code of the record for ipAddress, as
+// found in the City and Country databases. It navigates the record without
+// reflection or intermediate maps, skipping everything else, which makes it
+// much faster than Lookup for this common use case. The bool is false if
+// there is no record, no country code, or if the lookup fails.
+func (r *Reader) CountryCode(ipAddress net.IP) (string, bool) {
+	offset, ok := r.fastLookup(ipAddress)
+	if !ok {
+		return "", false
+	}
+	d := r.decoder
+	offset, ok, err := d.findPath(offset, "country", "iso_code")
+	if !ok || err != nil {
+		return "", false
+	}
+	return d.stringAt(offset)
+}
+
+// ASN returns the autonomous_system_number and
+// autonomous_system_organization of the record for ipAddress, as found in
+// the GeoLite2-ASN and ISP databases. Like CountryCode, it extracts the two
+// fields directly. The bool is false if there is no record, no AS number,
+// or if the lookup fails. The organization may be empty.
+func (r *Reader) ASN(ipAddress net.IP) (uint32, string, bool) {
+	offset, ok := r.fastLookup(ipAddress)
+	if !ok {
+		return 0, "", false
+	}
+	d := r.decoder
+	numberOffset, ok, err := d.findPath(offset, "autonomous_system_number")
+	if !ok || err != nil {
+		return 0, "", false
+	}
+	number, ok := d.uint32At(numberOffset)
+	if !ok {
+		return 0, "", false
+	}
+
+	var organization string
+	if organizationOffset, ok, err := d.findPath(offset, "autonomous_system_organization"); ok && err == nil {
+		organization, _ = d.stringAt(organizationOffset)
+	}
+	return number, organization, true
+}
+
+// fastLookup returns the offset of the record for ipAddress in the data
+// section.
+func (r *Reader) fastLookup(ipAddress net.IP) (uint, bool) {
+	pointer, err := r.lookupPointer(ipAddress)
+	if pointer == 0 || err != nil {
+		return 0, false
+	}
+	offset, err := r.resolveDataPointer(pointer)
+	if err != nil {
+		return 0, false
+	}
+	return uint(offset), true
+}
+
+// resolve follows the pointers at offset, if any, and returns the type,
+// size and offset of the value's payload.
+func (d *decoder) resolve(offset uint) (Kind, uint, uint, bool) {
+	for {
+		kind, size, newOffset, err := d.decodeCtrlData(offset)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		if kind != KindPointer {
+			switch kind {
+			case KindMap, KindSlice, KindBool:
+			default:
+				if _, err := d.payloadEnd(size, newOffset); err != nil {
+					return 0, 0, 0, false
+				}
+			}
+			return kind, size, newOffset, true
+		}
+		if offset, _, err = d.followPointer(size, newOffset); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+}
+
+// DecodePath decodes the value at path in the record at offset into
+// result, which must be a pointer. The path is a sequence of map keys, given
+// as strings, and array indices, given as ints; negative indices count from
+// the end of the array. Only the value at the end of the path is decoded;
+// everything else is skipped without allocating. If the path does not exist
+// in the record or is omitted by the DecodeProfile of the Reader, result is
+// left unchanged and the error is nil. It is an error for a key to address
+// a value other than a map, or an index a value other than an array.
+//
+// For example, DecodePath(offset, &code, "country", "iso_code") reads the
+// country code of a City record and DecodePath(offset, &code,
+// "subdivisions", -1, "iso_code") that of its most specific subdivision.
+func (r *Reader) DecodePath(offset uintptr, result interface{}, path ...interface{}) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("result param must be a pointer")
+	}
+	if r.buffer == nil {
+		return ErrClosed
+	}
+
+	d := r.decoder
+	valueOffset, ok, err := d.findPath(uint(offset), path...)
+	if err != nil || !ok {
+		return err
+	}
+	_, err = d.decode(valueOffset, rv)
+	return err
+}
+
+// findPath returns the offset of the value at path, a sequence of map keys
+// and array indices, in the record at offset. The bool is false if the path
+// does not exist in the record or is omitted by the DecodeProfile. The path
+// is followed through the profile as well, and d is left at the profile
+// node of the value, so that decoding it with d omits what Reader.Decode
+// would. Callers use a copy of the decoder of the Reader.
+func (d *decoder) findPath(offset uint, path ...interface{}) (uint, bool, error) {
+	d.node = d.profile
+	for _, element := range path {
+		kind, size, newOffset, ok := d.resolve(offset)
+		if !ok {
+			return 0, false, newInvalidDatabaseError("unexpected end of database")
+		}
+
+		switch element := element.(type) {
+		case string:
+			if kind != KindMap {
+				return 0, false, fmt.Errorf("maxminddb: expected a map for key %q, found a %s", element, kind)
+			}
+			offset = newOffset
+			found := false
+			for i := uint(0); i < size; i++ {
+				keyKind, keySize, keyOffset, ok := d.resolve(offset)
+				if !ok || (keyKind != KindString && keyKind != KindBytes) {
+					return 0, false, newInvalidDatabaseError("invalid map key at offset %d", offset)
+				}
+				var err error
+				if offset, err = d.nextValueOffset(offset, 1); err != nil {
+					return 0, false, err
+				}
+				if string(d.buffer[keyOffset:keyOffset+keySize]) == element {
+					found = true
+					break
+				}
+				if offset, err = d.nextValueOffset(offset, 1); err != nil {
+					return 0, false, err
+				}
+			}
+			if !found {
+				return 0, false, nil
+			}
+			if _, omitted := d.enterKey(element); omitted {
+				return 0, false, nil
+			}
+		case int:
+			if kind != KindSlice {
+				return 0, false, fmt.Errorf("maxminddb: expected an array for index %d, found a %s", element, kind)
+			}
+			index := element
+			if index < 0 {
+				index += int(size)
+			}
+			if index < 0 || index >= int(size) {
+				return 0, false, nil
+			}
+			var err error
+			if offset, err = d.nextValueOffset(newOffset, uint(index)); err != nil {
+				return 0, false, err
+			}
+		default:
+			return 0, false, fmt.Errorf("maxminddb: invalid path element %v of type %T", element, element)
+		}
+	}
+	return offset, true, nil
+}
+
+func (d *decoder) stringAt(offset uint) (string, bool) {
+	kind, size, offset, ok := d.resolve(offset)
+	if !ok || kind != KindString || d.checkUTF8(offset, offset+size) != nil {
+		return "", false
+	}
+	return string(d.buffer[offset : offset+size]), true
+}
+
+func (d *decoder) uintAt(offset uint) (uint64, bool) {
+	kind, size, offset, ok := d.resolve(offset)
+	if !ok {
+		return 0, false
+	}
+	var uintType uint
+	switch kind {
+	case KindUint16:
+		uintType = 16
+	case KindUint32:
+		uintType = 32
+	case KindUint64:
+		uintType = 64
+	default:
+		return 0, false
+	}
+	value, _, err := d.decodeUint(size, offset, uintType)
+	return value, err == nil
+}
+
+func (d *decoder) boolAt(offset uint) (bool, bool) {
+	kind, size, offset, ok := d.resolve(offset)
+	if !ok || kind != KindBool {
+		return false, false
+	}
+	value, _, err := d.decodeBool(size, offset)
+	return value, err == nil
+}
+
+func (d *decoder) float64At(offset uint) (float64, bool) {
+	kind, size, offset, ok := d.resolve(offset)
+	if !ok {
+		return 0, false
+	}
+	switch kind {
+	case KindFloat64:
+		value, _, err := d.decodeFloat64(size, offset)
+		return value, err == nil
+	case KindFloat32:
+		value, _, err := d.decodeFloat32(size, offset)
+		return float64(value), err == nil
+	default:
+		return 0, false
+	}
+}
+
+func (d *decoder) uint32At(offset uint) (uint32, bool) {
+	value, ok := d.uintAt(offset)
+	if !ok || value > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(value), true
+}
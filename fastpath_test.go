@@ -0,0 +1,176 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestCountryCode(t *testing.T) {
+	for _, fileName := range []string{
+		"test-data/test-data/GeoIP2-Country-Test.mmdb",
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+	} {
+		reader, err := Open(fileName)
+		if err != nil {
+			t.Fatalf("unexpected error while opening database: %v", err)
+		}
+
+		for _, address := range []string{"81.2.69.160", "2001:218::1", "10.0.0.1", "::1.1.1.1"} {
+			var record struct {
+				Country struct {
+					IsoCode string `maxminddb:"iso_code"`
+				} `maxminddb:"country"`
+			}
+			if err := reader.Lookup(net.ParseIP(address), &record); err != nil {
+				t.Fatal(err)
+			}
+
+			code, ok := reader.CountryCode(net.ParseIP(address))
+			if code != record.Country.IsoCode || ok != (code != "") {
+				t.Errorf("%s: expected %q for %s, got %q, %v", fileName, record.Country.IsoCode, address, code, ok)
+			}
+		}
+
+		if code, ok := reader.CountryCode(net.ParseIP("81.2.69.160")); code != "GB" || !ok {
+			t.Errorf("%s: expected GB for 81.2.69.160, got %q, %v", fileName, code, ok)
+		}
+		reader.Close()
+	}
+}
+
+func TestASN(t *testing.T) {
+	for _, fileName := range []string{
+		"test-data/test-data/GeoLite2-ASN-Test.mmdb",
+		"test-data/test-data/GeoIP2-ISP-Test.mmdb",
+	} {
+		reader, err := Open(fileName)
+		if err != nil {
+			t.Fatalf("unexpected error while opening database: %v", err)
+		}
+
+		for _, address := range []string{"1.128.0.1", "2600:6000::1", "10.0.0.1"} {
+			var record struct {
+				Number       uint32 `maxminddb:"autonomous_system_number"`
+				Organization string `maxminddb:"autonomous_system_organization"`
+			}
+			if err := reader.Lookup(net.ParseIP(address), &record); err != nil {
+				t.Fatal(err)
+			}
+
+			number, organization, ok := reader.ASN(net.ParseIP(address))
+			if number != record.Number || organization != record.Organization || ok != (number != 0) {
+				t.Errorf("%s: expected %+v for %s, got %d, %q, %v", fileName, record, address, number, organization, ok)
+			}
+		}
+
+		if number, organization, ok := reader.ASN(net.ParseIP("1.128.0.1")); number != 1221 || organization != "Telstra Pty Ltd" || !ok {
+			t.Errorf("%s: unexpected ASN for 1.128.0.1: %d, %q, %v", fileName, number, organization, ok)
+		}
+		reader.Close()
+	}
+}
+
+func TestDecodePath(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	offset, err := reader.LookupOffset(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var code string
+	if err := reader.DecodePath(offset, &code, "country", "iso_code"); err != nil || code != "GB" {
+		t.Errorf("expected GB, got %q (%v)", code, err)
+	}
+	if err := reader.DecodePath(offset, &code, "subdivisions", -1, "iso_code"); err != nil || code != "ENG" {
+		t.Errorf("expected ENG, got %q (%v)", code, err)
+	}
+
+	var names map[string]string
+	if err := reader.DecodePath(offset, &names, "city", "names"); err != nil || names["en"] != "London" {
+		t.Errorf("unexpected names: %v (%v)", names, err)
+	}
+
+	code = "unchanged"
+	for _, path := range [][]interface{}{
+		{"country", "missing"},
+		{"subdivisions", 1, "iso_code"},
+		{"subdivisions", -2},
+	} {
+		if err := reader.DecodePath(offset, &code, path...); err != nil || code != "unchanged" {
+			t.Errorf("expected %v to be missing, got %q (%v)", path, code, err)
+		}
+	}
+
+	for _, path := range [][]interface{}{
+		{"country", 0},
+		{"subdivisions", "iso_code"},
+		{"country", 1.5},
+	} {
+		if err := reader.DecodePath(offset, &code, path...); err == nil {
+			t.Errorf("expected an error for %v", path)
+		}
+	}
+	if err := reader.DecodePath(offset, code, "country"); err == nil {
+		t.Error("expected an error for a non-pointer result")
+	}
+}
+
+func TestDecodePathWithProfile(t *testing.T) {
+	reader, err := Open(
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+		WithDecodeProfile(PreciseLocationProfile),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	offset, err := reader.LookupOffset(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var latitude float64
+	if err := reader.DecodePath(offset, &latitude, "location", "latitude"); err != nil || latitude != 0 {
+		t.Errorf("expected the latitude to be omitted, got %v (%v)", latitude, err)
+	}
+
+	var location map[string]interface{}
+	if err := reader.DecodePath(offset, &location, "location"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := location["latitude"]; ok {
+		t.Errorf("expected no latitude, got %v", location)
+	}
+	if location["time_zone"] != "Europe/London" {
+		t.Errorf("expected a time zone, got %v", location)
+	}
+
+	var code string
+	if err := reader.DecodePath(offset, &code, "country", "iso_code"); err != nil || code != "GB" {
+		t.Errorf("expected GB, got %q (%v)", code, err)
+	}
+}
+
+func BenchmarkCountryCodeFastPath(b *testing.B) {
+	db, err := Open("GeoLite2-City.mmdb")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer db.Close()
+
+	ips := make([]net.IP, 1024)
+	for i := range ips {
+		ips[i] = net.IPv4(byte(i), byte(i*7), byte(i*13), 1)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		db.CountryCode(ips[i%len(ips)])
+	}
+}
@@ -0,0 +1,121 @@
+package maxminddb
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// Flags is a compact set of boolean facts about an address, combining the
+// traits of a record and the classification of special-purpose ranges. It is
+// cheaper to store and filter on than a struct of bools.
+type Flags uint16
+
+// The individual Flags.
+const (
+	FlagAnonymousProxy Flags = 1 << iota
+	FlagSatelliteProvider
+	FlagAnycast
+	FlagPrivate
+	FlagLoopback
+	FlagLinkLocal
+	FlagMulticast
+	// FlagReserved is set for the other special-purpose ranges that are not
+	// routed on the public internet, e.g., documentation networks.
+	FlagReserved
+)
+
+var flagNames = []string{
+	"anonymous_proxy",
+	"satellite_provider",
+	"anycast",
+	"private",
+	"loopback",
+	"link_local",
+	"multicast",
+	"reserved",
+}
+
+// reservedPrefixes are the bogon ranges that the net.IP predicates do not
+// cover.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// Has reports whether all of flag are set in f.
+func (f Flags) Has(flag Flags) bool {
+	return f&flag == flag
+}
+
+// String returns the names of the set flags separated by "|", e.g.,
+// "anycast|reserved".
+func (f Flags) String() string {
+	var names []string
+	for i, name := range flagNames {
+		if f&(1<<uint(i)) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Flags returns the flags corresponding to the traits.
+func (t Traits) Flags() Flags {
+	var f Flags
+	if t.IsAnonymousProxy {
+		f |= FlagAnonymousProxy
+	}
+	if t.IsSatelliteProvider {
+		f |= FlagSatelliteProvider
+	}
+	if t.IsAnycast {
+		f |= FlagAnycast
+	}
+	return f
+}
+
+// AddressFlags classifies ipAddress into the special-purpose ranges. It
+// returns 0 for globally routable addresses and for invalid ones.
+func AddressFlags(ipAddress net.IP) Flags {
+	addr, ok := netip.AddrFromSlice(ipAddress)
+	if !ok {
+		return 0
+	}
+	addr = addr.Unmap()
+
+	var f Flags
+	switch {
+	case addr.IsPrivate():
+		f |= FlagPrivate
+	case addr.IsLoopback():
+		f |= FlagLoopback
+	case addr.IsLinkLocalUnicast(), addr.IsLinkLocalMulticast():
+		f |= FlagLinkLocal
+	}
+	if addr.IsMulticast() {
+		f |= FlagMulticast
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			f |= FlagReserved
+			break
+		}
+	}
+	return f
+}
+
+// Flags returns the flags of the record combined with those of ipAddress,
+// which should be the address the record was looked up for.
+func (c *City) Flags(ipAddress net.IP) Flags {
+	return c.Traits.Flags() | AddressFlags(ipAddress)
+}
@@ -0,0 +1,46 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestAddressFlags(t *testing.T) {
+	tests := map[string]Flags{
+		"81.2.69.160":    0,
+		"2001:218::1":    0,
+		"10.1.2.3":       FlagPrivate,
+		"fd00::1":        FlagPrivate,
+		"127.0.0.1":      FlagLoopback,
+		"::1":            FlagLoopback,
+		"169.254.0.1":    FlagLinkLocal,
+		"fe80::1":        FlagLinkLocal,
+		"224.0.0.1":      FlagMulticast | FlagLinkLocal,
+		"239.1.1.1":      FlagMulticast,
+		"192.0.2.1":      FlagReserved,
+		"::ffff:0.0.0.1": FlagReserved,
+		"2001:db8::1":    FlagReserved,
+	}
+	for address, expected := range tests {
+		if flags := AddressFlags(net.ParseIP(address)); flags != expected {
+			t.Errorf("expected %v for %s, got %v", expected, address, flags)
+		}
+	}
+	if flags := AddressFlags(nil); flags != 0 {
+		t.Errorf("expected no flags for a nil address, got %v", flags)
+	}
+}
+
+func TestFlags(t *testing.T) {
+	city := City{Traits: Traits{IsAnonymousProxy: true, IsAnycast: true}}
+	flags := city.Flags(net.ParseIP("192.0.2.1"))
+	if !flags.Has(FlagAnonymousProxy|FlagAnycast) || flags.Has(FlagSatelliteProvider) {
+		t.Errorf("unexpected flags: %v", flags)
+	}
+	if s := flags.String(); s != "anonymous_proxy|anycast|reserved" {
+		t.Errorf("unexpected string: %q", s)
+	}
+	if s := Flags(0).String(); s != "" {
+		t.Errorf("unexpected string for no flags: %q", s)
+	}
+}
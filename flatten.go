@@ -0,0 +1,82 @@
+package maxminddb
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// FlatRecord is a record flattened to string keys and values. Nested keys are
+// joined with dots and array elements are keyed by their index, e.g.,
+// "country.names.en" or "subdivisions.0.iso_code". It is suited for use in
+// HTTP headers, log fields or templates.
+type FlatRecord map[string]string
+
+// Values returns the record as url.Values, with one value per key.
+func (f FlatRecord) Values() url.Values {
+	values := make(url.Values, len(f))
+	for key, value := range f {
+		values.Set(key, value)
+	}
+	return values
+}
+
+// LookupFlat looks up ipAddress and returns its record flattened. A nil
+// FlatRecord is returned if there is no record for the address.
+func (r *Reader) LookupFlat(ipAddress net.IP) (FlatRecord, error) {
+	var record interface{}
+	if err := r.Lookup(ipAddress, &record); err != nil || record == nil {
+		return nil, err
+	}
+	return Flatten(record), nil
+}
+
+// Flatten flattens a record decoded into an interface{} value. Numbers and
+// booleans are formatted with strconv and bytes are hex encoded.
+func Flatten(record interface{}) FlatRecord {
+	flat := FlatRecord{}
+	flatten(flat, "", record)
+	return flat
+}
+
+func flatten(flat FlatRecord, prefix string, value interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + "." + key
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, element := range v {
+			flatten(flat, join(key), element)
+		}
+	case []interface{}:
+		for i, element := range v {
+			flatten(flat, join(strconv.Itoa(i)), element)
+		}
+	case string:
+		flat[prefix] = v
+	case bool:
+		flat[prefix] = strconv.FormatBool(v)
+	case uint64:
+		flat[prefix] = strconv.FormatUint(v, 10)
+	case int:
+		flat[prefix] = strconv.Itoa(v)
+	case float64:
+		flat[prefix] = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		flat[prefix] = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case []byte:
+		flat[prefix] = hex.EncodeToString(v)
+	case *big.Int:
+		flat[prefix] = v.String()
+	case nil:
+	default:
+		flat[prefix] = fmt.Sprint(v)
+	}
+}
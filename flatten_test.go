@@ -0,0 +1,67 @@
+package maxminddb
+
+import (
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestFlatten(t *testing.T) {
+	record := map[string]interface{}{
+		"array":   []interface{}{uint64(1), "two"},
+		"boolean": true,
+		"bytes":   []byte{0x00, 0x2a},
+		"double":  42.5,
+		"float":   float32(1.1),
+		"int32":   -268435456,
+		"map": map[string]interface{}{
+			"mapX": map[string]interface{}{"utf8_stringX": "hello"},
+		},
+		"uint128": big.NewInt(1 << 40),
+	}
+
+	expected := FlatRecord{
+		"array.0":               "1",
+		"array.1":               "two",
+		"boolean":               "true",
+		"bytes":                 "002a",
+		"double":                "42.5",
+		"float":                 "1.1",
+		"int32":                 "-268435456",
+		"map.mapX.utf8_stringX": "hello",
+		"uint128":               "1099511627776",
+	}
+	flat := Flatten(record)
+	if !reflect.DeepEqual(flat, expected) {
+		t.Errorf("unexpected flattened record: %v", flat)
+	}
+
+	values := flat.Values()
+	if values.Get("map.mapX.utf8_stringX") != "hello" || len(values) != len(expected) {
+		t.Errorf("unexpected values: %v", values)
+	}
+}
+
+func TestLookupFlat(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	flat, err := reader.LookupFlat(net.ParseIP("81.2.69.142"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flat["country.iso_code"] != "GB" ||
+		flat["location.time_zone"] != "Europe/London" ||
+		flat["subdivisions.0.iso_code"] != "ENG" {
+		t.Errorf("unexpected flattened record: %v", flat)
+	}
+
+	flat, err = reader.LookupFlat(net.ParseIP("10.0.0.1"))
+	if err != nil || flat != nil {
+		t.Errorf("expected no record, got %v (%v)", flat, err)
+	}
+}
@@ -0,0 +1,21 @@
+package maxminddb
+
+import "net/netip"
+
+// Lookup looks up ip in reader and returns its record decoded into a value
+// of type T, e.g., Lookup[City](reader, ip). The zero value is returned if
+// there is no record. As with Reader.Lookup, the fields of struct types are
+// resolved once per type and cached.
+func Lookup[T any](reader *Reader, ip netip.Addr) (T, error) {
+	var result T
+	err := reader.LookupAddr(ip, &result)
+	return result, err
+}
+
+// LookupNetwork is like Lookup but also returns the network of the search
+// tree containing ip, as Reader.LookupAddrNetwork does. ok is false if there
+// is no record for ip.
+func LookupNetwork[T any](reader *Reader, ip netip.Addr) (result T, network netip.Prefix, ok bool, err error) {
+	network, ok, err = reader.LookupAddrNetwork(ip, &result)
+	return result, network, ok, err
+}
@@ -0,0 +1,42 @@
+package maxminddb
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestGenericLookup(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	city, err := Lookup[City](reader, netip.MustParseAddr("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if city.Country.IsoCode != "GB" {
+		t.Errorf("unexpected country: %q", city.Country.IsoCode)
+	}
+
+	record, err := Lookup[map[string]interface{}](reader, netip.MustParseAddr("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record != nil {
+		t.Errorf("expected no record, got %v", record)
+	}
+
+	city, network, ok, err := LookupNetwork[City](reader, netip.MustParseAddr("2001:218::1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || city.Country.IsoCode != "JP" || !network.Contains(netip.MustParseAddr("2001:218::1")) {
+		t.Errorf("unexpected result: %v, %v, %v", city.Country.IsoCode, network, ok)
+	}
+
+	if _, err := Lookup[City](reader, netip.Addr{}); err == nil {
+		t.Error("expected an error for the zero address")
+	}
+}
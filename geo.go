@@ -0,0 +1,104 @@
+package maxminddb
+
+import "math"
+
+const (
+	earthRadiusKm   = 6371.0088
+	kmPerMile       = 1.609344
+	degreesToRadian = math.Pi / 180
+)
+
+// Point is a geographic coordinate in decimal degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// DistanceKm returns the great-circle distance between p and other in
+// kilometers, using the haversine formula on a spherical earth. The error is
+// below 0.5% for any pair of points.
+func (p Point) DistanceKm(other Point) float64 {
+	lat1 := p.Latitude * degreesToRadian
+	lat2 := other.Latitude * degreesToRadian
+	dLat := lat2 - lat1
+	dLon := (other.Longitude - p.Longitude) * degreesToRadian
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// DistanceMiles is like DistanceKm but returns statute miles.
+func (p Point) DistanceMiles(other Point) float64 {
+	return p.DistanceKm(other) / kmPerMile
+}
+
+// BoundingBox is a latitude/longitude rectangle. If MinLongitude is greater
+// than MaxLongitude, the box crosses the antimeridian.
+type BoundingBox struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Contains reports whether p lies within the box, edges included.
+func (b BoundingBox) Contains(p Point) bool {
+	if p.Latitude < b.MinLatitude || p.Latitude > b.MaxLatitude {
+		return false
+	}
+	if b.MinLongitude <= b.MaxLongitude {
+		return p.Longitude >= b.MinLongitude && p.Longitude <= b.MaxLongitude
+	}
+	return p.Longitude >= b.MinLongitude || p.Longitude <= b.MaxLongitude
+}
+
+// Point returns the coordinates of the location.
+func (l Location) Point() Point {
+	return Point{Latitude: l.Latitude, Longitude: l.Longitude}
+}
+
+// HasCoordinates reports whether the record has coordinates. The databases
+// set the latitude and the longitude together and no location lies at
+// exactly 0, 0, so a location at 0, 0 is one without coordinates.
+func (l Location) HasCoordinates() bool {
+	return l.Latitude != 0 || l.Longitude != 0
+}
+
+// Within reports whether the location may be within toleranceKm of point.
+// The coordinates of a location are only an estimate: the address is within
+// AccuracyRadius kilometers of them, so the location is considered within the
+// tolerance if any part of that circle is. A location without coordinates
+// is never within the tolerance.
+func (l Location) Within(point Point, toleranceKm float64) bool {
+	return l.HasCoordinates() && l.Point().DistanceKm(point)-float64(l.AccuracyRadius) <= toleranceKm
+}
+
+// CertainlyWithin is like Within but requires the whole accuracy circle of
+// the location to be within toleranceKm of point.
+func (l Location) CertainlyWithin(point Point, toleranceKm float64) bool {
+	return l.HasCoordinates() && l.Point().DistanceKm(point)+float64(l.AccuracyRadius) <= toleranceKm
+}
+
+// DistanceKm returns the distance in kilometers between the location of the
+// record and p. The bool is false if the record has no coordinates.
+func (c *City) DistanceKm(p Point) (float64, bool) {
+	if !c.Location.HasCoordinates() {
+		return 0, false
+	}
+	return c.Location.Point().DistanceKm(p), true
+}
+
+// DistanceMiles is like DistanceKm but returns statute miles.
+func (c *City) DistanceMiles(p Point) (float64, bool) {
+	if !c.Location.HasCoordinates() {
+		return 0, false
+	}
+	return c.Location.Point().DistanceMiles(p), true
+}
+
+// InBoundingBox reports whether the location of the record lies within box.
+// It is false if the record has no coordinates.
+func (c *City) InBoundingBox(box BoundingBox) bool {
+	return c.Location.HasCoordinates() && box.Contains(c.Location.Point())
+}
@@ -0,0 +1,109 @@
+package maxminddb
+
+import (
+	"math"
+	"net"
+	"testing"
+)
+
+func TestPointDistance(t *testing.T) {
+	london := Point{Latitude: 51.5074, Longitude: -0.1278}
+	paris := Point{Latitude: 48.8566, Longitude: 2.3522}
+
+	if d := london.DistanceKm(paris); math.Abs(d-343.5) > 1 {
+		t.Errorf("unexpected distance in km: %v", d)
+	}
+	if d := london.DistanceMiles(paris); math.Abs(d-213.4) > 1 {
+		t.Errorf("unexpected distance in miles: %v", d)
+	}
+	if d := london.DistanceKm(london); d != 0 {
+		t.Errorf("expected no distance to the same point, got %v", d)
+	}
+}
+
+func TestBoundingBoxContains(t *testing.T) {
+	box := BoundingBox{MinLatitude: 49, MinLongitude: -8, MaxLatitude: 61, MaxLongitude: 2}
+	if !box.Contains(Point{Latitude: 51.5, Longitude: -0.1}) {
+		t.Error("expected London to be in the box")
+	}
+	if box.Contains(Point{Latitude: 48.9, Longitude: 2.4}) {
+		t.Error("expected Paris not to be in the box")
+	}
+
+	pacific := BoundingBox{MinLatitude: -50, MinLongitude: 170, MaxLatitude: 0, MaxLongitude: -170}
+	if !pacific.Contains(Point{Latitude: -18, Longitude: 178}) ||
+		!pacific.Contains(Point{Latitude: -18, Longitude: -175}) {
+		t.Error("expected points on both sides of the antimeridian to be in the box")
+	}
+	if pacific.Contains(Point{Latitude: -18, Longitude: 160}) {
+		t.Error("expected a point outside the antimeridian box not to be in it")
+	}
+}
+
+func TestCityDistance(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var city City
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &city); err != nil {
+		t.Fatal(err)
+	}
+	london := Point{Latitude: 51.5074, Longitude: -0.1278}
+	km, ok := city.DistanceKm(london)
+	if !ok || km > 50 {
+		t.Errorf("expected the record to be near London, got %v km, %v", km, ok)
+	}
+	if miles, ok := city.DistanceMiles(london); !ok || math.Abs(miles*kmPerMile-km) > 1e-9 {
+		t.Errorf("unexpected distance in miles: %v, %v", miles, ok)
+	}
+	britishIsles := BoundingBox{MinLatitude: 49, MinLongitude: -8, MaxLatitude: 61, MaxLongitude: 2}
+	if !city.InBoundingBox(britishIsles) {
+		t.Error("expected the record to be in the British Isles")
+	}
+
+	// A record without coordinates is not placed at 0, 0.
+	country := City{Country: Country{IsoCode: "GB"}}
+	if country.Location.HasCoordinates() {
+		t.Fatalf("expected a record without coordinates, got %+v", country.Location)
+	}
+	if km, ok := country.DistanceKm(Point{}); ok {
+		t.Errorf("expected no distance without coordinates, got %v km", km)
+	}
+	if _, ok := country.DistanceMiles(london); ok {
+		t.Error("expected no distance in miles without coordinates")
+	}
+	if country.InBoundingBox(BoundingBox{MinLatitude: -1, MinLongitude: -1, MaxLatitude: 1, MaxLongitude: 1}) {
+		t.Error("expected a record without coordinates not to be in any box")
+	}
+	if country.Location.Within(Point{}, 100) {
+		t.Error("expected a location without coordinates not to be within any distance")
+	}
+}
+
+func TestLocationWithin(t *testing.T) {
+	london := Point{Latitude: 51.5074, Longitude: -0.1278}
+	paris := Point{Latitude: 48.8566, Longitude: 2.3522}
+	location := Location{AccuracyRadius: 100, Latitude: london.Latitude, Longitude: london.Longitude}
+
+	// Paris is about 344 km away, and the address may be 100 km closer.
+	if !location.Within(paris, 250) {
+		t.Error("expected the location to possibly be within 250 km of Paris")
+	}
+	if location.Within(paris, 200) {
+		t.Error("expected the location not to be within 200 km of Paris")
+	}
+	if location.CertainlyWithin(paris, 400) {
+		t.Error("expected the location not to certainly be within 400 km of Paris")
+	}
+	if !location.CertainlyWithin(paris, 450) {
+		t.Error("expected the location to certainly be within 450 km of Paris")
+	}
+
+	exact := Location{Latitude: london.Latitude, Longitude: london.Longitude}
+	if exact.Within(paris, 300) || !exact.Within(paris, 350) {
+		t.Error("expected a location without a radius to be treated as exact")
+	}
+}
@@ -0,0 +1,67 @@
+package geohttp_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/oschwald/maxminddb-golang/geohttp"
+)
+
+// This example shows how to pass the country of the client on to a handler.
+func ExampleHeaderEmitter() {
+	db, err := maxminddb.Open("../test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	emitter := &geohttp.HeaderEmitter{Readers: []*maxminddb.Reader{db}}
+	handler := emitter.Handler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(w, "Hello from %s", req.Header.Get("X-Geo-City"))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "81.2.69.142:4242"
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	fmt.Print(w.Body.String())
+	// Output:
+	// Hello from London
+}
+
+// This example shows how to serve the status of a database on a health
+// endpoint.
+func ExampleHealthHandler() {
+	db, err := maxminddb.Open("../test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	health := &geohttp.HealthHandler{
+		Reader: func() *maxminddb.Reader { return db },
+		Verify: true,
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/healthz/geoip", health)
+
+	// The database is verified in the background. Wait for it so that the
+	// status below reports the result.
+	health.Loaded(db)
+	health.WaitVerified(db)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/healthz/geoip", nil))
+
+	var status geohttp.HealthStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(w.Code, status.Healthy, status.DatabaseType, *status.Verified)
+	// Output:
+	// 200 true GeoIP2-City true
+}
@@ -0,0 +1,124 @@
+// Package geohttp provides net/http integration for MaxMind DB readers.
+package geohttp
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// DefaultHeaders maps the headers set by a HeaderEmitter to record paths in
+// the GeoIP2/GeoLite2 City and ASN databases.
+var DefaultHeaders = map[string]string{
+	"X-Geo-Country": "country.iso_code",
+	"X-Geo-City":    "city.names.en",
+	"X-ASN":         "autonomous_system_number",
+}
+
+// HeaderEmitter is a middleware that looks up the client address of each
+// request and passes the result on in headers, similar to what the nginx
+// geoip modules do.
+type HeaderEmitter struct {
+	// Readers are the databases looked up, e.g., a City and an ASN
+	// database. If several records have a value for a path, the first
+	// reader wins.
+	Readers []*maxminddb.Reader
+
+	// Headers maps header names to record paths, as used by FlatRecord.
+	// If nil, DefaultHeaders is used. Only the values at these paths are
+	// decoded, not the whole records, and paths the DecodeProfile of a
+	// reader omits are not set.
+	Headers map[string]string
+
+	// ClientIP returns the address to look up for a request. If nil, the
+	// host of the request's RemoteAddr is used.
+	ClientIP func(*http.Request) net.IP
+
+	// ResponseHeaders makes the emitter set the headers on the response as
+	// well as on the request passed to the next handler.
+	ResponseHeaders bool
+}
+
+// Handler returns a handler setting the headers before calling next.
+// Headers are always removed from the incoming request first, so clients
+// cannot spoof them. The headers are set on a copy of the request, which is
+// passed to next, so handlers holding the original request do not see them.
+func (e *HeaderEmitter) Handler(next http.Handler) http.Handler {
+	headers := e.Headers
+	if headers == nil {
+		headers = DefaultHeaders
+	}
+	paths := make(map[string][]interface{}, len(headers))
+	for header, path := range headers {
+		paths[header] = splitPath(path)
+	}
+	clientIP := e.ClientIP
+	if clientIP == nil {
+		clientIP = RemoteIP
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req = req.Clone(req.Context())
+		for header := range headers {
+			req.Header.Del(header)
+		}
+
+		if ip := clientIP(req); ip != nil {
+			for _, reader := range e.Readers {
+				offset, err := reader.LookupOffset(ip)
+				if err != nil || offset == maxminddb.NotFound {
+					continue
+				}
+				for header, path := range paths {
+					if req.Header.Get(header) != "" {
+						continue
+					}
+					var value interface{}
+					if err := reader.DecodePath(offset, &value, path...); err != nil {
+						continue
+					}
+					// Flattening a single value keys it by the empty path,
+					// and formats it as FlatRecord does.
+					formatted, ok := maxminddb.Flatten(value)[""]
+					if !ok {
+						continue
+					}
+					req.Header.Set(header, formatted)
+					if e.ResponseHeaders {
+						w.Header().Set(header, formatted)
+					}
+				}
+			}
+		}
+
+		next.ServeHTTP(w, req)
+	})
+}
+
+// splitPath converts a FlatRecord key to a path for DecodePath, in which
+// array indices are ints.
+func splitPath(key string) []interface{} {
+	elements := strings.Split(key, ".")
+	path := make([]interface{}, len(elements))
+	for i, element := range elements {
+		if index, err := strconv.Atoi(element); err == nil && index >= 0 {
+			path[i] = index
+		} else {
+			path[i] = element
+		}
+	}
+	return path
+}
+
+// RemoteIP returns the IP address of the host in req.RemoteAddr, or nil if
+// it cannot be parsed.
+func RemoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	return net.ParseIP(host)
+}
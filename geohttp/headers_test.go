@@ -0,0 +1,98 @@
+package geohttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestHeaderEmitter(t *testing.T) {
+	city, err := maxminddb.Open("../test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer city.Close()
+
+	var received http.Header
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	})
+	headers := map[string]string{"X-Geo-Subdivision": "subdivisions.0.iso_code"}
+	for header, path := range DefaultHeaders {
+		headers[header] = path
+	}
+	emitter := &HeaderEmitter{
+		Readers:         []*maxminddb.Reader{city},
+		Headers:         headers,
+		ResponseHeaders: true,
+	}
+	handler := emitter.Handler(next)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "81.2.69.142:4242"
+	req.Header.Set("X-ASN", "spoofed")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	if received.Get("X-Geo-Country") != "GB" || received.Get("X-Geo-City") != "London" || received.Get("X-Geo-Subdivision") != "ENG" {
+		t.Errorf("unexpected request headers: %v", received)
+	}
+	if req.Header.Get("X-ASN") != "spoofed" || req.Header.Get("X-Geo-Country") != "" {
+		t.Errorf("expected the incoming request to be left unchanged, got %v", req.Header)
+	}
+	if received.Get("X-ASN") != "" {
+		t.Errorf("expected the spoofed X-ASN header to be removed, got %q", received.Get("X-ASN"))
+	}
+	if w.Header().Get("X-Geo-Country") != "GB" {
+		t.Errorf("unexpected response headers: %v", w.Header())
+	}
+
+	req = httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.1:4242"
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if received.Get("X-Geo-Country") != "" {
+		t.Errorf("unexpected request headers: %v", received)
+	}
+}
+
+func TestHeaderEmitterWithProfile(t *testing.T) {
+	city, err := maxminddb.Open(
+		"../test-data/test-data/GeoIP2-City-Test.mmdb",
+		maxminddb.WithDecodeProfile(maxminddb.PreciseLocationProfile),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer city.Close()
+
+	var received http.Header
+	next := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	})
+	emitter := &HeaderEmitter{
+		Readers: []*maxminddb.Reader{city},
+		Headers: map[string]string{
+			"X-Geo-Country":   "country.iso_code",
+			"X-Geo-Latitude":  "location.latitude",
+			"X-Geo-Longitude": "location.longitude",
+			"X-Geo-Postal":    "postal.code",
+		},
+		ResponseHeaders: true,
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "81.2.69.142:4242"
+	w := httptest.NewRecorder()
+	emitter.Handler(next).ServeHTTP(w, req)
+
+	if received.Get("X-Geo-Country") != "GB" {
+		t.Errorf("unexpected request headers: %v", received)
+	}
+	for _, header := range []string{"X-Geo-Latitude", "X-Geo-Longitude", "X-Geo-Postal"} {
+		if received.Get(header) != "" || w.Header().Get(header) != "" {
+			t.Errorf("expected %s to be omitted, got %v and %v", header, received, w.Header())
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package geohttp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// HealthStatus is the JSON body written by a HealthHandler.
+type HealthStatus struct {
+	// Healthy is false if the database is not open, is older than
+	// MaxBuildAge or failed verification.
+	Healthy      bool       `json:"healthy"`
+	Open         bool       `json:"open"`
+	DatabaseType string     `json:"database_type,omitempty"`
+	DatabaseID   string     `json:"database_id,omitempty"` // see maxminddb.Metadata.ID
+	BuildTime    *time.Time `json:"build_time,omitempty"`
+	// BuildAgeSeconds is the age of the database at the time of the
+	// request.
+	BuildAgeSeconds float64     `json:"build_age_seconds,omitempty"`
+	LastReload      *time.Time  `json:"last_reload,omitempty"`
+	Verified        *bool       `json:"verified,omitempty"`
+	VerifyError     string      `json:"verify_error,omitempty"`
+	Stats           interface{} `json:"stats,omitempty"`
+	// Verifying is true while the database is being verified, in which
+	// case Verified is not set.
+	Verifying bool `json:"verifying,omitempty"`
+	// Problems lists why the database is not healthy.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// HealthHandler is an http.Handler reporting the status of a database as
+// a HealthStatus. It responds with 200 OK if the database is healthy and
+// with 503 Service Unavailable otherwise, so it can be mounted as is on a
+// health or readiness endpoint, or called from an existing one with
+// Status.
+type HealthHandler struct {
+	// Reader returns the database to report on, or nil if it is not open
+	// yet. It is called on every request, so setups reloading the database
+	// can return the current reader, which must stay open until the
+	// request completes and, with Verify, until its verification does.
+	Reader func() *maxminddb.Reader
+
+	// MaxBuildAge, if positive, marks databases built longer ago than it
+	// as unhealthy.
+	MaxBuildAge time.Duration
+
+	// LastReload, if set, returns when the database was last (re)loaded,
+	// e.g., proxyplugin.Plugin.LastReload.
+	LastReload func() time.Time
+
+	// Verify makes the handler verify each database it reports on. Since
+	// verification reads the whole file, it runs in the background, once
+	// per reader, from the call to Loaded or the first request for the
+	// reader, and the status reports Verifying until it completes. A
+	// reader must stay open until its verification completes, see
+	// WaitVerified.
+	Verify bool
+
+	// Stats, if set, returns application counters to include in the
+	// status, such as the statistics of a cache in front of the database.
+	// The value must be encodable as JSON.
+	Stats func() interface{}
+
+	// now is replaced in tests.
+	now func() time.Time
+
+	mu      sync.Mutex
+	current *verification
+	// running holds the verifications in progress by reader.
+	running map[*maxminddb.Reader]*verification
+}
+
+type verification struct {
+	reader *maxminddb.Reader
+	done   chan struct{}
+	// err is set before done is closed.
+	err error
+}
+
+// Status returns the current status of the database.
+func (h *HealthHandler) Status() HealthStatus {
+	now := time.Now
+	if h.now != nil {
+		now = h.now
+	}
+
+	var status HealthStatus
+	if h.LastReload != nil {
+		if reload := h.LastReload(); !reload.IsZero() {
+			status.LastReload = &reload
+		}
+	}
+	if h.Stats != nil {
+		status.Stats = h.Stats()
+	}
+
+	var reader *maxminddb.Reader
+	if h.Reader != nil {
+		reader = h.Reader()
+	}
+	if reader == nil {
+		status.Problems = append(status.Problems, "database is not open")
+		return status
+	}
+	// ReadNode is the cheapest call that reports a closed reader.
+	if _, _, err := reader.ReadNode(0); errors.Is(err, maxminddb.ErrClosed) {
+		status.Problems = append(status.Problems, "database is closed")
+		return status
+	}
+	status.Open = true
+
+	status.DatabaseType = reader.Metadata.DatabaseType
+	status.DatabaseID = reader.Metadata.ID()
+	buildTime := time.Unix(int64(reader.Metadata.BuildEpoch), 0).UTC()
+	status.BuildTime = &buildTime
+	buildAge := now().Sub(buildTime)
+	status.BuildAgeSeconds = buildAge.Seconds()
+	if h.MaxBuildAge > 0 && buildAge > h.MaxBuildAge {
+		status.Problems = append(status.Problems, "database is older than "+h.MaxBuildAge.String())
+	}
+
+	if h.Verify {
+		v := h.verify(reader)
+		select {
+		case <-v.done:
+			verified := v.err == nil
+			status.Verified = &verified
+			if v.err != nil {
+				status.VerifyError = v.err.Error()
+				status.Problems = append(status.Problems, "database failed verification")
+			}
+		default:
+			status.Verifying = true
+		}
+	}
+
+	status.Healthy = len(status.Problems) == 0
+	return status
+}
+
+// Loaded starts verifying reader in the background if Verify is set, so
+// that a database (re)loaded by the application is verified before the
+// next health request rather than from it.
+func (h *HealthHandler) Loaded(reader *maxminddb.Reader) {
+	if h.Verify {
+		h.verify(reader)
+	}
+}
+
+// WaitVerified waits for the verification of reader to complete, if it is
+// in progress. Setups reloading the database call it before closing the
+// previous reader.
+func (h *HealthHandler) WaitVerified(reader *maxminddb.Reader) {
+	h.mu.Lock()
+	v := h.running[reader]
+	h.mu.Unlock()
+	if v != nil {
+		<-v.done
+	}
+}
+
+// verify returns the verification of reader, starting it if reader is not
+// the reader last verified.
+func (h *HealthHandler) verify(reader *maxminddb.Reader) *verification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if v := h.running[reader]; v != nil {
+		return v
+	}
+	if h.current != nil && h.current.reader == reader {
+		return h.current
+	}
+
+	v := &verification{reader: reader, done: make(chan struct{})}
+	h.current = v
+	if h.running == nil {
+		h.running = map[*maxminddb.Reader]*verification{}
+	}
+	h.running[reader] = v
+	go func() {
+		v.err = reader.Verify()
+		h.mu.Lock()
+		delete(h.running, reader)
+		h.mu.Unlock()
+		close(v.done)
+	}()
+	return v
+}
+
+// ServeHTTP writes the status of the database as JSON.
+func (h *HealthHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	status := h.Status()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.Healthy {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if req.Method != http.MethodHead {
+		json.NewEncoder(w).Encode(status)
+	}
+}
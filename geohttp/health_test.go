@@ -0,0 +1,103 @@
+package geohttp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestHealthHandler(t *testing.T) {
+	city, err := maxminddb.Open("../test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer city.Close()
+	buildTime := time.Unix(int64(city.Metadata.BuildEpoch), 0)
+
+	var reader *maxminddb.Reader
+	handler := &HealthHandler{
+		Reader:      func() *maxminddb.Reader { return reader },
+		MaxBuildAge: 24 * time.Hour,
+		Verify:      true,
+		Stats:       func() interface{} { return map[string]int{"hits": 3} },
+		now:         func() time.Time { return buildTime.Add(time.Hour) },
+	}
+
+	serve := func(expectedCode int) HealthStatus {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", "/healthz", nil))
+		if w.Code != expectedCode {
+			t.Errorf("expected status code %d, got %d", expectedCode, w.Code)
+		}
+		if contentType := w.Header().Get("Content-Type"); contentType != "application/json" {
+			t.Errorf("unexpected content type %q", contentType)
+		}
+		var status HealthStatus
+		if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+			t.Fatal(err)
+		}
+		return status
+	}
+
+	status := serve(http.StatusServiceUnavailable)
+	if status.Healthy || status.Open || len(status.Problems) != 1 {
+		t.Errorf("unexpected status without a database: %+v", status)
+	}
+
+	// A database being verified is not reported unhealthy.
+	reader = city
+	handler.current = &verification{reader: city, done: make(chan struct{})}
+	status = serve(http.StatusOK)
+	if !status.Healthy || !status.Verifying || status.Verified != nil {
+		t.Errorf("unexpected status while verifying: %+v", status)
+	}
+
+	handler.current = nil
+	handler.Loaded(city)
+	handler.WaitVerified(city)
+	status = serve(http.StatusOK)
+	if !status.Healthy || !status.Open || status.DatabaseType != "GeoIP2-City" || status.DatabaseID != city.Metadata.ID() {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.BuildAgeSeconds != 3600 || status.Verified == nil || !*status.Verified {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if stats, ok := status.Stats.(map[string]interface{}); !ok || stats["hits"] != 3.0 {
+		t.Errorf("unexpected stats: %v", status.Stats)
+	}
+
+	handler.now = func() time.Time { return buildTime.Add(48 * time.Hour) }
+	status = serve(http.StatusServiceUnavailable)
+	if status.Healthy || status.Problems[0] != "database is older than 24h0m0s" {
+		t.Errorf("unexpected status of an old database: %+v", status)
+	}
+}
+
+func TestHealthHandlerVerifyFailure(t *testing.T) {
+	broken, err := maxminddb.Open("../test-data/test-data/MaxMind-DB-test-broken-pointers-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer broken.Close()
+
+	handler := &HealthHandler{
+		Reader: func() *maxminddb.Reader { return broken },
+		Verify: true,
+	}
+	handler.Loaded(broken)
+	handler.WaitVerified(broken)
+	status := handler.Status()
+	if status.Healthy || status.Verified == nil || *status.Verified || status.VerifyError == "" {
+		t.Errorf("unexpected status of a broken database: %+v", status)
+	}
+
+	broken.Close()
+	if status := handler.Status(); status.Healthy || status.Open {
+		t.Errorf("unexpected status of a closed database: %+v", status)
+	}
+}
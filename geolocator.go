@@ -0,0 +1,24 @@
+package maxminddb
+
+import "net"
+
+// Geolocator is the subset of the methods of Reader that most code looking
+// up addresses needs. Depending on it rather than on *Reader lets that code
+// be handed a FailoverReader, or a fake in tests, instead.
+type Geolocator interface {
+	Lookup(ipAddress net.IP, result interface{}) error
+	LookupNetwork(ipAddress net.IP, result interface{}) (network *net.IPNet, ok bool, err error)
+	DatabaseMetadata() Metadata
+	Close() error
+}
+
+var (
+	_ Geolocator = (*Reader)(nil)
+	_ Geolocator = (*FailoverReader)(nil)
+)
+
+// DatabaseMetadata returns r.Metadata. It exists for Geolocator, as
+// interfaces cannot include fields.
+func (r *Reader) DatabaseMetadata() Metadata {
+	return r.Metadata
+}
@@ -0,0 +1,8 @@
+module github.com/oschwald/maxminddb-golang
+
+go 1.18
+
+require (
+	golang.org/x/sys v0.9.0
+	gopkg.in/check.v1 v0.0.0-20161208181325-20d25e280405
+)
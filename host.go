@@ -0,0 +1,45 @@
+package maxminddb
+
+import (
+	"context"
+	"net"
+)
+
+// HostResult holds the record found for one of the addresses of a host.
+type HostResult struct {
+	IP     net.IP
+	Result interface{} // the value returned by newResult, filled in by Lookup
+	Err    error       // the error returned by Lookup, if any
+}
+
+// LookupHost resolves host with resolver and looks up every address it
+// resolves to. If resolver is nil, net.DefaultResolver is used. newResult is
+// called once per address to allocate the value to decode its record into,
+// as passed to Lookup. An error is returned only if host cannot be resolved;
+// lookup errors are reported per address.
+func (r *Reader) LookupHost(
+	ctx context.Context,
+	resolver *net.Resolver,
+	host string,
+	newResult func() interface{},
+) ([]HostResult, error) {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]HostResult, 0, len(addrs))
+	for _, addr := range addrs {
+		result := newResult()
+		results = append(results, HostResult{
+			IP:     addr.IP,
+			Result: result,
+			Err:    r.Lookup(addr.IP, result),
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,48 @@
+package maxminddb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+)
+
+func TestLookupHost(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	newResult := func() interface{} { return new(map[string]string) }
+
+	results, err := reader.LookupHost(context.Background(), nil, "1.1.1.3", newResult)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err != nil || !results[0].IP.Equal(net.ParseIP("1.1.1.3")) {
+		t.Fatalf("unexpected results: %v", results)
+	}
+	if ip := (*results[0].Result.(*map[string]string))["ip"]; ip != "1.1.1.2" {
+		t.Errorf("expected 1.1.1.2, got %s", ip)
+	}
+
+	results, err = reader.LookupHost(context.Background(), nil, "2001:db8::1", newResult)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Err == nil {
+		t.Errorf("expected a lookup error for an IPv6 address, got %v", results)
+	}
+
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			return nil, errors.New("no network in tests")
+		},
+	}
+	_, err = reader.LookupHost(context.Background(), resolver, "maxmind.invalid", newResult)
+	if err == nil {
+		t.Error("expected an error when the host cannot be resolved")
+	}
+}
@@ -0,0 +1,27 @@
+package maxminddb
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+)
+
+// ID returns a stable identifier of the database build, made of its type,
+// its build time and a hash of its description, e.g.,
+// "GeoIP2-City-1571326584-8ea0c6e6c6c1d2a5". Databases with the same ID can
+// be assumed to hold the same data, so the ID can key caches and label
+// metrics without identifying anything beyond the database itself. It is
+// the database_id reported by geohttp.HealthHandler.
+func (m Metadata) ID() string {
+	languages := make([]string, 0, len(m.Description))
+	for language := range m.Description {
+		languages = append(languages, language)
+	}
+	sort.Strings(languages)
+
+	h := fnv.New64a()
+	for _, language := range languages {
+		fmt.Fprintf(h, "%s\x00%s\x00", language, m.Description[language])
+	}
+	return fmt.Sprintf("%s-%d-%016x", m.DatabaseType, m.BuildEpoch, h.Sum64())
+}
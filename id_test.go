@@ -0,0 +1,39 @@
+package maxminddb
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMetadataID(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	id := reader.Metadata.ID()
+	if !strings.HasPrefix(id, "GeoIP2-City-") {
+		t.Errorf("unexpected ID %q", id)
+	}
+
+	other, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if other.Metadata.ID() != id {
+		t.Errorf("expected the same database to have the same ID")
+	}
+
+	metadata := reader.Metadata
+	metadata.Description = map[string]string{"en": "another description"}
+	if metadata.ID() == id {
+		t.Errorf("expected a different description to change the ID")
+	}
+	metadata = reader.Metadata
+	metadata.BuildEpoch++
+	if metadata.ID() == id {
+		t.Errorf("expected a different build time to change the ID")
+	}
+}
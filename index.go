@@ -0,0 +1,67 @@
+package maxminddb
+
+import (
+	"net"
+	"net/netip"
+)
+
+// BuildIndex scans the networks of reader once and builds an inverse index
+// from the keys returned by project to the prefixes having them, e.g., from
+// country codes to the networks of each country. Each record is decoded
+// into a value of type R and projected only once, however many networks
+// share it. Networks whose record project rejects are left out.
+//
+// Networks of the IPv4 subtree of an IPv6 database, ::/96, are indexed as
+// IPv4 prefixes, since that is where IPv4 addresses are looked up. As with
+// Networks, they may also appear under each of the locations they are
+// aliased to, such as ::ffff:0:0/96.
+func BuildIndex[R any, K comparable](reader *Reader, project func(R) (K, bool)) (map[K][]netip.Prefix, error) {
+	type projection struct {
+		key K
+		ok  bool
+	}
+	projections := map[uintptr]projection{}
+	index := map[K][]netip.Prefix{}
+
+	err := reader.WalkNetworks(func(network *net.IPNet, offset uintptr) error {
+		p, seen := projections[offset]
+		if !seen {
+			var record R
+			if err := reader.Decode(offset, &record); err != nil {
+				return err
+			}
+			p.key, p.ok = project(record)
+			projections[offset] = p
+		}
+		if !p.ok {
+			return nil
+		}
+
+		prefix, err := NetworkToPrefix(network)
+		if err != nil {
+			return err
+		}
+		prefix = ipv4SubtreePrefix(prefix)
+		index[p.key] = append(index[p.key], prefix)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// ipv4SubtreePrefix returns the IPv4 prefix corresponding to prefix if it is
+// within ::/96, or prefix otherwise.
+func ipv4SubtreePrefix(prefix netip.Prefix) netip.Prefix {
+	if !prefix.Addr().Is6() || prefix.Bits() < 96 {
+		return prefix
+	}
+	b := prefix.Addr().As16()
+	for _, x := range b[:12] {
+		if x != 0 {
+			return prefix
+		}
+	}
+	return netip.PrefixFrom(netip.AddrFrom4([4]byte{b[12], b[13], b[14], b[15]}), prefix.Bits()-96)
+}
@@ -0,0 +1,48 @@
+package maxminddb
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestBuildIndex(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-Country-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	type country struct {
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+	}
+	index, err := BuildIndex(reader, func(record country) (string, bool) {
+		return record.Country.IsoCode, record.Country.IsoCode != ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := index[""]; ok {
+		t.Error("expected records without a country to be left out")
+	}
+	for code, prefixes := range index {
+		for _, prefix := range prefixes {
+			got, ok := reader.CountryCode(prefix.Addr().AsSlice())
+			if !ok || got != code {
+				t.Errorf("expected %s to be in %s, got %q", prefix, code, got)
+			}
+		}
+	}
+
+	var found bool
+	for _, prefix := range index["GB"] {
+		if prefix.Contains(netip.MustParseAddr("81.2.69.160")) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected 81.2.69.160 in the GB prefixes, got %v", index["GB"])
+	}
+}
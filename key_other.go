@@ -10,13 +10,32 @@ import (
 // decodeStructKey returns a string which points into the database. Don't keep
 // it around.
 func (d *decoder) decodeStructKey(offset uint) (string, uint, error) {
-	typeNum, size, newOffset := d.decodeCtrlData(offset)
+	typeNum, size, newOffset, err := d.decodeCtrlData(offset)
+	if err != nil {
+		return "", 0, err
+	}
 	switch typeNum {
-	case _Pointer:
-		pointer, ptrOffset := d.decodePointer(size, newOffset)
+	case KindPointer:
+		pointer, ptrOffset, err := d.followPointer(size, newOffset)
+		if err != nil {
+			return "", 0, err
+		}
 		s, _, err := d.decodeStructKey(pointer)
 		return s, ptrOffset, err
-	case _String:
+	case KindString, KindBytes:
+		if newOffset+size > uint(len(d.buffer)) {
+			// Unlike slicing, the string header below is not bounds
+			// checked, and reading past the buffer cannot be recovered.
+			return "", 0, newInvalidDatabaseError("unexpected end of database while decoding struct key")
+		}
+		if err := d.checkUTF8(newOffset, newOffset+size); err != nil {
+			return "", 0, err
+		}
+		if size == 0 {
+			// An empty key may end the buffer, which has no byte at
+			// newOffset to point to.
+			return "", newOffset, nil
+		}
 		var s string
 		val := (*reflect.StringHeader)(unsafe.Pointer(&s))
 		val.Data = uintptr(unsafe.Pointer(&d.buffer[newOffset]))
@@ -0,0 +1,7 @@
+// +build !linux,!windows,!appengine
+
+package maxminddb
+
+func adviseHugePages(b []byte) error {
+	return nil
+}
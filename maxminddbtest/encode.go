@@ -0,0 +1,206 @@
+package maxminddbtest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var bigIntType = reflect.TypeOf(big.Int{})
+
+// encodeValue appends value, encoded in the MaxMind DB data format, to b.
+// Structs are encoded as maps, using the maxminddb tags of their fields as
+// the decoder does. Nil pointers, maps and slices in structs are left out.
+func encodeValue(b []byte, value reflect.Value) ([]byte, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil, fmt.Errorf("maxminddbtest: cannot encode a nil %s", value.Type())
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.String:
+		b = appendControl(b, maxminddb.KindString, value.Len())
+		return append(b, value.String()...), nil
+	case reflect.Bool:
+		if value.Bool() {
+			return appendControl(b, maxminddb.KindBool, 1), nil
+		}
+		return appendControl(b, maxminddb.KindBool, 0), nil
+	case reflect.Float64:
+		b = appendControl(b, maxminddb.KindFloat64, 8)
+		return appendBigEndian(b, math.Float64bits(value.Float()), 8), nil
+	case reflect.Float32:
+		b = appendControl(b, maxminddb.KindFloat32, 4)
+		return appendBigEndian(b, uint64(math.Float32bits(float32(value.Float()))), 4), nil
+	case reflect.Uint8, reflect.Uint16:
+		return appendUint(b, maxminddb.KindUint16, value.Uint()), nil
+	case reflect.Uint32:
+		return appendUint(b, maxminddb.KindUint32, value.Uint()), nil
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return appendUint(b, maxminddb.KindUint64, value.Uint()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n := value.Int()
+		if n < math.MinInt32 || n > math.MaxInt32 {
+			return nil, fmt.Errorf("maxminddbtest: %d does not fit in an int32", n)
+		}
+		b = appendControl(b, maxminddb.KindInt32, 4)
+		return appendBigEndian(b, uint64(uint32(int32(n))), 4), nil
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendControl(b, maxminddb.KindBytes, value.Len())
+			for i := 0; i < value.Len(); i++ {
+				b = append(b, byte(value.Index(i).Uint()))
+			}
+			return b, nil
+		}
+		b = appendControl(b, maxminddb.KindSlice, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			var err error
+			if b, err = encodeValue(b, value.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("maxminddbtest: cannot encode a %s, map keys must be strings", value.Type())
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		b = appendControl(b, maxminddb.KindMap, len(keys))
+		for _, key := range keys {
+			var err error
+			if b, err = encodeValue(b, key); err != nil {
+				return nil, err
+			}
+			if b, err = encodeValue(b, value.MapIndex(key)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		if value.Type() == bigIntType {
+			n := new(big.Int)
+			reflect.ValueOf(n).Elem().Set(value)
+			if n.Sign() < 0 || n.BitLen() > 128 {
+				return nil, fmt.Errorf("maxminddbtest: %s does not fit in a uint128", n)
+			}
+			bytes := n.Bytes()
+			b = appendControl(b, maxminddb.KindUint128, len(bytes))
+			return append(b, bytes...), nil
+		}
+		fields := map[string]reflect.Value{}
+		structFields(value, fields)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b = appendControl(b, maxminddb.KindMap, len(names))
+		for _, name := range names {
+			b = appendControl(b, maxminddb.KindString, len(name))
+			b = append(b, name...)
+			var err error
+			if b, err = encodeValue(b, fields[name]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("maxminddbtest: cannot encode a %s", value.Type())
+}
+
+// structFields adds the fields of the struct value to fields, under the
+// names the decoder maps them to. Fields of embedded structs are added as
+// if they were fields of value.
+func structFields(value reflect.Value, fields map[string]reflect.Value) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		fieldValue := value.Field(i)
+
+		name := field.Name
+		if tag := field.Tag.Get("maxminddb"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if tagName, _, _ := strings.Cut(tag, ","); tagName != "" {
+				name = tagName
+			}
+		}
+		if field.Anonymous {
+			if fieldValue.Kind() == reflect.Ptr {
+				if fieldValue.IsNil() {
+					continue
+				}
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				structFields(fieldValue, fields)
+				continue
+			}
+		}
+
+		switch fieldValue.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+			if fieldValue.IsNil() {
+				continue
+			}
+		}
+		fields[name] = fieldValue
+	}
+}
+
+// appendUint appends n with as few bytes as needed.
+func appendUint(b []byte, kind maxminddb.Kind, n uint64) []byte {
+	size := 0
+	for size < 8 && n>>(8*size) != 0 {
+		size++
+	}
+	b = appendControl(b, kind, size)
+	return appendBigEndian(b, n, size)
+}
+
+// appendBigEndian appends the size low-order bytes of n, in big-endian order.
+func appendBigEndian(b []byte, n uint64, size int) []byte {
+	var payload [8]byte
+	binary.BigEndian.PutUint64(payload[:], n)
+	return append(b, payload[8-size:]...)
+}
+
+// appendControl appends the control byte of a value of kind and size, with
+// the extended type byte and the size bytes it may need.
+func appendControl(b []byte, kind maxminddb.Kind, size int) []byte {
+	control := len(b)
+	if kind > 7 {
+		b = append(b, 0, byte(kind-7))
+	} else {
+		b = append(b, byte(kind)<<5)
+	}
+
+	switch {
+	case size < 29:
+		b[control] |= byte(size)
+	case size < 29+256:
+		b[control] |= 29
+		b = append(b, byte(size-29))
+	case size < 285+65536:
+		b[control] |= 30
+		b = append(b, byte((size-285)>>8), byte(size-285))
+	default:
+		size -= 65821
+		b[control] |= 31
+		b = append(b, byte(size>>16), byte(size>>8), byte(size))
+	}
+	return b
+}
@@ -0,0 +1,45 @@
+package maxminddbtest_test
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/oschwald/maxminddb-golang/maxminddbtest"
+)
+
+// greeting is the code under test, which depends on a Geolocator rather
+// than on a database file.
+func greeting(geolocator maxminddb.Geolocator, ip net.IP) (string, error) {
+	var city maxminddb.City
+	if err := geolocator.Lookup(ip, &city); err != nil {
+		return "", err
+	}
+	if city.Country.InEuropeanUnion() {
+		return "Hallo " + city.City.Names["en"], nil
+	}
+	return "Hello " + city.City.Names["en"], nil
+}
+
+// This example shows how to test code looking up addresses without a
+// database file. The records are encoded and decoded as in a database, so
+// they round trip to the structs of the code under test.
+func ExampleStubReader() {
+	stub := maxminddbtest.NewStubReader().
+		Add("198.51.100.0/24", maxminddbtest.Record().Country("DE").City("Berlin")).
+		Add("203.0.113.0/24", maxminddbtest.Record().Country("US").City("Austin"))
+
+	for _, address := range []string{"198.51.100.7", "203.0.113.9"} {
+		message, err := greeting(stub, net.ParseIP(address))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(message)
+	}
+	fmt.Println(stub.Lookups())
+	// Output:
+	// Hallo Berlin
+	// Hello Austin
+	// [198.51.100.7 203.0.113.9]
+}
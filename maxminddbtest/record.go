@@ -0,0 +1,143 @@
+package maxminddbtest
+
+import (
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// RecordBuilder builds records in the layout of the GeoIP2 and GeoLite2
+// City and ASN databases, e.g.,
+//
+//	Record().Country("US").City("Austin").ASN(15169)
+//
+// The records may be registered with StubReader.Add or decoded directly
+// into the structs used by the code under test. Names are set for the "en"
+// locale.
+type RecordBuilder struct {
+	values map[string]interface{}
+}
+
+// Record returns a builder of an empty record.
+func Record() *RecordBuilder {
+	return &RecordBuilder{values: map[string]interface{}{}}
+}
+
+// Country sets the country ISO code, along with the continent code and the
+// European Union membership known for it.
+func (b *RecordBuilder) Country(isoCode string) *RecordBuilder {
+	b.Set("country.iso_code", isoCode)
+	if maxminddb.CountryInEuropeanUnion(isoCode) {
+		b.Set("country.is_in_european_union", true)
+	}
+	if continent, ok := maxminddb.CountryContinentCode(isoCode); ok {
+		b.Set("continent.code", continent)
+	}
+	return b
+}
+
+// City sets the name of the city.
+func (b *RecordBuilder) City(name string) *RecordBuilder {
+	return b.Set("city.names.en", name)
+}
+
+// Subdivision adds a subdivision, which is more specific than those added
+// before.
+func (b *RecordBuilder) Subdivision(isoCode string) *RecordBuilder {
+	subdivisions, _ := b.values["subdivisions"].([]interface{})
+	b.values["subdivisions"] = append(subdivisions, map[string]interface{}{"iso_code": isoCode})
+	return b
+}
+
+// Location sets the coordinates of the location.
+func (b *RecordBuilder) Location(latitude, longitude float64) *RecordBuilder {
+	b.Set("location.latitude", latitude)
+	return b.Set("location.longitude", longitude)
+}
+
+// TimeZone sets the IANA time zone of the location.
+func (b *RecordBuilder) TimeZone(name string) *RecordBuilder {
+	return b.Set("location.time_zone", name)
+}
+
+// Postal sets the postal code.
+func (b *RecordBuilder) Postal(code string) *RecordBuilder {
+	return b.Set("postal.code", code)
+}
+
+// ASN sets the autonomous system number.
+func (b *RecordBuilder) ASN(number uint32) *RecordBuilder {
+	return b.Set("autonomous_system_number", number)
+}
+
+// ASOrganization sets the organization of the autonomous system.
+func (b *RecordBuilder) ASOrganization(name string) *RecordBuilder {
+	return b.Set("autonomous_system_organization", name)
+}
+
+// Set sets the value at path, a dot-separated sequence of map keys such as
+// "traits.is_anycast", creating the maps on the way. Values in other
+// layouts may be set this way.
+func (b *RecordBuilder) Set(path string, value interface{}) *RecordBuilder {
+	keys := strings.Split(path, ".")
+	m := b.values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := m[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[key] = next
+		}
+		m = next
+	}
+	m[keys[len(keys)-1]] = value
+	return b
+}
+
+// Map returns a copy of the record as nested maps and slices.
+func (b *RecordBuilder) Map() map[string]interface{} {
+	return copyValue(b.values).(map[string]interface{})
+}
+
+// Decode decodes the record into result as a Reader would.
+func (b *RecordBuilder) Decode(result interface{}) error {
+	data, err := encodeValue(nil, reflect.ValueOf(b.values))
+	if err != nil {
+		return err
+	}
+	reader, err := maxminddb.FromBytes(singleRecordDatabase(data))
+	if err != nil {
+		return err
+	}
+	return reader.Lookup(net.IPv6zero, result)
+}
+
+// CityRecord returns the record decoded as a maxminddb.City. It panics if
+// the record cannot be decoded.
+func (b *RecordBuilder) CityRecord() maxminddb.City {
+	var city maxminddb.City
+	if err := b.Decode(&city); err != nil {
+		panic(err)
+	}
+	return city
+}
+
+func copyValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(value))
+		for key, v := range value {
+			m[key] = copyValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(value))
+		for i, v := range value {
+			s[i] = copyValue(v)
+		}
+		return s
+	default:
+		return value
+	}
+}
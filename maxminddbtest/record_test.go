@@ -0,0 +1,53 @@
+package maxminddbtest
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestRecordBuilder(t *testing.T) {
+	builder := Record().
+		Country("DE").
+		City("Berlin").
+		Subdivision("BE").
+		Location(52.52, 13.405).
+		TimeZone("Europe/Berlin").
+		ASN(3320).
+		ASOrganization("Deutsche Telekom AG").
+		Set("traits.is_anycast", true)
+
+	city := builder.CityRecord()
+	if city.Country.IsoCode != "DE" || !city.Country.IsInEuropeanUnion || city.Continent.Code != "EU" {
+		t.Errorf("unexpected country: %+v, %+v", city.Country, city.Continent)
+	}
+	if city.City.Names["en"] != "Berlin" || city.Location.Latitude != 52.52 || city.Location.TimeZoneName != "Europe/Berlin" {
+		t.Errorf("unexpected city: %+v", city)
+	}
+	if !reflect.DeepEqual(city.SubdivisionISOCodes(), []string{"BE"}) || !city.Traits.IsAnycast {
+		t.Errorf("unexpected city: %+v", city)
+	}
+
+	var asn struct {
+		Number       uint   `maxminddb:"autonomous_system_number"`
+		Organization string `maxminddb:"autonomous_system_organization"`
+	}
+	if err := builder.Decode(&asn); err != nil || asn.Number != 3320 || asn.Organization != "Deutsche Telekom AG" {
+		t.Errorf("unexpected ASN: %+v (%v)", asn, err)
+	}
+
+	m := builder.Map()
+	m["country"].(map[string]interface{})["iso_code"] = "FR"
+	if builder.CityRecord().Country.IsoCode != "DE" {
+		t.Error("expected Map to return a copy")
+	}
+
+	stub := NewStubReader().Add("203.0.113.0/24", builder)
+	var record map[string]interface{}
+	if err := stub.Lookup(net.ParseIP("203.0.113.7"), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record["autonomous_system_number"] != uint64(3320) {
+		t.Errorf("unexpected record: %v", record)
+	}
+}
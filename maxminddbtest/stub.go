@@ -0,0 +1,230 @@
+// Package maxminddbtest provides fakes of MaxMind DB readers for tests of
+// code that depends on maxminddb.Geolocator, so that they need no database
+// files.
+package maxminddbtest
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// StubReader is a maxminddb.Geolocator serving records registered by a test
+// and recording the addresses looked up. Records are decoded by the real
+// decoder, so maxminddb tags, Unmarshaler implementations and decoding
+// errors behave as with a database file. It is safe for concurrent use.
+type StubReader struct {
+	// Metadata is returned by DatabaseMetadata.
+	Metadata maxminddb.Metadata
+
+	mu      sync.Mutex
+	entries []stubEntry
+	lookups []net.IP
+	closed  bool
+}
+
+type stubEntry struct {
+	network netip.Prefix
+	reader  *maxminddb.Reader
+	err     error
+}
+
+// NewStubReader returns a StubReader without records, reporting the
+// metadata of an IPv6 database of type "Stub".
+func NewStubReader() *StubReader {
+	return &StubReader{
+		Metadata: maxminddb.Metadata{
+			BinaryFormatMajorVersion: 2,
+			DatabaseType:             "Stub",
+			IPVersion:                6,
+			RecordSize:               24,
+		},
+	}
+}
+
+// Add registers record for network, which is a network in CIDR notation or
+// a single address. The record may be a *RecordBuilder, a map with string
+// keys, a slice or a struct with maxminddb tags, built from the types the
+// decoder produces. Lookups are served by the most specific network
+// containing the address. Add panics if network or record is invalid, and
+// returns s so that calls may be chained.
+func (s *StubReader) Add(network string, record interface{}) *StubReader {
+	prefix := mustParseNetwork(network)
+	if builder, ok := record.(*RecordBuilder); ok {
+		record = builder.values
+	}
+	data, err := encodeValue(nil, reflect.ValueOf(record))
+	if err != nil {
+		panic(err)
+	}
+	reader, err := maxminddb.FromBytes(singleRecordDatabase(data))
+	if err != nil {
+		panic(fmt.Sprintf("maxminddbtest: %v", err))
+	}
+	return s.add(stubEntry{network: prefix, reader: reader})
+}
+
+// AddError makes lookups of addresses in network fail with err, as they
+// would for a corrupt database.
+func (s *StubReader) AddError(network string, err error) *StubReader {
+	return s.add(stubEntry{network: mustParseNetwork(network), err: err})
+}
+
+func (s *StubReader) add(entry stubEntry) *StubReader {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.entries {
+		if s.entries[i].network == entry.network {
+			s.entries[i] = entry
+			return s
+		}
+	}
+	s.entries = append(s.entries, entry)
+	return s
+}
+
+func mustParseNetwork(network string) netip.Prefix {
+	if prefix, err := netip.ParsePrefix(network); err == nil {
+		prefix = prefix.Masked()
+		if prefix.Addr().Is4In6() {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix
+	}
+	addr, err := netip.ParseAddr(network)
+	if err != nil {
+		panic(fmt.Sprintf("maxminddbtest: invalid network %q", network))
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen())
+}
+
+// Lookup decodes the record registered for the most specific network
+// containing ipAddress into result. As with a Reader, result is left
+// unchanged if there is none.
+func (s *StubReader) Lookup(ipAddress net.IP, result interface{}) error {
+	_, _, err := s.LookupNetwork(ipAddress, result)
+	return err
+}
+
+// LookupNetwork is like Lookup but also returns the network of the record.
+// If there is none, the network is the address itself, with a full-length
+// mask.
+func (s *StubReader) LookupNetwork(ipAddress net.IP, result interface{}) (network *net.IPNet, ok bool, err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil, false, maxminddb.ErrClosed
+	}
+	s.lookups = append(s.lookups, append(net.IP(nil), ipAddress...))
+	addr, valid := netip.AddrFromSlice(ipAddress)
+	var match *stubEntry
+	if valid {
+		addr = addr.Unmap()
+		for i, entry := range s.entries {
+			if entry.network.Contains(addr) && (match == nil || entry.network.Bits() > match.network.Bits()) {
+				match = &s.entries[i]
+			}
+		}
+	}
+	var entry stubEntry
+	if match != nil {
+		entry = *match
+	}
+	s.mu.Unlock()
+
+	if !valid {
+		return nil, false, fmt.Errorf("maxminddbtest: invalid IP address %v", ipAddress)
+	}
+	if match == nil {
+		prefix := netip.PrefixFrom(addr, addr.BitLen())
+		return prefixToIPNet(prefix), false, nil
+	}
+	if entry.err != nil {
+		return nil, false, entry.err
+	}
+	if err := entry.reader.Lookup(ipAddress, result); err != nil {
+		return nil, false, err
+	}
+	return prefixToIPNet(entry.network), true, nil
+}
+
+func prefixToIPNet(prefix netip.Prefix) *net.IPNet {
+	addr := prefix.Addr()
+	return &net.IPNet{
+		IP:   net.IP(addr.AsSlice()),
+		Mask: net.CIDRMask(prefix.Bits(), addr.BitLen()),
+	}
+}
+
+// DatabaseMetadata returns s.Metadata.
+func (s *StubReader) DatabaseMetadata() maxminddb.Metadata {
+	return s.Metadata
+}
+
+// Close makes later lookups fail with maxminddb.ErrClosed.
+func (s *StubReader) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// Lookups returns the addresses looked up so far, in order.
+func (s *StubReader) Lookups() []net.IP {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]net.IP(nil), s.lookups...)
+}
+
+// AssertLookups reports an error on t unless the addresses looked up so far
+// are exactly addresses, in order.
+func (s *StubReader) AssertLookups(t testing.TB, addresses ...string) {
+	t.Helper()
+	lookups := s.Lookups()
+	if len(lookups) != len(addresses) {
+		t.Errorf("expected lookups of %v, got %v", addresses, lookups)
+		return
+	}
+	for i, address := range addresses {
+		if !lookups[i].Equal(net.ParseIP(address)) {
+			t.Errorf("expected lookups of %v, got %v", addresses, lookups)
+			return
+		}
+	}
+}
+
+var _ maxminddb.Geolocator = (*StubReader)(nil)
+
+// singleRecordDatabase returns an IPv6 database with a single node whose
+// both records point to data, so that every address has that record.
+func singleRecordDatabase(data []byte) []byte {
+	const nodeCount = 1
+	const dataSectionSeparatorSize = 16
+	pointer := byte(nodeCount + dataSectionSeparatorSize)
+
+	database := []byte{0, 0, pointer, 0, 0, pointer}
+	database = append(database, make([]byte, dataSectionSeparatorSize)...)
+	database = append(database, data...)
+	database = append(database, "\xAB\xCD\xEFMaxMind.com"...)
+	metadata, err := encodeValue(nil, reflect.ValueOf(map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(0),
+		"database_type":               "Stub",
+		"description":                 map[string]string{},
+		"ip_version":                  uint16(6),
+		"languages":                   []string{},
+		"node_count":                  uint32(nodeCount),
+		"record_size":                 uint16(24),
+	}))
+	if err != nil {
+		panic(err)
+	}
+	return append(database, metadata...)
+}
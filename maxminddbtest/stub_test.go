@@ -0,0 +1,130 @@
+package maxminddbtest
+
+import (
+	"errors"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestStubReader(t *testing.T) {
+	stub := NewStubReader().
+		Add("81.2.69.0/24", map[string]interface{}{
+			"country": map[string]interface{}{"iso_code": "GB"},
+		}).
+		Add("81.2.69.160/27", maxminddb.City{
+			Country:      maxminddb.Country{IsoCode: "GB", Names: map[string]string{"en": "United Kingdom"}},
+			Location:     maxminddb.Location{Latitude: 51.5142, Longitude: -0.0931, TimeZoneName: "Europe/London"},
+			Subdivisions: []maxminddb.Subdivision{{IsoCode: "ENG"}},
+		}).
+		Add("2001:218::1", map[string]interface{}{"asn": uint32(2497)})
+
+	var geolocator maxminddb.Geolocator = stub
+
+	var city maxminddb.City
+	network, ok, err := geolocator.LookupNetwork(net.ParseIP("81.2.69.161"), &city)
+	if err != nil || !ok || network.String() != "81.2.69.160/27" {
+		t.Fatalf("unexpected lookup: %v, %v, %v", network, ok, err)
+	}
+	if city.Country.Names["en"] != "United Kingdom" || city.Location.TimeZoneName != "Europe/London" ||
+		!reflect.DeepEqual(city.SubdivisionISOCodes(), []string{"ENG"}) {
+		t.Errorf("unexpected record: %+v", city)
+	}
+
+	city = maxminddb.City{}
+	if err := geolocator.Lookup(net.ParseIP("81.2.69.1"), &city); err != nil || city.Country.IsoCode != "GB" || city.Location.TimeZoneName != "" {
+		t.Errorf("unexpected record of the less specific network: %+v (%v)", city, err)
+	}
+
+	var record map[string]interface{}
+	if err := geolocator.Lookup(net.ParseIP("2001:218::1"), &record); err != nil || record["asn"] != uint64(2497) {
+		t.Errorf("unexpected record: %v (%v)", record, err)
+	}
+
+	record = nil
+	network, ok, err = geolocator.LookupNetwork(net.ParseIP("10.0.0.1"), &record)
+	if err != nil || ok || record != nil || network.String() != "10.0.0.1/32" {
+		t.Errorf("unexpected lookup without a record: %v, %v, %v, %v", network, ok, record, err)
+	}
+
+	var asn struct {
+		ASN string `maxminddb:"asn"`
+	}
+	if err := geolocator.Lookup(net.ParseIP("2001:218::1"), &asn); err == nil {
+		t.Error("expected an error decoding a number into a string")
+	}
+
+	stub.AssertLookups(t, "81.2.69.161", "81.2.69.1", "2001:218::1", "10.0.0.1", "2001:218::1")
+
+	if err := geolocator.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := geolocator.Lookup(net.ParseIP("81.2.69.1"), &city); !errors.Is(err, maxminddb.ErrClosed) {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestStubReaderError(t *testing.T) {
+	corrupt := errors.New("corrupt")
+	stub := NewStubReader().AddError("::/0", corrupt).AddError("0.0.0.0/0", corrupt)
+	var record map[string]interface{}
+	if err := stub.Lookup(net.ParseIP("203.0.113.1"), &record); err != corrupt {
+		t.Errorf("expected the registered error, got %v", err)
+	}
+	if stub.DatabaseMetadata().DatabaseType != "Stub" {
+		t.Errorf("unexpected metadata: %+v", stub.DatabaseMetadata())
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	long := make([]byte, 70000)
+	value := map[string]interface{}{
+		"bool":    true,
+		"bytes":   []byte{0, 0, 0, 42},
+		"double":  42.123456,
+		"float":   float32(1.1),
+		"int32":   int32(-268435456),
+		"long":    string(long),
+		"map":     map[string]interface{}{"array": []interface{}{uint32(1), uint32(2)}},
+		"uint16":  uint16(100),
+		"uint32":  uint32(268435456),
+		"uint64":  uint64(1152921504606846976),
+		"uint128": new(big.Int).Lsh(big.NewInt(1), 120),
+	}
+	data, err := encodeValue(nil, reflect.ValueOf(value))
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := maxminddb.FromBytes(singleRecordDatabase(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err := reader.Lookup(net.ParseIP("1.1.1.1"), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"bool":    true,
+		"bytes":   []byte{0, 0, 0, 42},
+		"double":  42.123456,
+		"float":   float32(1.1),
+		"int32":   -268435456,
+		"long":    string(long),
+		"map":     map[string]interface{}{"array": []interface{}{uint64(1), uint64(2)}},
+		"uint16":  uint64(100),
+		"uint32":  uint64(268435456),
+		"uint64":  uint64(1152921504606846976),
+		"uint128": new(big.Int).Lsh(big.NewInt(1), 120),
+	}
+	if !reflect.DeepEqual(decoded, expected) {
+		t.Errorf("expected %v, got %v", expected, decoded)
+	}
+
+	if _, err := encodeValue(nil, reflect.ValueOf(map[int]string{1: "a"})); err == nil {
+		t.Error("expected an error for a map with int keys")
+	}
+}
@@ -12,6 +12,25 @@ func mmap(fd int, length int) (data []byte, err error) {
 	return unix.Mmap(fd, 0, length, syscall.PROT_READ, syscall.MAP_SHARED)
 }
 
+// allocate returns a page-aligned, writable anonymous mapping of length
+// bytes. The returned bool reports that the memory must be released with
+// munmap.
+func allocate(length int, hugePages bool) ([]byte, bool, error) {
+	if length == 0 {
+		return nil, false, nil
+	}
+	b, err := unix.Mmap(-1, 0, length, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return nil, false, err
+	}
+	if hugePages {
+		// Huge pages are only an optimization, so failing to get them is
+		// not an error.
+		_ = adviseHugePages(b)
+	}
+	return b, true, nil
+}
+
 func munmap(b []byte) (err error) {
 	return unix.Munmap(b)
 }
@@ -47,6 +47,19 @@ func mmap(fd int, length int) (data []byte, err error) {
 	return m, nil
 }
 
+// allocate returns a page-aligned slice of length bytes from the Go heap.
+// Huge pages are not supported, and the returned bool is always false as
+// the memory is released by the garbage collector.
+func allocate(length int, hugePages bool) ([]byte, bool, error) {
+	pageSize := os.Getpagesize()
+	b := make([]byte, length+pageSize)
+	skip := 0
+	if rem := int(uintptr(unsafe.Pointer(&b[0])) % uintptr(pageSize)); rem != 0 {
+		skip = pageSize - rem
+	}
+	return b[skip : skip+length : skip+length], false, nil
+}
+
 func (m *memoryMap) header() *reflect.SliceHeader {
 	return (*reflect.SliceHeader)(unsafe.Pointer(m))
 }
@@ -0,0 +1,229 @@
+package mmdbcbor
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestRoundTripRecords(t *testing.T) {
+	reader, err := maxminddb.Open("../test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	for _, address := range []string{"81.2.69.160", "2001:218::1", "89.160.20.112"} {
+		var record interface{}
+		if err := reader.LookupString(address, &record); err != nil {
+			t.Fatal(err)
+		}
+		data, err := Marshal(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded interface{}
+		if err := Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, record) {
+			t.Errorf("%s: expected %v, got %v", address, record, decoded)
+		}
+		if j, _ := json.Marshal(record); len(data) >= len(j) {
+			t.Errorf("%s: expected fewer than the %d bytes of JSON, got %d", address, len(j), len(data))
+		}
+
+		var city maxminddb.City
+		if err := reader.LookupString(address, &city); err != nil {
+			t.Fatal(err)
+		}
+		data, err = Marshal(&city)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decodedCity maxminddb.City
+		if err := Unmarshal(data, &decodedCity); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decodedCity, city) {
+			t.Errorf("%s: expected %+v, got %+v", address, city, decodedCity)
+		}
+	}
+}
+
+func TestEncoding(t *testing.T) {
+	twoTo64, _ := new(big.Int).SetString("18446744073709551616", 10)
+	minusTwoTo64Minus1, _ := new(big.Int).SetString("-18446744073709551617", 10)
+	// The expected encodings are examples of RFC 8949, appendix A.
+	values := map[string]interface{}{
+		"00":                     uint64(0),
+		"17":                     uint64(23),
+		"1818":                   uint64(24),
+		"1903e8":                 uint64(1000),
+		"1bffffffffffffffff":     uint64(18446744073709551615),
+		"20":                     -1,
+		"3903e7":                 -1000,
+		"fb3ff199999999999a":     1.1,
+		"fa47c35000":             float32(100000.0),
+		"f4":                     false,
+		"f5":                     true,
+		"f6":                     nil,
+		"4401020304":             []byte{1, 2, 3, 4},
+		"6449455446":             "IETF",
+		"83010203":               []interface{}{uint64(1), uint64(2), uint64(3)},
+		"a26161016162820203":     map[string]interface{}{"a": uint64(1), "b": []interface{}{uint64(2), uint64(3)}},
+		"c249010000000000000000": twoTo64,
+		"c349010000000000000000": minusTwoTo64Minus1,
+	}
+	for expected, value := range values {
+		data, err := Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(data) != expected {
+			t.Errorf("expected %s for %v, got %x", expected, value, data)
+		}
+
+		var decoded interface{}
+		if err := Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("%s: expected %#v, got %#v", expected, value, decoded)
+		}
+	}
+
+	var half interface{}
+	if err := Unmarshal([]byte{0xf9, 0x3c, 0x00}, &half); err != nil || half != float32(1) {
+		t.Errorf("expected the half-precision float 1, got %v, %v", half, err)
+	}
+}
+
+func TestStructs(t *testing.T) {
+	type Base struct {
+		Name string `maxminddb:"name"`
+		Code string `maxminddb:"code"`
+	}
+	type Record struct {
+		Base
+		Code    uint16            `maxminddb:"code"`
+		IP      netip.Addr        `maxminddb:"ip,ip"`
+		Network [4]byte           `maxminddb:"network"`
+		Names   map[string]string `maxminddb:"names"`
+		Big     *big.Int          `maxminddb:"big"`
+		Skipped string            `maxminddb:"-"`
+		Empty   string            `maxminddb:"empty"`
+	}
+	record := Record{
+		Base:    Base{Name: "base"},
+		Code:    42,
+		IP:      netip.MustParseAddr("2001:db8::1"),
+		Network: [4]byte{192, 0, 2, 0},
+		Names:   map[string]string{"en": "Name"},
+		Big:     big.NewInt(7),
+		Skipped: "skipped",
+	}
+	data, err := Marshal(record)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var generic map[string]interface{}
+	if err := Unmarshal(data, &generic); err != nil {
+		t.Fatal(err)
+	}
+	expectedGeneric := map[string]interface{}{
+		"name":    "base",
+		"code":    uint64(42),
+		"ip":      netip.MustParseAddr("2001:db8::1").AsSlice(),
+		"network": []byte{192, 0, 2, 0},
+		"names":   map[string]interface{}{"en": "Name"},
+		"big":     big.NewInt(7),
+	}
+	if !reflect.DeepEqual(generic, expectedGeneric) {
+		t.Errorf("expected %v, got %v", expectedGeneric, generic)
+	}
+
+	var decoded Record
+	if err := Unmarshal(data, &decoded); err == nil {
+		t.Error("expected an error decoding the code of the record into the string of the embedded struct")
+	}
+
+	type Decoded struct {
+		Base
+		IP      netip.Addr        `maxminddb:"ip,ip"`
+		Network [4]byte           `maxminddb:"network"`
+		Names   map[string]string `maxminddb:"names"`
+		Big     *big.Int          `maxminddb:"big"`
+	}
+	delete(generic, "code")
+	if data, err = Marshal(generic); err != nil {
+		t.Fatal(err)
+	}
+	var partial Decoded
+	if err := Unmarshal(data, &partial); err != nil {
+		t.Fatal(err)
+	}
+	expected := Decoded{
+		Base:    Base{Name: "base"},
+		IP:      record.IP,
+		Network: record.Network,
+		Names:   record.Names,
+		Big:     record.Big,
+	}
+	if !reflect.DeepEqual(partial, expected) {
+		t.Errorf("expected %+v, got %+v", expected, partial)
+	}
+}
+
+func TestInvalidData(t *testing.T) {
+	inputs := map[string]string{
+		"empty":               "",
+		"truncated integer":   "19",
+		"truncated string":    "6449",
+		"trailing data":       "0000",
+		"indefinite length":   "5f",
+		"reserved":            "1c",
+		"huge array":          "9bffffffffffffffff",
+		"huge map":            "bb7fffffffffffffff00",
+		"huge skipped map":    "a1617abb7fffffffffffffff00",
+		"non-string key":      "a10101",
+		"unsupported tag":     "c001",
+		"deep nesting":        strings.Repeat("81", maxDepth+1) + "00",
+		"unsupported simple":  "f7",
+		"overflowing integer": "3bffffffffffffffff",
+	}
+	for name, input := range inputs {
+		data, _ := hex.DecodeString(input)
+		var record interface{}
+		if err := Unmarshal(data, &record); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		var s struct {
+			A string `maxminddb:"a"`
+		}
+		if err := Unmarshal(data, &s); err == nil {
+			t.Errorf("%s: expected an error decoding into a struct", name)
+		}
+	}
+
+	var s struct {
+		A uint8 `maxminddb:"a"`
+	}
+	err := Unmarshal([]byte{0xa1, 0x61, 'a', 0x19, 0x01, 0x00}, &s)
+	if _, ok := err.(maxminddb.UnmarshalTypeError); !ok {
+		t.Errorf("expected an UnmarshalTypeError for an overflowing uint8, got %v", err)
+	}
+	if err := Unmarshal([]byte{0x00}, s); err == nil {
+		t.Error("expected an error for a non-pointer result")
+	}
+	if _, err := Marshal(map[int]string{1: "a"}); err == nil {
+		t.Error("expected an error encoding a map with integer keys")
+	}
+}
@@ -0,0 +1,538 @@
+package mmdbcbor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"reflect"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// maxDepth limits the nesting of maps and arrays, as the maxminddb decoder
+// does by default, so that corrupt cache entries cannot exhaust the stack.
+const maxDepth = 512
+
+// Unmarshal decodes the CBOR data into v, which must be a pointer, as the
+// maxminddb decoder does with records: maps are decoded into maps with
+// string keys or into structs by the maxminddb tags of their fields,
+// skipping unknown keys, and values into interface{} are of the types
+// maxminddb decodes them to. Since CBOR does not tell integer sizes apart,
+// non-negative integers are decoded into interface{} as uint64 and negative
+// ones as int. Bignums are decoded as *big.Int. Values that v has and data
+// does not are left unchanged, and nulls leave values unchanged too.
+// Values of the wrong type fail with a maxminddb.UnmarshalTypeError.
+func Unmarshal(data []byte, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("result param must be a pointer")
+	}
+	d := decodeState{data: data}
+	if err := d.decode(rv); err != nil {
+		return err
+	}
+	if d.offset != len(d.data) {
+		return fmt.Errorf("mmdbcbor: unexpected data after the value at offset %d", d.offset)
+	}
+	return nil
+}
+
+type decodeState struct {
+	data   []byte
+	offset int
+	depth  int
+}
+
+// head reads the head of the next item and returns its initial byte and
+// its argument, which is the value of an integer or simple value, the
+// length of a string, array or map, the number of a tag or the bits of a
+// float.
+func (d *decodeState) head() (byte, uint64, error) {
+	if d.offset >= len(d.data) {
+		return 0, 0, d.truncated()
+	}
+	initial := d.data[d.offset]
+	d.offset++
+
+	info := initial & 0x1f
+	switch {
+	case info < 24:
+		return initial, uint64(info), nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(d.data)-d.offset < size {
+			return 0, 0, d.truncated()
+		}
+		var arg uint64
+		for _, b := range d.data[d.offset : d.offset+size] {
+			arg = arg<<8 | uint64(b)
+		}
+		d.offset += size
+		return initial, arg, nil
+	case info == 31:
+		return 0, 0, fmt.Errorf("mmdbcbor: indefinite-length item at offset %d is not supported", d.offset-1)
+	default:
+		return 0, 0, fmt.Errorf("mmdbcbor: invalid initial byte %#02x at offset %d", initial, d.offset-1)
+	}
+}
+
+func (d *decodeState) truncated() error {
+	return fmt.Errorf("mmdbcbor: unexpected end of data at offset %d", d.offset)
+}
+
+// payload returns the n bytes of a byte or text string.
+func (d *decodeState) payload(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.offset) {
+		return nil, d.truncated()
+	}
+	b := d.data[d.offset : d.offset+int(n)]
+	d.offset += int(n)
+	return b, nil
+}
+
+// enter increments the nesting depth before decoding the n entries of a
+// map or an array, which are made of itemsPerEntry items of at least a
+// byte each.
+func (d *decodeState) enter(n uint64, itemsPerEntry uint64) error {
+	if n > uint64(len(d.data)-d.offset)/itemsPerEntry {
+		return d.truncated()
+	}
+	if d.depth >= maxDepth {
+		return fmt.Errorf("mmdbcbor: maps and arrays are nested more than %d levels deep", maxDepth)
+	}
+	d.depth++
+	return nil
+}
+
+func (d *decodeState) decode(result reflect.Value) error {
+	start := d.offset
+	initial, arg, err := d.head()
+	if err != nil {
+		return err
+	}
+	if initial == simpleNull {
+		return nil
+	}
+
+	for result.Kind() == reflect.Ptr {
+		if result.IsNil() {
+			result.Set(reflect.New(result.Type().Elem()))
+		}
+		result = result.Elem()
+	}
+	if result.Kind() == reflect.Interface && result.NumMethod() == 0 {
+		d.offset = start
+		value, err := d.decodeInterface()
+		if err != nil {
+			return err
+		}
+		result.Set(reflect.ValueOf(value))
+		return nil
+	}
+
+	switch major := initial >> 5; major {
+	case majorUint, majorNegInt:
+		return d.setInt(result, major, arg)
+	case majorBytes:
+		bytes, err := d.payload(arg)
+		if err != nil {
+			return err
+		}
+		return setBytes(result, bytes)
+	case majorText:
+		text, err := d.payload(arg)
+		if err != nil {
+			return err
+		}
+		if result.Kind() != reflect.String {
+			return typeError(string(text), result)
+		}
+		result.SetString(string(text))
+		return nil
+	case majorArray:
+		return d.decodeArray(result, arg)
+	case majorMap:
+		if result.Kind() == reflect.Struct {
+			return d.decodeStruct(result, arg, start)
+		}
+		return d.decodeMap(result, arg)
+	case majorTag:
+		n, err := d.decodeBignum(arg)
+		if err != nil {
+			return err
+		}
+		if result.Type() != bigIntType {
+			return typeError(n, result)
+		}
+		result.Set(reflect.ValueOf(*n))
+		return nil
+	default:
+		return d.setSimple(result, initial, arg)
+	}
+}
+
+// decodeInterface decodes the next item into the type maxminddb decodes
+// the corresponding value into interface{}.
+func (d *decodeState) decodeInterface() (interface{}, error) {
+	initial, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch initial >> 5 {
+	case majorUint:
+		return arg, nil
+	case majorNegInt:
+		if arg > math.MaxInt {
+			return nil, fmt.Errorf("mmdbcbor: integer before offset %d overflows an int", d.offset)
+		}
+		return -1 - int(arg), nil
+	case majorBytes:
+		bytes, err := d.payload(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, bytes...), nil
+	case majorText:
+		text, err := d.payload(arg)
+		return string(text), err
+	case majorArray:
+		if err := d.enter(arg, 1); err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, arg)
+		for i := range values {
+			if values[i], err = d.decodeInterface(); err != nil {
+				return nil, err
+			}
+		}
+		d.depth--
+		return values, nil
+	case majorMap:
+		if err := d.enter(arg, 2); err != nil {
+			return nil, err
+		}
+		values := make(map[string]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.key()
+			if err != nil {
+				return nil, err
+			}
+			if values[key], err = d.decodeInterface(); err != nil {
+				return nil, err
+			}
+		}
+		d.depth--
+		return values, nil
+	case majorTag:
+		return d.decodeBignum(arg)
+	}
+
+	switch initial {
+	case simpleFalse:
+		return false, nil
+	case simpleTrue:
+		return true, nil
+	case simpleNull:
+		return nil, nil
+	case simpleFloat16:
+		return float32(float16ToFloat64(uint16(arg))), nil
+	case simpleFloat32:
+		return math.Float32frombits(uint32(arg)), nil
+	case simpleFloat64:
+		return math.Float64frombits(arg), nil
+	}
+	return nil, fmt.Errorf("mmdbcbor: unsupported simple value %d before offset %d", arg, d.offset)
+}
+
+// key decodes the next item, which must be a text string, as a map key.
+func (d *decodeState) key() (string, error) {
+	initial, arg, err := d.head()
+	if err != nil {
+		return "", err
+	}
+	if initial>>5 != majorText {
+		return "", fmt.Errorf("mmdbcbor: map key before offset %d is not a string", d.offset)
+	}
+	key, err := d.payload(arg)
+	return string(key), err
+}
+
+// decodeBignum decodes the content of the tag number tag, which must be a
+// bignum.
+func (d *decodeState) decodeBignum(tag uint64) (*big.Int, error) {
+	if tag != tagPositiveBignum && tag != tagNegativeBignum {
+		return nil, fmt.Errorf("mmdbcbor: unsupported tag %d before offset %d", tag, d.offset)
+	}
+	initial, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	if initial>>5 != majorBytes {
+		return nil, fmt.Errorf("mmdbcbor: bignum before offset %d is not a byte string", d.offset)
+	}
+	bytes, err := d.payload(arg)
+	if err != nil {
+		return nil, err
+	}
+	n := new(big.Int).SetBytes(bytes)
+	if tag == tagNegativeBignum {
+		n.Neg(n.Add(n, big.NewInt(1)))
+	}
+	return n, nil
+}
+
+func (d *decodeState) setInt(result reflect.Value, major byte, arg uint64) error {
+	switch result.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if arg <= math.MaxInt64 {
+			n := int64(arg)
+			if major == majorNegInt {
+				n = -1 - n
+			}
+			if !result.OverflowInt(n) {
+				result.SetInt(n)
+				return nil
+			}
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if major == majorUint && !result.OverflowUint(arg) {
+			result.SetUint(arg)
+			return nil
+		}
+	}
+	n := new(big.Int).SetUint64(arg)
+	if major == majorNegInt {
+		n.Neg(n.Add(n, big.NewInt(1)))
+	}
+	return typeError(n, result)
+}
+
+func setBytes(result reflect.Value, bytes []byte) error {
+	switch {
+	case result.Type() == addrType:
+		addr, ok := netip.AddrFromSlice(bytes)
+		if !ok {
+			return typeError(bytes, result)
+		}
+		result.Set(reflect.ValueOf(addr))
+		return nil
+	case result.Kind() == reflect.Slice && result.Type().Elem().Kind() == reflect.Uint8:
+		result.SetBytes(append(make([]byte, 0, len(bytes)), bytes...))
+		return nil
+	case result.Kind() == reflect.Array && result.Type().Elem().Kind() == reflect.Uint8:
+		// As the maxminddb decoder does for arrays, extra bytes are dropped
+		// and missing ones are zeroed.
+		for i := 0; i < result.Len(); i++ {
+			var b byte
+			if i < len(bytes) {
+				b = bytes[i]
+			}
+			result.Index(i).SetUint(uint64(b))
+		}
+		return nil
+	}
+	return typeError(bytes, result)
+}
+
+func (d *decodeState) setSimple(result reflect.Value, initial byte, arg uint64) error {
+	switch initial {
+	case simpleFalse, simpleTrue:
+		if result.Kind() != reflect.Bool {
+			return typeError(initial == simpleTrue, result)
+		}
+		result.SetBool(initial == simpleTrue)
+		return nil
+	case simpleFloat16, simpleFloat32, simpleFloat64:
+		var f float64
+		switch initial {
+		case simpleFloat16:
+			f = float16ToFloat64(uint16(arg))
+		case simpleFloat32:
+			f = float64(math.Float32frombits(uint32(arg)))
+		default:
+			f = math.Float64frombits(arg)
+		}
+		switch result.Kind() {
+		case reflect.Float32, reflect.Float64:
+			if !result.OverflowFloat(f) {
+				result.SetFloat(f)
+				return nil
+			}
+		}
+		return typeError(f, result)
+	}
+	return fmt.Errorf("mmdbcbor: unsupported simple value %d before offset %d", arg, d.offset)
+}
+
+func (d *decodeState) decodeArray(result reflect.Value, n uint64) error {
+	if err := d.enter(n, 1); err != nil {
+		return err
+	}
+	switch result.Kind() {
+	case reflect.Slice:
+		slice := reflect.MakeSlice(result.Type(), int(n), int(n))
+		for i := 0; i < int(n); i++ {
+			if err := d.decode(slice.Index(i)); err != nil {
+				return err
+			}
+		}
+		result.Set(slice)
+	case reflect.Array:
+		for i := 0; i < result.Len(); i++ {
+			if uint64(i) >= n {
+				result.Index(i).Set(reflect.Zero(result.Type().Elem()))
+				continue
+			}
+			if err := d.decode(result.Index(i)); err != nil {
+				return err
+			}
+		}
+		for i := uint64(result.Len()); i < n; i++ {
+			if err := d.skip(); err != nil {
+				return err
+			}
+		}
+	default:
+		return typeError("array", result)
+	}
+	d.depth--
+	return nil
+}
+
+func (d *decodeState) decodeMap(result reflect.Value, n uint64) error {
+	if result.Kind() != reflect.Map || result.Type().Key().Kind() != reflect.String {
+		return typeError("map", result)
+	}
+	if err := d.enter(n, 2); err != nil {
+		return err
+	}
+	if result.IsNil() {
+		result.Set(reflect.MakeMapWithSize(result.Type(), int(n)))
+	}
+	keyType := result.Type().Key()
+	elemType := result.Type().Elem()
+	for i := uint64(0); i < n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+		value := reflect.New(elemType).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+		result.SetMapIndex(reflect.ValueOf(key).Convert(keyType), value)
+	}
+	d.depth--
+	return nil
+}
+
+// decodeStruct decodes the map of n entries into the fields of result,
+// named by their maxminddb tags. As with the maxminddb decoder, the whole
+// map, whose head is at start, is also decoded into each embedded struct.
+func (d *decodeState) decodeStruct(result reflect.Value, n uint64, start int) error {
+	if err := d.enter(n, 2); err != nil {
+		return err
+	}
+	entries := d.offset
+	resultType := result.Type()
+	fields := make(map[string]int, resultType.NumField())
+	for i := 0; i < resultType.NumField(); i++ {
+		field := resultType.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		if field.Anonymous {
+			if !result.Field(i).CanSet() {
+				continue
+			}
+			d.offset = start
+			if err := d.decode(result.Field(i)); err != nil {
+				return err
+			}
+			continue
+		}
+		if field.PkgPath == "" {
+			fields[name] = i
+		}
+	}
+
+	d.offset = entries
+	for i := uint64(0); i < n; i++ {
+		key, err := d.key()
+		if err != nil {
+			return err
+		}
+		j, ok := fields[key]
+		if !ok {
+			if err := d.skip(); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := d.decode(result.Field(j)); err != nil {
+			return err
+		}
+	}
+	d.depth--
+	return nil
+}
+
+// skip moves past the next item.
+func (d *decodeState) skip() error {
+	for count := uint64(1); count > 0; count-- {
+		initial, arg, err := d.head()
+		if err != nil {
+			return err
+		}
+		switch initial >> 5 {
+		case majorBytes, majorText:
+			if _, err := d.payload(arg); err != nil {
+				return err
+			}
+		case majorArray, majorMap:
+			if arg > uint64(len(d.data)-d.offset) {
+				return d.truncated()
+			}
+			count += arg
+			if initial>>5 == majorMap {
+				count += arg
+			}
+		case majorTag:
+			count++
+		}
+	}
+	return nil
+}
+
+// float16ToFloat64 converts the bits of an IEEE 754 half-precision float.
+func float16ToFloat64(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+	var f float64
+	switch exponent {
+	case 0:
+		f = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if bits&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+func typeError(value interface{}, result reflect.Value) error {
+	return maxminddb.UnmarshalTypeError{
+		Value: fmt.Sprintf("%v", value),
+		Type:  result.Type(),
+	}
+}
@@ -0,0 +1,262 @@
+// Package mmdbcbor encodes records decoded from MaxMind DB files as CBOR
+// (RFC 8949) and decodes them back, for caches such as Redis where JSON
+// would take twice the space. Values are encoded as decoded by maxminddb:
+// maps with string keys, arrays, strings, bytes, unsigned and signed
+// integers, float32 and float64 values, booleans and uint128 values held in
+// a *big.Int, which are encoded as CBOR bignums. Structs are encoded as maps
+// keyed by the maxminddb tags of their fields, so records decoded into
+// structs, such as maxminddb.City, are cached as compactly as generic ones
+// and may be decoded into either.
+package mmdbcbor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"net/netip"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// CBOR major types.
+const (
+	majorUint byte = iota
+	majorNegInt
+	majorBytes
+	majorText
+	majorArray
+	majorMap
+	majorTag
+	majorSimple
+)
+
+// Tags of the CBOR bignums.
+const (
+	tagPositiveBignum = 2
+	tagNegativeBignum = 3
+)
+
+// Initial bytes of the simple values and floats.
+const (
+	simpleFalse   = majorSimple<<5 | 20
+	simpleTrue    = majorSimple<<5 | 21
+	simpleNull    = majorSimple<<5 | 22
+	simpleFloat16 = majorSimple<<5 | 25
+	simpleFloat32 = majorSimple<<5 | 26
+	simpleFloat64 = majorSimple<<5 | 27
+)
+
+var (
+	bigIntType = reflect.TypeOf(big.Int{})
+	addrType   = reflect.TypeOf(netip.Addr{})
+)
+
+// Marshal returns the CBOR encoding of v. Map keys are sorted, so equal
+// records have equal encodings. Struct fields are named by their maxminddb
+// tags as when decoding records, and fields with zero values are left out.
+// A netip.Addr is encoded as the 4 or 16 bytes of the address, which the ip
+// tag option of maxminddb accepts. Nil pointers, interfaces, maps and
+// slices outside of structs are encoded as CBOR nulls.
+func Marshal(v interface{}) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(v))
+}
+
+func appendValue(b []byte, value reflect.Value) ([]byte, error) {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return append(b, simpleNull), nil
+		}
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Invalid:
+		return append(b, simpleNull), nil
+	case reflect.String:
+		b = appendHead(b, majorText, uint64(value.Len()))
+		return append(b, value.String()...), nil
+	case reflect.Bool:
+		if value.Bool() {
+			return append(b, simpleTrue), nil
+		}
+		return append(b, simpleFalse), nil
+	case reflect.Float32:
+		b = append(b, simpleFloat32)
+		return appendBigEndian(b, uint64(math.Float32bits(float32(value.Float()))), 4), nil
+	case reflect.Float64:
+		b = append(b, simpleFloat64)
+		return appendBigEndian(b, math.Float64bits(value.Float()), 8), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendHead(b, majorUint, value.Uint()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n := value.Int(); n < 0 {
+			return appendHead(b, majorNegInt, uint64(-1-n)), nil
+		}
+		return appendHead(b, majorUint, uint64(value.Int())), nil
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return append(b, simpleNull), nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			b = appendHead(b, majorBytes, uint64(value.Len()))
+			for i := 0; i < value.Len(); i++ {
+				b = append(b, byte(value.Index(i).Uint()))
+			}
+			return b, nil
+		}
+		b = appendHead(b, majorArray, uint64(value.Len()))
+		for i := 0; i < value.Len(); i++ {
+			var err error
+			if b, err = appendValue(b, value.Index(i)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("mmdbcbor: cannot encode a %s, map keys must be strings", value.Type())
+		}
+		if value.IsNil() {
+			return append(b, simpleNull), nil
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		b = appendHead(b, majorMap, uint64(len(keys)))
+		for _, key := range keys {
+			b = appendHead(b, majorText, uint64(key.Len()))
+			b = append(b, key.String()...)
+			var err error
+			if b, err = appendValue(b, value.MapIndex(key)); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case reflect.Struct:
+		switch value.Type() {
+		case bigIntType:
+			n := new(big.Int)
+			reflect.ValueOf(n).Elem().Set(value)
+			return appendBignum(b, n), nil
+		case addrType:
+			addr := value.Interface().(netip.Addr)
+			if !addr.IsValid() {
+				return append(b, simpleNull), nil
+			}
+			ip := addr.AsSlice()
+			b = appendHead(b, majorBytes, uint64(len(ip)))
+			return append(b, ip...), nil
+		}
+		fields := map[string]reflect.Value{}
+		structFields(value, fields)
+		names := make([]string, 0, len(fields))
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		b = appendHead(b, majorMap, uint64(len(names)))
+		for _, name := range names {
+			b = appendHead(b, majorText, uint64(len(name)))
+			b = append(b, name...)
+			var err error
+			if b, err = appendValue(b, fields[name]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("mmdbcbor: cannot encode a %s", value.Type())
+}
+
+// appendBignum appends n as a CBOR bignum, i.e., a byte string tagged as a
+// positive or negative number.
+func appendBignum(b []byte, n *big.Int) []byte {
+	if n.Sign() < 0 {
+		// Negative bignums hold -1 - n.
+		n = new(big.Int).Neg(n)
+		n.Sub(n, big.NewInt(1))
+		b = appendHead(b, majorTag, tagNegativeBignum)
+	} else {
+		b = appendHead(b, majorTag, tagPositiveBignum)
+	}
+	bytes := n.Bytes()
+	b = appendHead(b, majorBytes, uint64(len(bytes)))
+	return append(b, bytes...)
+}
+
+// appendHead appends the initial byte of an item of type major with the
+// argument n, followed by the bytes of n that do not fit in it.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return appendBigEndian(append(b, major|25), n, 2)
+	case n <= math.MaxUint32:
+		return appendBigEndian(append(b, major|26), n, 4)
+	default:
+		return appendBigEndian(append(b, major|27), n, 8)
+	}
+}
+
+// appendBigEndian appends the size low-order bytes of n, in big-endian order.
+func appendBigEndian(b []byte, n uint64, size int) []byte {
+	var payload [8]byte
+	binary.BigEndian.PutUint64(payload[:], n)
+	return append(b, payload[8-size:]...)
+}
+
+// structFields adds the fields of the struct value with non-zero values to
+// fields, under the names the maxminddb decoder maps them to. Fields of
+// embedded structs are added as if they were fields of value, unless value
+// has a field of the same name.
+func structFields(value reflect.Value, fields map[string]reflect.Value) {
+	declared := map[string]bool{}
+	promoted := map[string]reflect.Value{}
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if field.Anonymous {
+			if fieldValue.Kind() == reflect.Ptr && !fieldValue.IsNil() {
+				fieldValue = fieldValue.Elem()
+			}
+			if fieldValue.Kind() == reflect.Struct {
+				structFields(fieldValue, promoted)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		declared[name] = true
+		if !fieldValue.IsZero() {
+			fields[name] = fieldValue
+		}
+	}
+	for name, fieldValue := range promoted {
+		if !declared[name] {
+			fields[name] = fieldValue
+		}
+	}
+}
+
+// fieldName returns the name of the record key decoded into field, or
+// false if the maxminddb tag of the field is "-".
+func fieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("maxminddb")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
@@ -0,0 +1,154 @@
+package maxminddb
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// PolicyDecision is the outcome of evaluating a Policy for an address.
+type PolicyDecision int
+
+const (
+	// PolicyAllow means the address is allowed.
+	PolicyAllow PolicyDecision = iota
+	// PolicyDeny means the address is denied.
+	PolicyDeny
+)
+
+func (d PolicyDecision) String() string {
+	if d == PolicyDeny {
+		return "deny"
+	}
+	return "allow"
+}
+
+// Policy lists the countries, autonomous systems and networks to allow or
+// deny. Rules are evaluated from the most to the least specific: networks
+// first, then autonomous systems, then countries. At each level a deny rule
+// wins over an allow rule. Addresses that no rule matches get Default.
+type Policy struct {
+	AllowNetworks  []string // CIDR notation, e.g. "192.0.2.0/24"
+	DenyNetworks   []string
+	AllowASNs      []uint
+	DenyASNs       []uint
+	AllowCountries []string // ISO 3166-1 alpha-2 codes, e.g. "US"
+	DenyCountries  []string
+	Default        PolicyDecision
+}
+
+// PolicyMatcher evaluates a Policy using a country database (e.g., GeoIP2
+// Country or City) and an ASN database (e.g., GeoLite2 ASN). It is safe for
+// concurrent use.
+type PolicyMatcher struct {
+	countryReader *Reader
+	asnReader     *Reader
+
+	allowNetworks  []*net.IPNet
+	denyNetworks   []*net.IPNet
+	asns           map[uint]PolicyDecision
+	countries      map[string]PolicyDecision
+	defaultOutcome PolicyDecision
+}
+
+// NewPolicyMatcher returns a PolicyMatcher for policy. countryReader and
+// asnReader may be nil if the policy has no country or ASN rules
+// respectively.
+func NewPolicyMatcher(countryReader, asnReader *Reader, policy Policy) (*PolicyMatcher, error) {
+	if countryReader == nil && len(policy.AllowCountries)+len(policy.DenyCountries) != 0 {
+		return nil, errors.New("maxminddb: country rules require a country database")
+	}
+	if asnReader == nil && len(policy.AllowASNs)+len(policy.DenyASNs) != 0 {
+		return nil, errors.New("maxminddb: ASN rules require an ASN database")
+	}
+
+	m := &PolicyMatcher{
+		countryReader:  countryReader,
+		asnReader:      asnReader,
+		asns:           map[uint]PolicyDecision{},
+		countries:      map[string]PolicyDecision{},
+		defaultOutcome: policy.Default,
+	}
+
+	var err error
+	if m.allowNetworks, err = parseNetworks(policy.AllowNetworks); err != nil {
+		return nil, err
+	}
+	if m.denyNetworks, err = parseNetworks(policy.DenyNetworks); err != nil {
+		return nil, err
+	}
+
+	// Deny rules are added last so that they win.
+	for _, asn := range policy.AllowASNs {
+		m.asns[asn] = PolicyAllow
+	}
+	for _, asn := range policy.DenyASNs {
+		m.asns[asn] = PolicyDeny
+	}
+	for _, country := range policy.AllowCountries {
+		m.countries[strings.ToUpper(country)] = PolicyAllow
+	}
+	for _, country := range policy.DenyCountries {
+		m.countries[strings.ToUpper(country)] = PolicyDeny
+	}
+	return m, nil
+}
+
+func parseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Decision returns the decision of the policy for ipAddress.
+func (m *PolicyMatcher) Decision(ipAddress net.IP) (PolicyDecision, error) {
+	if containsIP(m.denyNetworks, ipAddress) {
+		return PolicyDeny, nil
+	}
+	if containsIP(m.allowNetworks, ipAddress) {
+		return PolicyAllow, nil
+	}
+
+	if len(m.asns) != 0 {
+		var record struct {
+			AutonomousSystemNumber uint `maxminddb:"autonomous_system_number"`
+		}
+		if err := m.asnReader.Lookup(ipAddress, &record); err != nil {
+			return m.defaultOutcome, err
+		}
+		if decision, ok := m.asns[record.AutonomousSystemNumber]; ok && record.AutonomousSystemNumber != 0 {
+			return decision, nil
+		}
+	}
+
+	if len(m.countries) != 0 {
+		var record struct {
+			Country struct {
+				IsoCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		if err := m.countryReader.Lookup(ipAddress, &record); err != nil {
+			return m.defaultOutcome, err
+		}
+		if decision, ok := m.countries[record.Country.IsoCode]; ok {
+			return decision, nil
+		}
+	}
+
+	return m.defaultOutcome, nil
+}
+
+func containsIP(networks []*net.IPNet, ipAddress net.IP) bool {
+	for _, network := range networks {
+		if network.Contains(ipAddress) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPolicyMatcher(t *testing.T) {
+	country, err := Open("test-data/test-data/GeoIP2-Country-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer country.Close()
+
+	policy := Policy{
+		AllowNetworks:  []string{"81.2.69.160/30"},
+		DenyNetworks:   []string{"198.51.100.0/24"},
+		DenyCountries:  []string{"gb"},
+		AllowCountries: []string{"JP", "GB"},
+		Default:        PolicyDeny,
+	}
+	matcher, err := NewPolicyMatcher(country, nil, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions := map[string]PolicyDecision{
+		// The network rule wins over the country rule.
+		"81.2.69.161": PolicyAllow,
+		// Deny wins over allow for GB.
+		"81.2.69.170":   PolicyDeny,
+		"2001:218::1":   PolicyAllow,
+		"198.51.100.10": PolicyDeny,
+		// No rule matches, so the default applies.
+		"10.0.0.1": PolicyDeny,
+	}
+	for address, expected := range decisions {
+		decision, err := matcher.Decision(net.ParseIP(address))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision != expected {
+			t.Errorf("expected %v for %s, got %v", expected, address, decision)
+		}
+	}
+}
+
+func TestPolicyMatcherASNs(t *testing.T) {
+	isp, err := Open("test-data/test-data/GeoIP2-ISP-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer isp.Close()
+
+	matcher, err := NewPolicyMatcher(nil, isp, Policy{DenyASNs: []uint{1221}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decisions := map[string]PolicyDecision{
+		"1.128.0.1": PolicyDeny,
+		"10.0.0.1":  PolicyAllow,
+	}
+	for address, expected := range decisions {
+		decision, err := matcher.Decision(net.ParseIP(address))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision != expected {
+			t.Errorf("expected %v for %s, got %v", expected, address, decision)
+		}
+	}
+}
+
+func TestPolicyMatcherErrors(t *testing.T) {
+	if _, err := NewPolicyMatcher(nil, nil, Policy{DenyCountries: []string{"US"}}); err == nil {
+		t.Error("expected an error for country rules without a country database")
+	}
+	if _, err := NewPolicyMatcher(nil, nil, Policy{DenyASNs: []uint{64496}}); err == nil {
+		t.Error("expected an error for ASN rules without an ASN database")
+	}
+	if _, err := NewPolicyMatcher(nil, nil, Policy{DenyNetworks: []string{"10.0.0.0/33"}}); err == nil {
+		t.Error("expected an error for an invalid network")
+	}
+}
@@ -0,0 +1,156 @@
+package maxminddb
+
+import (
+	"math/big"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// PrefixSet is an immutable set of IP addresses, stored as the smallest
+// sorted list of prefixes covering them. It supports the set operations
+// needed to combine iteration results, e.g., the networks of a country minus
+// the networks of an ASN. IPv4-mapped IPv6 prefixes are stored as IPv4
+// prefixes. The zero value is an empty set.
+type PrefixSet struct {
+	prefixes []netip.Prefix
+
+	// encoded holds the IPv4 and IPv6 entries of a set loaded with
+	// LoadPrefixSet. They are searched in place by Contains and only decoded
+	// into prefixes by the other methods.
+	encoded        *encodedPrefixes
+	decodePrefixes sync.Once
+}
+
+// NewPrefixSet returns the set of the addresses covered by prefixes.
+// Invalid prefixes are ignored.
+func NewPrefixSet(prefixes ...netip.Prefix) *PrefixSet {
+	return &PrefixSet{prefixes: MergePrefixes(prefixes)}
+}
+
+// PrefixSet returns the set of the networks of the database for which match
+// returns true. match is called with each network and the offset of its
+// record, which may be passed to Decode. Networks aliasing the IPv4 subtree
+// are skipped, and IPv4 networks are passed as IPv4 prefixes.
+func (r *Reader) PrefixSet(match func(network netip.Prefix, offset uintptr) (bool, error)) (*PrefixSet, error) {
+	var prefixes []netip.Prefix
+	err := r.WalkNetworks(func(network *net.IPNet, offset uintptr) error {
+		prefix, err := NetworkToPrefix(network)
+		if err != nil {
+			return err
+		}
+		prefix = ipv4SubtreePrefix(prefix)
+		ok, err := match(prefix, offset)
+		if ok {
+			prefixes = append(prefixes, prefix)
+		}
+		return err
+	}, SkipAliasedNetworks())
+	if err != nil {
+		return nil, err
+	}
+	return NewPrefixSet(prefixes...), nil
+}
+
+// Prefixes returns the prefixes of the set, sorted and without overlaps.
+func (s *PrefixSet) Prefixes() []netip.Prefix {
+	return append([]netip.Prefix(nil), s.list()...)
+}
+
+// list returns the prefixes of the set, decoding them first if the set was
+// loaded with LoadPrefixSet.
+func (s *PrefixSet) list() []netip.Prefix {
+	if s.encoded != nil {
+		s.decodePrefixes.Do(func() {
+			s.prefixes = s.encoded.prefixes()
+		})
+	}
+	return s.prefixes
+}
+
+// Contains reports whether addr is in the set.
+func (s *PrefixSet) Contains(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if s.encoded != nil {
+		return s.encoded.contains(addr)
+	}
+	// Find the last prefix starting at or before addr.
+	i := sort.Search(len(s.prefixes), func(i int) bool {
+		return addr.Less(s.prefixes[i].Addr())
+	})
+	return i > 0 && s.prefixes[i-1].Contains(addr)
+}
+
+// AddressCount returns the number of addresses in the set.
+func (s *PrefixSet) AddressCount() *big.Int {
+	return TotalAddressCount(s.list())
+}
+
+// Union returns the set of the addresses in s or other.
+func (s *PrefixSet) Union(other *PrefixSet) *PrefixSet {
+	a, b := s.list(), other.list()
+	prefixes := make([]netip.Prefix, 0, len(a)+len(b))
+	prefixes = append(prefixes, a...)
+	prefixes = append(prefixes, b...)
+	return NewPrefixSet(prefixes...)
+}
+
+// Intersect returns the set of the addresses in both s and other.
+func (s *PrefixSet) Intersect(other *PrefixSet) *PrefixSet {
+	var prefixes []netip.Prefix
+	a, b := s.list(), other.list()
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case a[0].Overlaps(b[0]):
+			// One prefix contains the other. Keep the smaller one, which
+			// cannot overlap any other prefix of the other set.
+			if a[0].Bits() >= b[0].Bits() {
+				prefixes = append(prefixes, a[0])
+				a = a[1:]
+			} else {
+				prefixes = append(prefixes, b[0])
+				b = b[1:]
+			}
+		case a[0].Addr().Less(b[0].Addr()):
+			a = a[1:]
+		default:
+			b = b[1:]
+		}
+	}
+	return &PrefixSet{prefixes: MergePrefixes(prefixes)}
+}
+
+// Subtract returns the set of the addresses in s but not in other.
+func (s *PrefixSet) Subtract(other *PrefixSet) *PrefixSet {
+	var prefixes []netip.Prefix
+	b := other.list()
+	for _, prefix := range s.list() {
+		for len(b) > 0 && !b[0].Overlaps(prefix) && b[0].Addr().Less(prefix.Addr()) {
+			b = b[1:]
+		}
+		n := 0
+		for n < len(b) && b[n].Overlaps(prefix) {
+			n++
+		}
+		prefixes = append(prefixes, subtractPrefixes(prefix, b[:n])...)
+	}
+	return &PrefixSet{prefixes: MergePrefixes(prefixes)}
+}
+
+// subtractPrefixes returns the prefixes covering the addresses of prefix
+// that are not in others, which must be sorted and overlap prefix.
+func subtractPrefixes(prefix netip.Prefix, others []netip.Prefix) []netip.Prefix {
+	if len(others) == 0 {
+		return []netip.Prefix{prefix}
+	}
+	if others[0].Bits() <= prefix.Bits() {
+		return nil
+	}
+	lower, upper, _ := SplitPrefix(prefix)
+	n := 0
+	for n < len(others) && lower.Overlaps(others[n]) {
+		n++
+	}
+	return append(subtractPrefixes(lower, others[:n]), subtractPrefixes(upper, others[n:])...)
+}
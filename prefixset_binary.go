@@ -0,0 +1,124 @@
+package maxminddb
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"sort"
+	"sync"
+)
+
+// The binary form of a PrefixSet is a header made of prefixSetMagic and the
+// big-endian uint32 counts of IPv4 and IPv6 prefixes, followed by the sorted
+// IPv4 entries and the sorted IPv6 entries. An entry is the address of the
+// prefix followed by a byte holding its length. Entries have a fixed size,
+// so a loaded set can be searched without decoding it.
+const (
+	prefixSetMagic      = "MMPS\x01"
+	prefixSetHeaderSize = len(prefixSetMagic) + 8
+	ipv4EntrySize       = 4 + 1
+	ipv6EntrySize       = 16 + 1
+)
+
+var errInvalidPrefixSet = errors.New("maxminddb: invalid prefix set encoding")
+
+// MarshalBinary encodes the set in a compact binary form, which may be
+// loaded with LoadPrefixSet or UnmarshalBinary without the database it was
+// computed from.
+func (s *PrefixSet) MarshalBinary() ([]byte, error) {
+	prefixes := s.list()
+	v4 := sort.Search(len(prefixes), func(i int) bool {
+		return prefixes[i].Addr().Is6()
+	})
+	v6 := len(prefixes) - v4
+
+	data := make([]byte, prefixSetHeaderSize, prefixSetHeaderSize+v4*ipv4EntrySize+v6*ipv6EntrySize)
+	copy(data, prefixSetMagic)
+	binary.BigEndian.PutUint32(data[len(prefixSetMagic):], uint32(v4))
+	binary.BigEndian.PutUint32(data[len(prefixSetMagic)+4:], uint32(v6))
+	for _, prefix := range prefixes {
+		data = append(data, prefix.Addr().AsSlice()...)
+		data = append(data, byte(prefix.Bits()))
+	}
+	return data, nil
+}
+
+// UnmarshalBinary sets s to the set encoded in data by MarshalBinary. Like
+// LoadPrefixSet, it only decodes the prefixes when needed, but it works on
+// a copy of data.
+func (s *PrefixSet) UnmarshalBinary(data []byte) error {
+	encoded, err := parsePrefixSet(append([]byte(nil), data...))
+	if err != nil {
+		return err
+	}
+	s.prefixes = nil
+	s.encoded = encoded
+	s.decodePrefixes = sync.Once{}
+	return nil
+}
+
+// LoadPrefixSet returns the set encoded in data by MarshalBinary. Only the
+// header is read: Contains searches the entries in place, e.g., in a memory
+// mapped file, and the other methods decode them on first use. data must
+// not be modified while the set is in use.
+func LoadPrefixSet(data []byte) (*PrefixSet, error) {
+	encoded, err := parsePrefixSet(data)
+	if err != nil {
+		return nil, err
+	}
+	return &PrefixSet{encoded: encoded}, nil
+}
+
+// encodedPrefixes holds the entries of an encoded PrefixSet.
+type encodedPrefixes struct {
+	v4 []byte
+	v6 []byte
+}
+
+func parsePrefixSet(data []byte) (*encodedPrefixes, error) {
+	if len(data) < prefixSetHeaderSize || string(data[:len(prefixSetMagic)]) != prefixSetMagic {
+		return nil, errInvalidPrefixSet
+	}
+	v4 := uint64(binary.BigEndian.Uint32(data[len(prefixSetMagic):])) * ipv4EntrySize
+	v6 := uint64(binary.BigEndian.Uint32(data[len(prefixSetMagic)+4:])) * ipv6EntrySize
+	data = data[prefixSetHeaderSize:]
+	if uint64(len(data)) != v4+v6 {
+		return nil, errInvalidPrefixSet
+	}
+	return &encodedPrefixes{v4: data[:v4], v6: data[v4:]}, nil
+}
+
+// prefixEntry returns the prefix of the i-th entry of entries.
+func prefixEntry(entries []byte, size, i int) netip.Prefix {
+	entry := entries[i*size : (i+1)*size]
+	addr, _ := netip.AddrFromSlice(entry[:size-1])
+	prefix, _ := addr.Prefix(int(entry[size-1]))
+	return prefix
+}
+
+func (e *encodedPrefixes) contains(addr netip.Addr) bool {
+	entries, size := e.v4, ipv4EntrySize
+	if addr.Is6() {
+		entries, size = e.v6, ipv6EntrySize
+	}
+	n := len(entries) / size
+	// Find the last prefix starting at or before addr.
+	i := sort.Search(n, func(i int) bool {
+		entry := entries[i*size : (i+1)*size]
+		other, _ := netip.AddrFromSlice(entry[:size-1])
+		return addr.Less(other)
+	})
+	return i > 0 && prefixEntry(entries, size, i-1).Contains(addr)
+}
+
+func (e *encodedPrefixes) prefixes() []netip.Prefix {
+	prefixes := make([]netip.Prefix, 0, len(e.v4)/ipv4EntrySize+len(e.v6)/ipv6EntrySize)
+	for i := 0; i < len(e.v4)/ipv4EntrySize; i++ {
+		prefixes = append(prefixes, prefixEntry(e.v4, ipv4EntrySize, i))
+	}
+	for i := 0; i < len(e.v6)/ipv6EntrySize; i++ {
+		prefixes = append(prefixes, prefixEntry(e.v6, ipv6EntrySize, i))
+	}
+	// Invalid entries of corrupt data are dropped.
+	return MergePrefixes(prefixes)
+}
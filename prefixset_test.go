@@ -0,0 +1,183 @@
+package maxminddb
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func prefixSetOf(prefixes ...string) *PrefixSet {
+	var parsed []netip.Prefix
+	for _, p := range prefixes {
+		parsed = append(parsed, netip.MustParsePrefix(p))
+	}
+	return NewPrefixSet(parsed...)
+}
+
+func prefixStrings(s *PrefixSet) []string {
+	var prefixes []string
+	for _, prefix := range s.Prefixes() {
+		prefixes = append(prefixes, prefix.String())
+	}
+	return prefixes
+}
+
+func TestPrefixSetOperations(t *testing.T) {
+	a := prefixSetOf("10.0.0.0/16", "192.0.2.0/24", "2001:db8::/32")
+	b := prefixSetOf("10.0.5.0/24", "10.1.0.0/16", "::ffff:192.0.2.128/121", "2001:db8:1::/48")
+
+	tests := []struct {
+		name     string
+		set      *PrefixSet
+		expected []string
+	}{
+		{
+			name:     "union",
+			set:      a.Union(b),
+			expected: []string{"10.0.0.0/15", "192.0.2.0/24", "2001:db8::/32"},
+		},
+		{
+			name:     "intersect",
+			set:      a.Intersect(b),
+			expected: []string{"10.0.5.0/24", "192.0.2.128/25", "2001:db8:1::/48"},
+		},
+		{
+			name: "subtract",
+			set:  a.Subtract(b),
+			expected: []string{
+				"10.0.0.0/22", "10.0.4.0/24", "10.0.6.0/23", "10.0.8.0/21", "10.0.16.0/20",
+				"10.0.32.0/19", "10.0.64.0/18", "10.0.128.0/17",
+				"192.0.2.0/25",
+				"2001:db8::/48", "2001:db8:2::/47", "2001:db8:4::/46", "2001:db8:8::/45",
+				"2001:db8:10::/44", "2001:db8:20::/43", "2001:db8:40::/42", "2001:db8:80::/41",
+				"2001:db8:100::/40", "2001:db8:200::/39", "2001:db8:400::/38", "2001:db8:800::/37",
+				"2001:db8:1000::/36", "2001:db8:2000::/35", "2001:db8:4000::/34", "2001:db8:8000::/33",
+			},
+		},
+		{
+			name:     "subtract everything",
+			set:      b.Subtract(prefixSetOf("0.0.0.0/0", "::/0")),
+			expected: nil,
+		},
+	}
+	for _, test := range tests {
+		if prefixes := prefixStrings(test.set); !reflect.DeepEqual(prefixes, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, prefixes)
+		}
+	}
+
+	// The difference and the intersection partition the set.
+	if !reflect.DeepEqual(prefixStrings(a.Subtract(b).Union(a.Intersect(b))), prefixStrings(a)) {
+		t.Error("expected the difference and the intersection to make up the set")
+	}
+	if count := a.Intersect(b).AddressCount(); count.String() != "1208925819614629174706560" {
+		t.Errorf("unexpected address count: %v", count)
+	}
+}
+
+func TestPrefixSetContains(t *testing.T) {
+	s := prefixSetOf("10.0.0.0/16", "192.0.2.0/24", "2001:db8::/32")
+	for address, expected := range map[string]bool{
+		"10.0.255.255":       true,
+		"10.1.0.0":           false,
+		"9.255.255.255":      false,
+		"::ffff:192.0.2.200": true,
+		"2001:db8:ffff::1":   true,
+		"2001:db9::":         false,
+		"::":                 false,
+	} {
+		if s.Contains(netip.MustParseAddr(address)) != expected {
+			t.Errorf("expected Contains(%s) to be %v", address, expected)
+		}
+	}
+	if (&PrefixSet{}).Contains(netip.MustParseAddr("10.0.0.1")) {
+		t.Error("expected the empty set to contain nothing")
+	}
+}
+
+func TestReaderPrefixSet(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-Country-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	gb, err := reader.PrefixSet(func(_ netip.Prefix, offset uintptr) (bool, error) {
+		var record struct {
+			Country struct {
+				IsoCode string `maxminddb:"iso_code"`
+			} `maxminddb:"country"`
+		}
+		err := reader.Decode(offset, &record)
+		return record.Country.IsoCode == "GB", err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gb.Contains(netip.MustParseAddr("81.2.69.160")) {
+		t.Error("expected 81.2.69.160 to be in GB")
+	}
+	if gb.Contains(netip.MustParseAddr("2001:218::1")) {
+		t.Error("expected 2001:218::1 not to be in GB")
+	}
+	for _, prefix := range gb.Prefixes() {
+		if prefix.Addr().Is4In6() || prefix.Addr().Is6() && prefix.Bits() >= 96 && prefix.Addr().As16()[0] == 0 {
+			t.Errorf("expected IPv4 networks as IPv4 prefixes, got %v", prefix)
+		}
+	}
+}
+
+func TestPrefixSetBinary(t *testing.T) {
+	s := prefixSetOf("10.0.0.0/16", "192.0.2.128/25", "2001:db8::/32", "2001:db8:1::/48", "::/128")
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := prefixSetHeaderSize + 2*ipv4EntrySize + 2*ipv6EntrySize; len(data) != expected {
+		t.Errorf("expected %d bytes, got %d", expected, len(data))
+	}
+
+	loaded, err := LoadPrefixSet(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for address, expected := range map[string]bool{
+		"10.0.1.1":         true,
+		"10.1.0.0":         false,
+		"192.0.2.200":      true,
+		"192.0.2.1":        false,
+		"2001:db8:ffff::1": true,
+		"::":               true,
+		"::1":              false,
+	} {
+		if loaded.Contains(netip.MustParseAddr(address)) != expected {
+			t.Errorf("expected Contains(%s) to be %v", address, expected)
+		}
+	}
+	if !reflect.DeepEqual(prefixStrings(loaded), prefixStrings(s)) {
+		t.Errorf("expected %v, got %v", prefixStrings(s), prefixStrings(loaded))
+	}
+
+	var unmarshaled PrefixSet
+	if err := unmarshaled.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)-1] = 0
+	if !reflect.DeepEqual(prefixStrings(unmarshaled.Intersect(s)), prefixStrings(s)) {
+		t.Error("expected UnmarshalBinary not to retain its input")
+	}
+
+	empty, err := (&PrefixSet{}).MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded, err := LoadPrefixSet(empty); err != nil || len(loaded.Prefixes()) != 0 {
+		t.Errorf("expected an empty set, got %v, %v", loaded, err)
+	}
+
+	for _, invalid := range [][]byte{nil, []byte("MMPS\x02"), data[:len(data)-1], append(data, 0)} {
+		if _, err := LoadPrefixSet(invalid); err == nil {
+			t.Errorf("expected an error loading %x", invalid)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package maxminddb
+
+import "strings"
+
+// DecodeProfile is a set of record paths that a Reader never decodes. It
+// allows enforcing restrictions on the data an application reads, e.g., for
+// privacy reasons, independently of the types records are decoded into.
+type DecodeProfile struct {
+	children map[string]*DecodeProfile
+	omitted  bool
+}
+
+// PreciseLocationProfile omits the fields that locate an address more
+// precisely than its city: the coordinates, the metro code and the postal
+// information.
+var PreciseLocationProfile = NewDecodeProfile(
+	"location.latitude",
+	"location.longitude",
+	"location.metro_code",
+	"postal",
+)
+
+// NewDecodeProfile returns a DecodeProfile omitting the given paths. A path is
+// a dot-separated list of map keys starting at the root of the record, such
+// as "location.latitude". Arrays do not add a path element; for instance,
+// "subdivisions.names" omits the names of every subdivision.
+func NewDecodeProfile(omittedPaths ...string) *DecodeProfile {
+	profile := &DecodeProfile{}
+	for _, path := range omittedPaths {
+		node := profile
+		for _, key := range strings.Split(path, ".") {
+			if node.children == nil {
+				node.children = map[string]*DecodeProfile{}
+			}
+			child, ok := node.children[key]
+			if !ok {
+				child = &DecodeProfile{}
+				node.children[key] = child
+			}
+			node = child
+		}
+		node.omitted = true
+	}
+	return profile
+}
+
+// restricts reports whether some path below p is omitted. It may be called
+// on a nil profile.
+func (p *DecodeProfile) restricts() bool {
+	return p != nil && len(p.children) != 0
+}
+
+// enterKey moves the decoder to the profile node of key. It returns the node
+// to restore once the value of key is decoded, and whether the value must be
+// skipped instead.
+func (d *decoder) enterKey(key string) (*DecodeProfile, bool) {
+	parent := d.node
+	if parent == nil {
+		return nil, false
+	}
+	child := parent.children[key]
+	if child != nil && child.omitted {
+		return parent, true
+	}
+	d.node = child
+	return parent, false
+}
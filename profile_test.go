@@ -0,0 +1,59 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestPreciseLocationProfile(t *testing.T) {
+	reader, err := Open(
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+		WithDecodeProfile(PreciseLocationProfile),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	ip := net.ParseIP("81.2.69.142")
+
+	var record struct {
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			Longitude float64 `maxminddb:"longitude"`
+			TimeZone  string  `maxminddb:"time_zone"`
+		} `maxminddb:"location"`
+	}
+	if err := reader.Lookup(ip, &record); err != nil {
+		t.Fatal(err)
+	}
+	if record.Location.Latitude != 0 || record.Location.Longitude != 0 {
+		t.Errorf("expected no coordinates, got %v", record.Location)
+	}
+	if record.Country.IsoCode != "GB" || record.Location.TimeZone != "Europe/London" {
+		t.Errorf("unexpected record: %+v", record)
+	}
+
+	var generic map[string]interface{}
+	if err := reader.Lookup(ip, &generic); err != nil {
+		t.Fatal(err)
+	}
+	location := generic["location"].(map[string]interface{})
+	if _, ok := location["latitude"]; ok {
+		t.Errorf("expected no latitude, got %v", location)
+	}
+	if _, ok := location["time_zone"]; !ok {
+		t.Errorf("expected a time zone, got %v", location)
+	}
+
+	var offsets struct {
+		Country  uintptr `maxminddb:"country"`
+		Location uintptr `maxminddb:"location"`
+	}
+	if err := reader.Lookup(ip, &offsets); err == nil {
+		t.Error("expected an error when capturing the offset of a restricted map")
+	}
+}
@@ -0,0 +1,36 @@
+package proxyplugin_test
+
+import (
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang/proxyplugin"
+)
+
+// This example shows how to reload the database, e.g., after it was
+// updated, without interrupting lookups.
+func ExamplePlugin_Reload() {
+	plugin := proxyplugin.New(proxyplugin.Config{
+		Database: "../test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb",
+	})
+	defer plugin.Close()
+
+	var record map[string]string
+	if err := plugin.Lookup(net.ParseIP("1.1.1.3"), &record); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(record["ip"])
+
+	// Lookups in progress complete on the previous database.
+	if err := plugin.Reload(); err != nil {
+		log.Fatal(err)
+	}
+	if err := plugin.Lookup(net.ParseIP("1.1.1.3"), &record); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(record["ip"])
+	// Output:
+	// 1.1.1.2
+	// 1.1.1.2
+}
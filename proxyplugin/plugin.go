@@ -0,0 +1,217 @@
+// Package proxyplugin adapts a MaxMind DB reader to the needs of reverse
+// proxy plugins, such as Caddy modules or Traefik middlewares: configuration
+// from strings, lazy opening of the database and reloading on SIGHUP.
+package proxyplugin
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Config configures a Plugin.
+type Config struct {
+	// Database is the path of the MaxMind DB file.
+	Database string
+
+	// ReloadOnSIGHUP makes the plugin reopen the database when the process
+	// receives SIGHUP.
+	ReloadOnSIGHUP bool
+
+	// OnReloadError is called when reloading the database fails. The
+	// previous database stays in use.
+	OnReloadError func(error)
+}
+
+// ParseConfig builds a Config from the string options proxies pass to
+// plugins. The supported options are "database" (required) and
+// "reload_on_sighup" (a boolean as accepted by strconv.ParseBool).
+func ParseConfig(options map[string]string) (Config, error) {
+	var config Config
+	for key, value := range options {
+		switch key {
+		case "database":
+			config.Database = value
+		case "reload_on_sighup":
+			reload, err := strconv.ParseBool(value)
+			if err != nil {
+				return Config{}, fmt.Errorf("proxyplugin: invalid reload_on_sighup value %q", value)
+			}
+			config.ReloadOnSIGHUP = reload
+		default:
+			return Config{}, fmt.Errorf("proxyplugin: unknown option %q", key)
+		}
+	}
+	if config.Database == "" {
+		return Config{}, errors.New("proxyplugin: the database option is required")
+	}
+	return config, nil
+}
+
+// ErrClosed is returned when using a Plugin after Close.
+var ErrClosed = errors.New("proxyplugin: plugin is closed")
+
+// Plugin gives access to a MaxMind DB that is opened on first use. It is
+// safe for concurrent use.
+type Plugin struct {
+	config Config
+
+	mu      sync.RWMutex
+	reader  *maxminddb.Reader
+	loaded  time.Time
+	closed  bool
+	signals chan os.Signal
+}
+
+// New returns a Plugin for config. The database is not opened until it is
+// first needed, but with ReloadOnSIGHUP the plugin watches for SIGHUP right
+// away, until Close.
+func New(config Config) *Plugin {
+	p := &Plugin{config: config}
+	if config.ReloadOnSIGHUP {
+		p.signals = make(chan os.Signal, 1)
+		signal.Notify(p.signals, syscall.SIGHUP)
+		go p.watchSignals(p.signals)
+	}
+	return p
+}
+
+// Lookup opens the database if needed and looks up ip, decoding its record
+// into result.
+func (p *Plugin) Lookup(ip net.IP, result interface{}) error {
+	return p.withReader(func(reader *maxminddb.Reader) error {
+		return reader.Lookup(ip, result)
+	})
+}
+
+// LookupFlat opens the database if needed and returns the flattened record
+// for ip.
+func (p *Plugin) LookupFlat(ip net.IP) (maxminddb.FlatRecord, error) {
+	var record maxminddb.FlatRecord
+	err := p.withReader(func(reader *maxminddb.Reader) error {
+		var err error
+		record, err = reader.LookupFlat(ip)
+		return err
+	})
+	return record, err
+}
+
+// Metadata opens the database if needed and returns its metadata.
+func (p *Plugin) Metadata() (maxminddb.Metadata, error) {
+	var metadata maxminddb.Metadata
+	err := p.withReader(func(reader *maxminddb.Reader) error {
+		metadata = reader.Metadata
+		return nil
+	})
+	return metadata, err
+}
+
+// LastReload returns when the database in use was opened, or the zero
+// time if it has not been opened yet.
+func (p *Plugin) LastReload() time.Time {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loaded
+}
+
+// withReader calls f with the current reader, which stays open until f
+// returns.
+func (p *Plugin) withReader(f func(*maxminddb.Reader) error) error {
+	p.mu.RLock()
+	if p.reader != nil {
+		defer p.mu.RUnlock()
+		return f(p.reader)
+	}
+	closed := p.closed
+	p.mu.RUnlock()
+	if closed {
+		return ErrClosed
+	}
+
+	if err := p.open(); err != nil {
+		return err
+	}
+	return p.withReader(f)
+}
+
+func (p *Plugin) open() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return ErrClosed
+	}
+	if p.reader != nil {
+		return nil
+	}
+
+	reader, err := maxminddb.Open(p.config.Database)
+	if err != nil {
+		return err
+	}
+	p.reader = reader
+	p.loaded = time.Now()
+	return nil
+}
+
+func (p *Plugin) watchSignals(signals chan os.Signal) {
+	for range signals {
+		if err := p.Reload(); err != nil && p.config.OnReloadError != nil {
+			p.config.OnReloadError(err)
+		}
+	}
+}
+
+// Reload reopens the database. Lookups in progress complete on the previous
+// database, which is closed once they are done. If the database cannot be
+// opened, the previous one stays in use.
+func (p *Plugin) Reload() error {
+	reader, err := maxminddb.Open(p.config.Database)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		reader.Close()
+		return ErrClosed
+	}
+	previous := p.reader
+	p.reader = reader
+	p.loaded = time.Now()
+	p.mu.Unlock()
+
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// Close stops watching for signals and closes the database.
+func (p *Plugin) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+
+	if p.signals != nil {
+		signal.Stop(p.signals)
+		close(p.signals)
+	}
+	if p.reader == nil {
+		return nil
+	}
+	err := p.reader.Close()
+	p.reader = nil
+	return err
+}
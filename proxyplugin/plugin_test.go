@@ -0,0 +1,105 @@
+package proxyplugin
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const testDatabase = "../test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb"
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(map[string]string{
+		"database":         testDatabase,
+		"reload_on_sighup": "true",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Database != testDatabase || !config.ReloadOnSIGHUP {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	invalid := []map[string]string{
+		{},
+		{"database": testDatabase, "reload_on_sighup": "sometimes"},
+		{"database": testDatabase, "cache": "100"},
+	}
+	for _, options := range invalid {
+		if _, err := ParseConfig(options); err == nil {
+			t.Errorf("expected an error for %v", options)
+		}
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	plugin := New(Config{Database: testDatabase})
+	if plugin.reader != nil {
+		t.Fatal("expected the database to be opened lazily")
+	}
+
+	var result map[string]string
+	if err := plugin.Lookup(net.ParseIP("1.1.1.3"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["ip"] != "1.1.1.2" {
+		t.Errorf("unexpected record: %v", result)
+	}
+
+	opened := plugin.LastReload()
+	if opened.IsZero() {
+		t.Error("expected the time the database was opened")
+	}
+	if err := plugin.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if plugin.LastReload().Before(opened) {
+		t.Errorf("expected the reload time to be at least %v, got %v", opened, plugin.LastReload())
+	}
+	record, err := plugin.LookupFlat(net.ParseIP("1.1.1.3"))
+	if err != nil || record["ip"] != "1.1.1.2" {
+		t.Errorf("unexpected record after reload: %v (%v)", record, err)
+	}
+
+	if err := plugin.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := plugin.Lookup(net.ParseIP("1.1.1.3"), &result); err != ErrClosed {
+		t.Errorf("expected ErrClosed, got %v", err)
+	}
+}
+
+func TestPluginMissingDatabase(t *testing.T) {
+	plugin := New(Config{Database: "does-not-exist.mmdb"})
+	if _, err := plugin.Metadata(); err == nil {
+		t.Error("expected an error for a missing database")
+	}
+}
+
+func TestPluginSIGHUPBeforeOpen(t *testing.T) {
+	errs := make(chan error, 1)
+	plugin := New(Config{
+		Database:       "does-not-exist.mmdb",
+		ReloadOnSIGHUP: true,
+		OnReloadError:  func(err error) { errs <- err },
+	})
+	defer plugin.Close()
+
+	process, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := process.Signal(syscall.SIGHUP); err != nil {
+		t.Skipf("cannot send SIGHUP: %v", err)
+	}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected the error of the reload")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected SIGHUP to reload the database before it was first opened")
+	}
+}
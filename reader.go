@@ -2,10 +2,13 @@ package maxminddb
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"reflect"
+	"runtime/pprof"
 )
 
 const (
@@ -26,6 +29,8 @@ type Reader struct {
 	decoder       decoder
 	Metadata      Metadata
 	ipv4Start     uint
+	labels        map[string]pprof.LabelSet
+	zonePolicy    ZonePolicy
 }
 
 // Metadata holds the metadata decoded from the MaxMind DB file. In particular
@@ -44,9 +49,109 @@ type Metadata struct {
 	RecordSize               uint              `maxminddb:"record_size"`
 }
 
+// ReaderOption configures optional behavior of a Reader. Options may be
+// passed to Open and FromBytes.
+type ReaderOption func(*readerOptions)
+
+type readerOptions struct {
+	profile     *DecodeProfile
+	stats       *DecodeStats
+	pprofLabels bool
+	zonePolicy  ZonePolicy
+
+	warn               func(string)
+	lenientMetadata    bool
+	mappedIPv4Fallback bool
+
+	maxDepth     int
+	validateUTF8 bool
+}
+
+// WithDecodeProfile makes the Reader skip the paths omitted by profile when
+// decoding records, whatever the type of the result value.
+func WithDecodeProfile(profile *DecodeProfile) ReaderOption {
+	return func(o *readerOptions) {
+		o.profile = profile
+	}
+}
+
+// WithDecodeStats makes the Reader record in stats the mismatches between
+// the records it decodes and the structs they are decoded into. This slows
+// down decoding into structs.
+func WithDecodeStats(stats *DecodeStats) ReaderOption {
+	return func(o *readerOptions) {
+		o.stats = stats
+	}
+}
+
+// WithProfilerLabels makes LookupContext, Export and VerifyReportContext
+// attach pprof labels to the goroutine running them, so that CPU profiles
+// attribute time to lookups, scans and verification. The labels are
+// "operation", set to "lookup", "scan" or "verify", and "db", set to the
+// database type. As with pprof.Do, they are added to the labels of the
+// context, which the goroutine is set back to afterwards, so the context
+// should carry the labels of the goroutine. Methods without a context never
+// change the labels of the goroutine. This adds a small cost to each lookup.
+func WithProfilerLabels() ReaderOption {
+	return func(o *readerOptions) {
+		o.pprofLabels = true
+	}
+}
+
+// WithMaxDecodeDepth limits the number of maps and arrays that may contain
+// a decoded value to depth, instead of the default of 512. Decoding deeper
+// values fails with an InvalidDatabaseError. Since the decoder recurses into
+// maps and arrays, the limit bounds the stack used on hostile databases.
+// The default is used if depth is not positive.
+func WithMaxDecodeDepth(depth int) ReaderOption {
+	return func(o *readerOptions) {
+		o.maxDepth = depth
+	}
+}
+
+// WithUTF8Validation makes the Reader check that the strings and map keys
+// it decodes are valid UTF-8, as the MaxMind DB format requires, instead of
+// trusting the database. Invalid ones fail with an InvalidDatabaseError,
+// and the accessors returning a string and a bool report them as missing.
+// This is useful with databases of unknown provenance, at the cost of
+// reading every string twice.
+func WithUTF8Validation() ReaderOption {
+	return func(o *readerOptions) {
+		o.validateUTF8 = true
+	}
+}
+
+// ZonePolicy defines how a Reader handles IPv6 addresses with a zone, e.g.,
+// "fe80::1%eth0". Zones only have a meaning on the host where the address
+// was seen, and are never part of the database.
+type ZonePolicy int
+
+const (
+	// ZoneStrip looks up the address without its zone. This is the default.
+	ZoneStrip ZonePolicy = iota
+	// ZoneReject fails lookups of zoned addresses with a ZonedAddressError.
+	ZoneReject
+	// ZoneLinkLocal classifies zoned addresses as link-local, failing their
+	// lookups with ErrLinkLocal without searching the database.
+	ZoneLinkLocal
+)
+
+// WithZonePolicy sets how the Reader handles zoned IPv6 addresses passed to
+// the lookup methods taking textual or netip addresses.
+func WithZonePolicy(policy ZonePolicy) ReaderOption {
+	return func(o *readerOptions) {
+		o.zonePolicy = policy
+	}
+}
+
 // FromBytes takes a byte slice corresponding to a MaxMind DB file and returns
 // a Reader structure or an error.
-func FromBytes(buffer []byte) (*Reader, error) {
+func FromBytes(buffer []byte, options ...ReaderOption) (*Reader, error) {
+	var opts readerOptions
+	for _, option := range options {
+		option(&opts)
+	}
+
 	metadataStart := bytes.LastIndex(buffer, metadataStartMarker)
 
 	if metadataStart == -1 {
@@ -54,14 +159,21 @@ func FromBytes(buffer []byte) (*Reader, error) {
 	}
 
 	metadataStart += len(metadataStartMarker)
-	metadataDecoder := decoder{buffer[metadataStart:]}
+	metadataDecoder := decoder{buffer: buffer[metadataStart:], validateUTF8: opts.validateUTF8}
 
 	var metadata Metadata
 
 	rvMetdata := reflect.ValueOf(&metadata)
 	_, err := metadataDecoder.decode(0, rvMetdata)
 	if err != nil {
-		return nil, err
+		if _, ok := err.(UnmarshalTypeError); !ok || !opts.lenientMetadata {
+			return nil, err
+		}
+		opts.warnf("tolerating unusual metadata: %v", err)
+		metadata, err = decodeLenientMetadata(metadataDecoder, &opts)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	searchTreeSize := metadata.NodeCount * metadata.RecordSize / 4
@@ -71,17 +183,37 @@ func FromBytes(buffer []byte) (*Reader, error) {
 		return nil, newInvalidDatabaseError("the MaxMind DB contains invalid metadata")
 	}
 	d := decoder{
-		buffer[searchTreeSize+dataSectionSeparatorSize : metadataStart-len(metadataStartMarker)],
+		buffer:       buffer[searchTreeSize+dataSectionSeparatorSize : metadataStart-len(metadataStartMarker)],
+		profile:      opts.profile,
+		stats:        opts.stats,
+		maxDepth:     opts.maxDepth,
+		validateUTF8: opts.validateUTF8,
 	}
 
 	reader := &Reader{
-		buffer:    buffer,
-		decoder:   d,
-		Metadata:  metadata,
-		ipv4Start: 0,
+		buffer:     buffer,
+		decoder:    d,
+		Metadata:   metadata,
+		ipv4Start:  0,
+		zonePolicy: opts.zonePolicy,
+	}
+
+	if opts.pprofLabels {
+		reader.labels = make(map[string]pprof.LabelSet)
+		for _, operation := range []string{"lookup", "scan", "verify"} {
+			reader.labels[operation] = pprof.Labels("operation", operation, "db", metadata.DatabaseType)
+		}
 	}
 
 	reader.ipv4Start, err = reader.startNode()
+	if err == nil && opts.mappedIPv4Fallback && metadata.IPVersion == 6 && reader.ipv4Start == metadata.NodeCount {
+		var mapped uint
+		mapped, err = reader.mappedIPv4Start()
+		if err == nil && mapped != metadata.NodeCount {
+			opts.warnf("the database has no IPv4 subtree at ::/96; looking up IPv4 addresses in ::ffff:0:0/96")
+			reader.ipv4Start = mapped
+		}
+	}
 
 	return reader, err
 }
@@ -114,6 +246,71 @@ func (r *Reader) Lookup(ipAddress net.IP, result interface{}) error {
 	return r.retrieveData(pointer, result)
 }
 
+// LookupContext is like Lookup but, with WithProfilerLabels, labels the
+// lookup for CPU profiles on top of the labels of ctx.
+func (r *Reader) LookupContext(ctx context.Context, ipAddress net.IP, result interface{}) error {
+	if r.labels != nil {
+		return r.labeled(ctx, "lookup", func() error {
+			return r.Lookup(ipAddress, result)
+		})
+	}
+	return r.Lookup(ipAddress, result)
+}
+
+// LookupNetwork is like Lookup but also returns the network of the search
+// tree containing the address. Neighbouring networks may share the same
+// record. The network may be used to cache results by network rather than
+// by address. ok is false if there is no record for the address, in
+// which case the network is the block without a record.
+func (r *Reader) LookupNetwork(ipAddress net.IP, result interface{}) (network *net.IPNet, ok bool, err error) {
+	var pointer uint
+	pointer, network, err = r.lookupNetwork(ipAddress)
+	if pointer == 0 || err != nil {
+		return network, false, err
+	}
+	return network, true, r.retrieveData(pointer, result)
+}
+
+// LookupString is like Lookup but takes the IP address in its textual form,
+// e.g., "203.0.113.9" or "2001:db8::1". An AddressParseError is returned if
+// the address is not valid. IPv6 addresses with a zone, e.g.,
+// "fe80::1%eth0", are handled according to the ZonePolicy of the Reader.
+func (r *Reader) LookupString(address string, result interface{}) error {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return AddressParseError{Address: address}
+	}
+	ip, err := r.addrToIP(addr)
+	if err != nil {
+		return err
+	}
+	return r.Lookup(ip, result)
+}
+
+// addrToIP converts addr to a net.IP, applying the ZonePolicy of the Reader.
+func (r *Reader) addrToIP(addr netip.Addr) (net.IP, error) {
+	addr, err := r.unzone(addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.IP(addr.Unmap().AsSlice()), nil
+}
+
+// unzone applies the ZonePolicy of the Reader to addr, returning it without
+// its zone or the error of the policy.
+func (r *Reader) unzone(addr netip.Addr) (netip.Addr, error) {
+	if addr.Zone() == "" {
+		return addr, nil
+	}
+	switch r.zonePolicy {
+	case ZoneReject:
+		return netip.Addr{}, ZonedAddressError{Address: addr.String()}
+	case ZoneLinkLocal:
+		return netip.Addr{}, ErrLinkLocal
+	}
+	return addr.WithZone(""), nil
+}
+
 // LookupOffset maps an argument net.IP to a corresponding record offset in the
 // database. NotFound is returned if no such record is found, and a record may
 // otherwise be extracted by passing the returned offset to Decode. LookupOffset
@@ -143,19 +340,97 @@ func (r *Reader) LookupOffset(ipAddress net.IP) (uintptr, error) {
 // the City database, all records of the same country will reference a
 // single representative record for that country. This uintptr behavior allows
 // clients to leverage this normalization in their own sub-record caching.
+// When the Reader has a DecodeProfile, maps containing omitted paths cannot
+// be captured this way.
+//
+// A struct field of type netip.Addr with the ip tag option, e.g.,
+// `maxminddb:"address,ip"`, is decoded from a uint128, which is read as an
+// IPv6 address, or from a 4 or 16 byte array.
+//
+// Values of types implementing Unmarshaler decode themselves.
 func (r *Reader) Decode(offset uintptr, result interface{}) error {
 	rv := reflect.ValueOf(result)
 	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return errors.New("result param must be a pointer")
 	}
+	if r.buffer == nil {
+		return ErrClosed
+	}
+
+	// The decoder tracks its position in the record and the pointers it
+	// follows, so use a copy.
+	d := r.decoder
+	d.node = d.profile
+	_, err := d.decode(uint(offset), rv)
+	return err
+}
+
+// decodeReusing is like Decode but reuses the maps and slices already in
+// result.
+func (r *Reader) decodeReusing(offset uintptr, result interface{}) error {
+	rv := reflect.ValueOf(result)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("result param must be a pointer")
+	}
+	if r.buffer == nil {
+		return ErrClosed
+	}
 
-	_, err := r.decoder.decode(uint(offset), reflect.ValueOf(result))
+	d := r.decoder
+	d.node = d.profile
+	d.reuse = true
+	_, err := d.decode(uint(offset), rv)
 	return err
 }
 
+// AnonymizeToNetwork returns the first address of the network in the search
+// tree that contains ipAddress. For instance, if the database has a single
+// record for 1.2.3.0/24, 1.2.3.4 is anonymized to 1.2.3.0. This allows
+// storing an identifier that has the same granularity as the data in the
+// database. Addresses in networks without a record are anonymized to the
+// empty network of the search tree that contains them.
+func (r *Reader) AnonymizeToNetwork(ipAddress net.IP) (net.IP, error) {
+	_, network, err := r.lookupNetwork(ipAddress)
+	if err != nil {
+		return nil, err
+	}
+	return network.IP, nil
+}
+
+// lookupNetwork returns the record pointer for ipAddress, which is 0 if
+// there is no record, and the network of the search tree containing it.
+func (r *Reader) lookupNetwork(ipAddress net.IP) (uint, *net.IPNet, error) {
+	ipAddress, err := r.normalizeAddress(ipAddress)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	pointer, prefixLength, err := r.findAddressInTree(ipAddress, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	mask := net.CIDRMask(int(prefixLength), len(ipAddress)*8)
+	return pointer, &net.IPNet{IP: ipAddress.Mask(mask), Mask: mask}, nil
+}
+
 func (r *Reader) lookupPointer(ipAddress net.IP) (uint, error) {
+	ipAddress, err := r.normalizeAddress(ipAddress)
+	if err != nil {
+		return 0, err
+	}
+
+	pointer, _, err := r.findAddressInTree(ipAddress, nil)
+	return pointer, err
+}
+
+// normalizeAddress returns the 4 byte form of IPv4 addresses and checks that
+// the address can be looked up in the database, which must not be closed.
+func (r *Reader) normalizeAddress(ipAddress net.IP) (net.IP, error) {
+	if r.buffer == nil {
+		return nil, ErrClosed
+	}
 	if ipAddress == nil {
-		return 0, errors.New("ipAddress passed to Lookup cannot be nil")
+		return nil, errors.New("ipAddress passed to Lookup cannot be nil")
 	}
 
 	ipV4Address := ipAddress.To4()
@@ -163,13 +438,15 @@ func (r *Reader) lookupPointer(ipAddress net.IP) (uint, error) {
 		ipAddress = ipV4Address
 	}
 	if len(ipAddress) == 16 && r.Metadata.IPVersion == 4 {
-		return 0, fmt.Errorf("error looking up '%s': you attempted to look up an IPv6 address in an IPv4-only database", ipAddress.String())
+		return nil, fmt.Errorf("error looking up '%s': you attempted to look up an IPv6 address in an IPv4-only database", ipAddress.String())
 	}
-
-	return r.findAddressInTree(ipAddress)
+	return ipAddress, nil
 }
 
-func (r *Reader) findAddressInTree(ipAddress net.IP) (uint, error) {
+// findAddressInTree returns the record pointer for ipAddress along with the
+// prefix length of the network the record applies to. If steps is not nil,
+// the nodes read are appended to it.
+func (r *Reader) findAddressInTree(ipAddress net.IP, steps *[]TraceStep) (uint, uint, error) {
 
 	bitCount := uint(len(ipAddress) * 8)
 
@@ -180,23 +457,58 @@ func (r *Reader) findAddressInTree(ipAddress net.IP) (uint, error) {
 
 	nodeCount := r.Metadata.NodeCount
 
-	for i := uint(0); i < bitCount && node < nodeCount; i++ {
+	i := uint(0)
+	for ; i < bitCount && node < nodeCount; i++ {
 		bit := uint(1) & (uint(ipAddress[i>>3]) >> (7 - (i % 8)))
 
-		var err error
-		node, err = r.readNode(node, bit)
+		next, err := r.readNode(node, bit)
 		if err != nil {
-			return 0, err
+			return 0, 0, err
 		}
+		if steps != nil {
+			*steps = append(*steps, TraceStep{Node: node, Bit: bit, Next: next})
+		}
+		node = next
 	}
 	if node == nodeCount {
 		// Record is empty
-		return 0, nil
+		return 0, i, nil
 	} else if node > nodeCount {
-		return node, nil
+		return node, i, nil
 	}
 
-	return 0, newInvalidDatabaseError("invalid node in search tree")
+	return 0, 0, newInvalidDatabaseError("invalid node in search tree")
+}
+
+// ReadNode returns the left (0 bit) and right (1 bit) records of a node of
+// the search tree, for custom traversals. Node 0 is the root. A record less
+// than Metadata.NodeCount is the number of the next node, a record equal to
+// it means there is no data for the network, and a greater record points to
+// the data section, see RecordOffset.
+func (r *Reader) ReadNode(nodeNumber uint) (left, right uint, err error) {
+	if r.buffer == nil {
+		return 0, 0, ErrClosed
+	}
+	if nodeNumber >= r.Metadata.NodeCount {
+		return 0, 0, fmt.Errorf("maxminddb: node %d is out of range, the search tree has %d nodes", nodeNumber, r.Metadata.NodeCount)
+	}
+	if left, err = r.readNode(nodeNumber, 0); err != nil {
+		return 0, 0, err
+	}
+	if right, err = r.readNode(nodeNumber, 1); err != nil {
+		return 0, 0, err
+	}
+	return left, right, nil
+}
+
+// RecordOffset returns the offset of the data of a record returned by
+// ReadNode, which may be passed to Decode. NotFound is returned for records
+// that do not point to the data section.
+func (r *Reader) RecordOffset(record uint) (uintptr, error) {
+	if record <= r.Metadata.NodeCount {
+		return NotFound, nil
+	}
+	return r.resolveDataPointer(record)
 }
 
 func (r *Reader) readNode(nodeNumber uint, index uint) (uint, error) {
@@ -205,27 +517,35 @@ func (r *Reader) readNode(nodeNumber uint, index uint) (uint, error) {
 	baseOffset := nodeNumber * RecordSize / 4
 
 	var nodeBytes []byte
-	var prefix uint64
 	switch RecordSize {
 	case 24:
 		offset := baseOffset + index*3
 		nodeBytes = r.buffer[offset : offset+3]
 	case 28:
-		prefix = uint64(r.buffer[baseOffset+3])
-		if index != 0 {
-			prefix &= 0x0F
-		} else {
-			prefix = (0xF0 & prefix) >> 4
-		}
+		// The middle byte holds the high nibble of the left record and the
+		// low nibble of the right one. Shifting by 4 for index 0 and by 0
+		// for index 1 avoids a hard to predict branch on the hot path.
 		offset := baseOffset + index*4
-		nodeBytes = r.buffer[offset : offset+3]
+		b := r.buffer[offset : offset+3]
+		nibble := (uint(r.buffer[baseOffset+3]) >> ((index ^ 1) << 2)) & 0x0F
+		return nibble<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2]), nil
 	case 32:
 		offset := baseOffset + index*4
 		nodeBytes = r.buffer[offset : offset+4]
 	default:
 		return 0, newInvalidDatabaseError("unknown record size: %d", RecordSize)
 	}
-	return uint(uintFromBytes(prefix, nodeBytes)), nil
+	return uint(uintFromBytes(0, nodeBytes)), nil
+}
+
+// labeled runs f with the pprof labels of operation added to those of ctx,
+// which the goroutine is set back to afterwards.
+func (r *Reader) labeled(ctx context.Context, operation string, f func() error) error {
+	var err error
+	pprof.Do(ctx, r.labels[operation], func(context.Context) {
+		err = f()
+	})
+	return err
 }
 
 func (r *Reader) retrieveData(pointer uint, result interface{}) error {
@@ -237,6 +557,9 @@ func (r *Reader) retrieveData(pointer uint, result interface{}) error {
 }
 
 func (r *Reader) resolveDataPointer(pointer uint) (uintptr, error) {
+	if r.buffer == nil {
+		return 0, ErrClosed
+	}
 	var resolved = uintptr(pointer - r.Metadata.NodeCount - dataSectionSeparatorSize)
 
 	if resolved > uintptr(len(r.buffer)) {
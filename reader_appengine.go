@@ -9,18 +9,29 @@ import "io/ioutil"
 // except on Google App Engine where mmap is not supported; there the database
 // is loaded into memory. Use the Close method on the Reader object to return
 // the resources to the system.
-func Open(file string) (*Reader, error) {
+func Open(file string, options ...ReaderOption) (*Reader, error) {
 	bytes, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
 
-	return FromBytes(bytes)
+	return FromBytes(bytes, options...)
+}
+
+// OpenInMemory is like Open but reads the database file into memory instead
+// of mapping it. On Google App Engine, this is the same as Open, and
+// hugePages is ignored.
+func OpenInMemory(file string, hugePages bool, options ...ReaderOption) (*Reader, error) {
+	return Open(file, options...)
 }
 
 // Close unmaps the database file from virtual memory and returns the
 // resources to the system. If called on a Reader opened using FromBytes
-// or Open on Google App Engine, this method does nothing.
+// or Open on Google App Engine, the buffer is only released. Afterwards,
+// methods reading the database return ErrClosed. Close must not be called
+// concurrently with other methods, and calling it again does nothing.
 func (r *Reader) Close() error {
+	r.buffer = nil
+	r.decoder.buffer = nil
 	return nil
 }
@@ -2,14 +2,17 @@
 
 package maxminddb
 
-import "os"
+import (
+	"io"
+	"os"
+)
 
 // Open takes a string path to a MaxMind DB file and returns a Reader
 // structure or an error. The database file is opened using a memory map,
 // except on Google App Engine where mmap is not supported; there the database
 // is loaded into memory. Use the Close method on the Reader object to return
 // the resources to the system.
-func Open(file string) (*Reader, error) {
+func Open(file string, options ...ReaderOption) (*Reader, error) {
 	mapFile, err := os.Open(file)
 	if err != nil {
 		return nil, err
@@ -31,7 +34,7 @@ func Open(file string) (*Reader, error) {
 		return nil, err
 	}
 
-	reader, err := FromBytes(mmap)
+	reader, err := FromBytes(mmap, options...)
 	if err != nil {
 		if err2 := munmap(mmap); err2 != nil {
 			// failing to unmap the file is probably the more severe error
@@ -44,13 +47,60 @@ func Open(file string) (*Reader, error) {
 	return reader, err
 }
 
+// OpenInMemory is like Open but reads the database file into memory instead
+// of mapping it, so that lookups never wait on storage. The memory is
+// page-aligned and, where supported, allocated with an anonymous mapping. If
+// hugePages is true, the kernel is advised to back it with huge pages, which
+// reduces TLB misses on large databases. Use the Close method on the Reader
+// object to release the memory.
+func OpenInMemory(file string, hugePages bool, options ...ReaderOption) (*Reader, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	stats, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	buffer, mapped, err := allocate(int(stats.Size()), hugePages)
+	if err != nil {
+		return nil, err
+	}
+	release := func() {
+		if mapped {
+			munmap(buffer)
+		}
+	}
+
+	if _, err := io.ReadFull(f, buffer); err != nil {
+		release()
+		return nil, err
+	}
+
+	reader, err := FromBytes(buffer, options...)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	reader.hasMappedFile = mapped
+	return reader, nil
+}
+
 // Close unmaps the database file from virtual memory and returns the
 // resources to the system. If called on a Reader opened using FromBytes
-// or Open on Google App Engine, this method does nothing.
+// or Open on Google App Engine, the buffer is only released. Afterwards,
+// methods reading the database return ErrClosed. Close must not be called
+// concurrently with other methods, and calling it again does nothing.
 func (r *Reader) Close() (err error) {
 	if r.hasMappedFile {
 		err = munmap(r.buffer)
 		r.hasMappedFile = false
 	}
+	r.buffer = nil
+	r.decoder.buffer = nil
 	return err
 }
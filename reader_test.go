@@ -1,12 +1,15 @@
 package maxminddb
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/big"
 	"math/rand"
 	"net"
+	"runtime/pprof"
 	"testing"
 	"time"
 
@@ -62,6 +65,49 @@ func (s *MySuite) TestReaderBytes(c *C) {
 	}
 }
 
+func (s *MySuite) TestOpenInMemory(c *C) {
+	for _, recordSize := range []uint{24, 28, 32} {
+		for _, ipVersion := range []uint{4, 6} {
+			fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-ipv%d-%d.mmdb", ipVersion, recordSize)
+			for _, hugePages := range []bool{false, true} {
+				reader, err := OpenInMemory(fileName, hugePages)
+				c.Assert(err, IsNil)
+
+				checkMetadata(c, reader, ipVersion, recordSize)
+
+				if ipVersion == 4 {
+					checkIpv4(c, reader)
+				} else {
+					checkIpv6(c, reader)
+				}
+				c.Assert(reader.Close(), IsNil)
+			}
+		}
+	}
+}
+
+func (s *MySuite) TestProfilerLabels(c *C) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb", WithProfilerLabels())
+	c.Assert(err, IsNil)
+
+	labels := pprof.WithLabels(context.Background(), reader.labels["scan"])
+	operation, _ := pprof.Label(labels, "operation")
+	db, _ := pprof.Label(labels, "db")
+	c.Assert(operation, Equals, "scan")
+	c.Assert(db, Equals, "Test")
+
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "1"))
+	var record map[string]string
+	c.Assert(reader.LookupContext(ctx, net.ParseIP("1.1.1.1"), &record), IsNil)
+	c.Assert(record["ip"], Equals, "1.1.1.1")
+	c.Assert(reader.VerifyReportContext(ctx).Err(), IsNil)
+
+	var out bytes.Buffer
+	c.Assert(reader.Export(ctx, &out, ExportOptions{}), IsNil)
+	c.Assert(out.Len() > 0, Equals, true)
+	c.Assert(reader.Close(), IsNil)
+}
+
 func (s *MySuite) TestDecodingToInterface(c *C) {
 	reader, err := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
 	if err != nil {
@@ -318,6 +364,109 @@ func (s *MySuite) TestNestedOffsetDecode(c *C) {
 	c.Check(db.Close(), IsNil)
 }
 
+func (s *MySuite) TestLookupString(c *C) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-mixed-24.mmdb")
+	c.Assert(err, IsNil)
+
+	for _, address := range []string{"1.1.1.3", "::1.1.1.3", "::ffff:1.1.1.3"} {
+		var result map[string]string
+		c.Assert(reader.LookupString(address, &result), IsNil)
+		c.Assert(result, DeepEquals, map[string]string{"ip": "1.1.1.2"})
+	}
+
+	var result map[string]string
+	c.Assert(reader.LookupString("::2:0:59", &result), IsNil)
+	c.Assert(result, DeepEquals, map[string]string{"ip": "::2:0:58"})
+
+	for _, address := range []string{"", "1.1.1", "1.1.1.256", "example.com"} {
+		err = reader.LookupString(address, &result)
+		c.Assert(err, DeepEquals, AddressParseError{Address: address})
+	}
+	c.Assert(reader.Close(), IsNil)
+}
+
+func (s *MySuite) TestLookupStringZonePolicy(c *C) {
+	for _, policy := range []ZonePolicy{ZoneStrip, ZoneReject, ZoneLinkLocal} {
+		reader, err := Open("test-data/test-data/MaxMind-DB-test-mixed-24.mmdb", WithZonePolicy(policy))
+		c.Assert(err, IsNil)
+
+		var result map[string]string
+		err = reader.LookupString("::2:0:59%eth0", &result)
+		switch policy {
+		case ZoneStrip:
+			c.Assert(err, IsNil)
+			c.Assert(result, DeepEquals, map[string]string{"ip": "::2:0:58"})
+		case ZoneReject:
+			c.Assert(err, DeepEquals, ZonedAddressError{Address: "::2:0:59%eth0"})
+		case ZoneLinkLocal:
+			c.Assert(err, Equals, ErrLinkLocal)
+		}
+
+		// Addresses without a zone are not affected.
+		c.Assert(reader.LookupString("::2:0:59", &result), IsNil)
+		c.Assert(result, DeepEquals, map[string]string{"ip": "::2:0:58"})
+		c.Assert(reader.Close(), IsNil)
+	}
+}
+
+func (s *MySuite) TestAnonymizeToNetwork(c *C) {
+	pairs := map[string]string{
+		"1.1.1.1":  "1.1.1.1",
+		"1.1.1.3":  "1.1.1.2",
+		"1.1.1.7":  "1.1.1.4",
+		"1.1.1.15": "1.1.1.8",
+		"1.1.1.31": "1.1.1.16",
+		"::2:0:1":  "::2:0:0",
+		"::2:0:49": "::2:0:40",
+		"::2:0:59": "::2:0:58",
+	}
+
+	for _, recordSize := range []uint{24, 28, 32} {
+		fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-mixed-%d.mmdb", recordSize)
+		reader, err := Open(fileName)
+		c.Assert(err, IsNil)
+
+		for keyAddress, valueAddress := range pairs {
+			ip, err := reader.AnonymizeToNetwork(net.ParseIP(keyAddress))
+			c.Assert(err, IsNil)
+			c.Assert(ip.String(), Equals, valueAddress)
+		}
+		c.Assert(reader.Close(), IsNil)
+	}
+}
+
+func (s *MySuite) TestLookupNetwork(c *C) {
+	networks := map[string]string{
+		"1.1.1.1":  "1.1.1.1/32",
+		"1.1.1.3":  "1.1.1.2/31",
+		"1.1.1.7":  "1.1.1.4/30",
+		"::2:0:49": "::2:0:40/124",
+	}
+
+	for _, recordSize := range []uint{24, 28, 32} {
+		fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-mixed-%d.mmdb", recordSize)
+		reader, err := Open(fileName)
+		c.Assert(err, IsNil)
+
+		for address, expected := range networks {
+			var result map[string]string
+			network, ok, err := reader.LookupNetwork(net.ParseIP(address), &result)
+			c.Assert(err, IsNil)
+			c.Assert(ok, Equals, true)
+			c.Assert(network.String(), Equals, expected)
+			c.Assert(result["ip"], Not(Equals), "")
+		}
+
+		var result map[string]string
+		network, ok, err := reader.LookupNetwork(net.ParseIP("1.1.1.33"), &result)
+		c.Assert(err, IsNil)
+		c.Assert(ok, Equals, false)
+		c.Assert(network.String(), Equals, "1.1.1.33/32")
+		c.Assert(result, IsNil)
+		c.Assert(reader.Close(), IsNil)
+	}
+}
+
 func (s *MySuite) TestDecodingUint16IntoInt(c *C) {
 	reader, err := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
 	if err != nil {
@@ -368,6 +517,9 @@ func (s *MySuite) TestBrokenDoubleDatabase(c *C) {
 	err = reader.Lookup(net.ParseIP("2001:220::"), &result)
 
 	expected := newInvalidDatabaseError("the MaxMind DB file's data section contains bad data (float 64 size of 2)")
+	expected.Offset = 20
+	expected.ControlByte = 0x62
+	expected.Kind = KindFloat64
 	c.Assert(err, DeepEquals, expected)
 	if err = reader.Close(); err != nil {
 		c.Assert(err, nil, "no error on close")
@@ -410,6 +562,76 @@ func (s *MySuite) TestDecodingToNonPointer(c *C) {
 	}
 }
 
+func (s *MySuite) TestReadNode(c *C) {
+	for _, recordSize := range []uint{24, 28, 32} {
+		fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-ipv4-%d.mmdb", recordSize)
+		reader, err := Open(fileName)
+		c.Assert(err, IsNil)
+
+		// Walk the tree by hand and compare with LookupOffset.
+		ip := net.ParseIP("1.1.1.3").To4()
+		node := uint(0)
+		for i := 0; i < 32 && node < reader.Metadata.NodeCount; i++ {
+			left, right, err := reader.ReadNode(node)
+			c.Assert(err, IsNil)
+			node = left
+			if ip[i/8]&(0x80>>uint(i%8)) != 0 {
+				node = right
+			}
+		}
+		offset, err := reader.RecordOffset(node)
+		c.Assert(err, IsNil)
+		expected, err := reader.LookupOffset(ip)
+		c.Assert(err, IsNil)
+		c.Assert(offset, Equals, expected)
+
+		offset, err = reader.RecordOffset(reader.Metadata.NodeCount)
+		c.Assert(err, IsNil)
+		c.Assert(offset, Equals, NotFound)
+
+		_, _, err = reader.ReadNode(reader.Metadata.NodeCount)
+		c.Assert(err, NotNil)
+		c.Assert(reader.Close(), IsNil)
+	}
+}
+
+func (s *MySuite) TestUseAfterClose(c *C) {
+	buffer, err := ioutil.ReadFile("test-data/test-data/GeoIP2-City-Test.mmdb")
+	c.Assert(err, IsNil)
+	mapped, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	c.Assert(err, IsNil)
+	inMemory, err := FromBytes(buffer)
+	c.Assert(err, IsNil)
+
+	for _, reader := range []*Reader{mapped, inMemory} {
+		ip := net.ParseIP("81.2.69.160")
+		offset, err := reader.LookupOffset(ip)
+		c.Assert(err, IsNil)
+
+		c.Assert(reader.Close(), IsNil)
+		c.Assert(reader.Close(), IsNil)
+
+		var result interface{}
+		c.Assert(reader.Lookup(ip, &result), Equals, ErrClosed)
+		c.Assert(reader.LookupString("81.2.69.160", &result), Equals, ErrClosed)
+		_, err = reader.LookupOffset(ip)
+		c.Assert(err, Equals, ErrClosed)
+		_, _, err = reader.LookupNetwork(ip, &result)
+		c.Assert(err, Equals, ErrClosed)
+		c.Assert(reader.Decode(offset, &result), Equals, ErrClosed)
+		_, _, err = reader.ReadNode(0)
+		c.Assert(err, Equals, ErrClosed)
+		c.Assert(reader.Verify(), Equals, ErrClosed)
+
+		_, ok := reader.CountryCode(ip)
+		c.Assert(ok, Equals, false)
+
+		networks := reader.Networks()
+		c.Assert(networks.Next(), Equals, false)
+		c.Assert(networks.Err(), Equals, ErrClosed)
+	}
+}
+
 func (s *MySuite) TestNilLookup(c *C) {
 	reader, _ := Open("test-data/test-data/MaxMind-DB-test-decoder.mmdb")
 
@@ -568,6 +790,28 @@ func BenchmarkMaxMindDB(b *testing.B) {
 	}
 }
 
+func BenchmarkReadNode(b *testing.B) {
+	for _, recordSize := range []uint{24, 28, 32} {
+		b.Run(fmt.Sprintf("%d", recordSize), func(b *testing.B) {
+			fileName := fmt.Sprintf("test-data/test-data/MaxMind-DB-test-ipv6-%d.mmdb", recordSize)
+			db, err := Open(fileName)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+
+			nodeCount := db.Metadata.NodeCount
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				node := uint(i) % nodeCount
+				if _, err := db.readNode(node, uint(i)&1); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkCountryCode(b *testing.B) {
 	db, err := Open("GeoLite2-City.mmdb")
 	if err != nil {
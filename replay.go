@@ -0,0 +1,277 @@
+package maxminddb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LookupEntry describes a lookup recorded by a LookupRecorder.
+type LookupEntry struct {
+	Time time.Time
+	IP   net.IP
+	// Network is the network of the search tree containing IP.
+	Network *net.IPNet
+	// RecordHash identifies the content of the record found, independently
+	// of where it is stored in the database. It is 0 if there is no record.
+	RecordHash uint64
+}
+
+// String formats the entry as a line of a lookup log: the time in RFC 3339
+// format, the IP address, the network and the record hash in hexadecimal,
+// separated by tabs.
+func (e LookupEntry) String() string {
+	return fmt.Sprintf(
+		"%s\t%s\t%s\t%016x",
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.IP,
+		e.Network,
+		e.RecordHash,
+	)
+}
+
+// ParseLookupEntry parses a line formatted by LookupEntry.String.
+func ParseLookupEntry(line string) (LookupEntry, error) {
+	fields := strings.Split(line, "\t")
+	if len(fields) != 4 {
+		return LookupEntry{}, fmt.Errorf("maxminddb: invalid lookup log line %q", line)
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return LookupEntry{}, err
+	}
+	ip := net.ParseIP(fields[1])
+	if ip == nil {
+		return LookupEntry{}, AddressParseError{Address: fields[1]}
+	}
+	_, network, err := net.ParseCIDR(fields[2])
+	if err != nil {
+		return LookupEntry{}, err
+	}
+	recordHash, err := strconv.ParseUint(fields[3], 16, 64)
+	if err != nil {
+		return LookupEntry{}, err
+	}
+	return LookupEntry{
+		Time:       timestamp,
+		IP:         ip,
+		Network:    network,
+		RecordHash: recordHash,
+	}, nil
+}
+
+// LookupRecorder wraps a Reader and records every lookup made through it.
+// Records are shared by many networks, so the recorder keeps the hash of
+// each record it finds by offset, and decodes a record a second time to
+// compute its hash only when first found. It is safe for concurrent use.
+type LookupRecorder struct {
+	reader *Reader
+	record func(LookupEntry) error
+	hashes sync.Map
+}
+
+// NewLookupRecorder returns a LookupRecorder writing lookups to w, one
+// line per lookup.
+func NewLookupRecorder(reader *Reader, w io.Writer) *LookupRecorder {
+	var mu sync.Mutex
+	return &LookupRecorder{
+		reader: reader,
+		record: func(entry LookupEntry) error {
+			mu.Lock()
+			defer mu.Unlock()
+			_, err := io.WriteString(w, entry.String()+"\n")
+			return err
+		},
+	}
+}
+
+// NewLookupRecorderChan returns a LookupRecorder sending lookups to
+// entries. Lookups block until their entry is received.
+func NewLookupRecorderChan(reader *Reader, entries chan<- LookupEntry) *LookupRecorder {
+	return &LookupRecorder{
+		reader: reader,
+		record: func(entry LookupEntry) error {
+			entries <- entry
+			return nil
+		},
+	}
+}
+
+// Lookup looks up ipAddress like Reader.Lookup and records it. An error is
+// returned if the lookup fails or if it cannot be recorded.
+func (l *LookupRecorder) Lookup(ipAddress net.IP, result interface{}) error {
+	entry, offset, err := lookupEntry(l.reader, ipAddress, &l.hashes)
+	if err != nil {
+		return err
+	}
+	if offset != NotFound {
+		if err := l.reader.Decode(offset, result); err != nil {
+			return err
+		}
+	}
+	return l.record(entry)
+}
+
+// lookupEntry looks up ipAddress and returns its entry and the offset of its
+// record. hashes caches the RecordHash of records by offset.
+func lookupEntry(reader *Reader, ipAddress net.IP, hashes *sync.Map) (LookupEntry, uintptr, error) {
+	pointer, network, err := reader.lookupNetwork(ipAddress)
+	if err != nil {
+		return LookupEntry{}, NotFound, err
+	}
+
+	entry := LookupEntry{
+		Time: time.Now(),
+		// Callers may reuse the buffer of ipAddress.
+		IP:      append(net.IP(nil), ipAddress...),
+		Network: network,
+	}
+	if pointer == 0 {
+		return entry, NotFound, nil
+	}
+
+	offset, err := reader.resolveDataPointer(pointer)
+	if err != nil {
+		return LookupEntry{}, NotFound, err
+	}
+	if recordHash, ok := hashes.Load(offset); ok {
+		entry.RecordHash = recordHash.(uint64)
+		return entry, offset, nil
+	}
+	var record interface{}
+	if err := reader.Decode(offset, &record); err != nil {
+		return LookupEntry{}, NotFound, err
+	}
+	entry.RecordHash = RecordHash(record)
+	hashes.Store(offset, entry.RecordHash)
+	return entry, offset, nil
+}
+
+// RecordHash returns a hash of a record decoded into an interface{} value.
+// Equal records have the same hash, whatever database they come from.
+func RecordHash(record interface{}) uint64 {
+	h := fnv.New64a()
+	hashValue(h, record)
+	sum := h.Sum64()
+	if sum == 0 {
+		// 0 is reserved for lookups without a record.
+		sum = 1
+	}
+	return sum
+}
+
+func hashValue(h hash.Hash64, value interface{}) {
+	var buf [9]byte
+	writeUint := func(kind Kind, v uint64) {
+		buf[0] = byte(kind)
+		binary.BigEndian.PutUint64(buf[1:], v)
+		h.Write(buf[:])
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeUint(KindMap, uint64(len(v)))
+		for _, key := range keys {
+			hashValue(h, key)
+			hashValue(h, v[key])
+		}
+	case []interface{}:
+		writeUint(KindSlice, uint64(len(v)))
+		for _, element := range v {
+			hashValue(h, element)
+		}
+	case string:
+		writeUint(KindString, uint64(len(v)))
+		io.WriteString(h, v)
+	case []byte:
+		writeUint(KindBytes, uint64(len(v)))
+		h.Write(v)
+	case bool:
+		if v {
+			writeUint(KindBool, 1)
+		} else {
+			writeUint(KindBool, 0)
+		}
+	case uint64:
+		writeUint(KindUint64, v)
+	case int:
+		writeUint(KindInt32, uint64(v))
+	case float64:
+		writeUint(KindFloat64, math.Float64bits(v))
+	case float32:
+		writeUint(KindFloat32, uint64(math.Float32bits(v)))
+	case *big.Int:
+		b := v.Bytes()
+		writeUint(KindUint128, uint64(len(b)))
+		h.Write(b)
+	default:
+		writeUint(KindExtended, 0)
+		io.WriteString(h, fmt.Sprint(v))
+	}
+}
+
+// ReplayStats summarizes the replay of a lookup log.
+type ReplayStats struct {
+	Lookups           int // lookups replayed
+	NetworkMismatches int // lookups that matched a different network
+	RecordMismatches  int // lookups that found a different record
+}
+
+// Replay repeats the lookups of a log written by a LookupRecorder against
+// reader and compares the results. If mismatch is not nil, it is called for
+// each lookup whose network or record differs.
+func Replay(
+	reader *Reader,
+	log io.Reader,
+	mismatch func(recorded, replayed LookupEntry),
+) (ReplayStats, error) {
+	var stats ReplayStats
+	var hashes sync.Map
+
+	scanner := bufio.NewScanner(log)
+	for scanner.Scan() {
+		if scanner.Text() == "" {
+			continue
+		}
+		recorded, err := ParseLookupEntry(scanner.Text())
+		if err != nil {
+			return stats, err
+		}
+
+		replayed, _, err := lookupEntry(reader, recorded.IP, &hashes)
+		if err != nil {
+			return stats, err
+		}
+
+		stats.Lookups++
+		networkMismatch := replayed.Network.String() != recorded.Network.String()
+		recordMismatch := replayed.RecordHash != recorded.RecordHash
+		if networkMismatch {
+			stats.NetworkMismatches++
+		}
+		if recordMismatch {
+			stats.RecordMismatches++
+		}
+		if (networkMismatch || recordMismatch) && mismatch != nil {
+			mismatch(recorded, replayed)
+		}
+	}
+	return stats, scanner.Err()
+}
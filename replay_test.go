@@ -0,0 +1,121 @@
+package maxminddb
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestLookupRecorderAndReplay(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var log bytes.Buffer
+	recorder := NewLookupRecorder(reader, &log)
+	for _, address := range []string{"1.1.1.1", "1.1.1.3", "1.1.1.17", "255.254.253.123"} {
+		var result map[string]string
+		if err := recorder.Lookup(net.ParseIP(address), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries := bytes.Split(bytes.TrimSpace(log.Bytes()), []byte("\n"))
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 log lines, got %q", log.String())
+	}
+	entry, err := ParseLookupEntry(string(entries[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Network.String() != "1.1.1.2/31" || entry.RecordHash == 0 {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+	entry, err = ParseLookupEntry(string(entries[3]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.RecordHash != 0 {
+		t.Errorf("expected no record hash, got %v", entry)
+	}
+
+	// The same data in a database with another layout replays cleanly.
+	other, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-32.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer other.Close()
+
+	stats, err := Replay(other, bytes.NewReader(log.Bytes()), func(recorded, replayed LookupEntry) {
+		t.Errorf("unexpected mismatch: %v != %v", recorded, replayed)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (ReplayStats{Lookups: 4}) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLookupRecorderChan(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	entries := make(chan LookupEntry, 1)
+	recorder := NewLookupRecorderChan(reader, entries)
+
+	var result map[string]string
+	if err := recorder.Lookup(net.ParseIP("1.1.1.5"), &result); err != nil {
+		t.Fatal(err)
+	}
+	entry := <-entries
+	if entry.Network.String() != "1.1.1.4/30" || result["ip"] != "1.1.1.4" {
+		t.Errorf("unexpected entry %v and result %v", entry, result)
+	}
+}
+
+func TestLookupRecorderReusedBuffer(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	entries := make(chan LookupEntry, 2)
+	recorder := NewLookupRecorderChan(reader, entries)
+
+	ip := net.ParseIP("1.1.1.1").To4()
+	var result map[string]string
+	if err := recorder.Lookup(ip, &result); err != nil {
+		t.Fatal(err)
+	}
+	copy(ip, net.ParseIP("1.1.1.3").To4())
+	if err := recorder.Lookup(ip, &result); err != nil {
+		t.Fatal(err)
+	}
+	first, second := <-entries, <-entries
+	if first.IP.String() != "1.1.1.1" || second.IP.String() != "1.1.1.3" {
+		t.Errorf("expected the recorded addresses to be kept, got %v and %v", first.IP, second.IP)
+	}
+	if first.RecordHash == second.RecordHash {
+		t.Errorf("expected different records, got the hash %x twice", first.RecordHash)
+	}
+}
+
+func TestRecordHash(t *testing.T) {
+	a := map[string]interface{}{"a": uint64(1), "b": []interface{}{"x", true}}
+	b := map[string]interface{}{"b": []interface{}{"x", true}, "a": uint64(1)}
+	c := map[string]interface{}{"a": uint64(2), "b": []interface{}{"x", true}}
+
+	if RecordHash(a) != RecordHash(b) {
+		t.Error("expected equal records to have the same hash")
+	}
+	if RecordHash(a) == RecordHash(c) {
+		t.Error("expected different records to have different hashes")
+	}
+}
@@ -0,0 +1,129 @@
+package maxminddb
+
+import (
+	"math"
+	"net"
+)
+
+// LookupResult is the record found by Reader.LookupResult. It holds the
+// offset of the record rather than its decoded value, so the record is only
+// decoded, in full or in part, when needed. It is only valid until its
+// Reader is closed.
+type LookupResult struct {
+	reader  *Reader
+	offset  uintptr
+	network *net.IPNet
+	found   bool
+}
+
+// LookupResult looks up ipAddress without decoding its record. This is
+// useful when the record is often not needed, e.g., because it is already
+// cached by offset or by network, or when only a few of its values are.
+func (r *Reader) LookupResult(ipAddress net.IP) (LookupResult, error) {
+	pointer, network, err := r.lookupNetwork(ipAddress)
+	if err != nil {
+		return LookupResult{}, err
+	}
+	result := LookupResult{reader: r, network: network}
+	if pointer == 0 {
+		return result, nil
+	}
+	if result.offset, err = r.resolveDataPointer(pointer); err != nil {
+		return LookupResult{}, err
+	}
+	result.found = true
+	return result, nil
+}
+
+// Found reports whether there is a record for the address.
+func (r LookupResult) Found() bool {
+	return r.found
+}
+
+// Network returns the network of the search tree containing the address,
+// as LookupNetwork does.
+func (r LookupResult) Network() *net.IPNet {
+	return r.network
+}
+
+// Offset returns the offset of the record, which may be passed to
+// Reader.Decode, or 0 if there is none.
+func (r LookupResult) Offset() uintptr {
+	return r.offset
+}
+
+// Decode decodes the record into result, as Reader.Decode does. If there is
+// no record, result is left unchanged.
+func (r LookupResult) Decode(result interface{}) error {
+	if !r.found {
+		return nil
+	}
+	return r.reader.Decode(r.offset, result)
+}
+
+// DecodePath decodes the value at path in the record into result, as
+// Reader.DecodePath does. If there is no record, result is left unchanged.
+func (r LookupResult) DecodePath(result interface{}, path ...interface{}) error {
+	if !r.found {
+		return nil
+	}
+	return r.reader.DecodePath(r.offset, result, path...)
+}
+
+// GetString returns the string at path in the record, read directly from
+// the database. The path is given as for DecodePath. The bool is false if
+// there is no record, no value at path, if the DecodeProfile of the Reader
+// omits it, or if the value has another type.
+func (r LookupResult) GetString(path ...interface{}) (string, bool) {
+	offset, ok := r.valueOffset(path)
+	if !ok {
+		return "", false
+	}
+	return r.reader.decoder.stringAt(offset)
+}
+
+// GetUint64 is like GetString for unsigned integers of up to 64 bits.
+func (r LookupResult) GetUint64(path ...interface{}) (uint64, bool) {
+	offset, ok := r.valueOffset(path)
+	if !ok {
+		return 0, false
+	}
+	return r.reader.decoder.uintAt(offset)
+}
+
+// GetUint32 is like GetUint64, and is false for values that do not fit in
+// a uint32.
+func (r LookupResult) GetUint32(path ...interface{}) (uint32, bool) {
+	value, ok := r.GetUint64(path...)
+	if !ok || value > math.MaxUint32 {
+		return 0, false
+	}
+	return uint32(value), true
+}
+
+// GetFloat64 is like GetString for floating point numbers.
+func (r LookupResult) GetFloat64(path ...interface{}) (float64, bool) {
+	offset, ok := r.valueOffset(path)
+	if !ok {
+		return 0, false
+	}
+	return r.reader.decoder.float64At(offset)
+}
+
+// GetBool is like GetString for booleans.
+func (r LookupResult) GetBool(path ...interface{}) (bool, bool) {
+	offset, ok := r.valueOffset(path)
+	if !ok {
+		return false, false
+	}
+	return r.reader.decoder.boolAt(offset)
+}
+
+func (r LookupResult) valueOffset(path []interface{}) (uint, bool) {
+	if !r.found {
+		return 0, false
+	}
+	d := r.reader.decoder
+	offset, ok, err := d.findPath(uint(r.offset), path...)
+	return offset, ok && err == nil
+}
@@ -0,0 +1,125 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestLookupResult(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	result, err := reader.LookupResult(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	offset, err := reader.LookupOffset(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Found() || result.Offset() != offset || result.Network().String() != "81.2.69.160/27" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	var city City
+	if err := result.Decode(&city); err != nil || city.Country.IsoCode != "GB" {
+		t.Errorf("unexpected record: %+v (%v)", city, err)
+	}
+	var timeZone string
+	if err := result.DecodePath(&timeZone, "location", "time_zone"); err != nil || timeZone != "Europe/London" {
+		t.Errorf("expected Europe/London, got %q (%v)", timeZone, err)
+	}
+
+	result, err = reader.LookupResult(net.ParseIP("10.0.0.1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	city = City{}
+	if result.Found() || result.Offset() != 0 || result.Network() == nil {
+		t.Errorf("unexpected result without a record: %+v", result)
+	}
+	if err := result.Decode(&city); err != nil || city.Country.IsoCode != "" {
+		t.Errorf("expected an empty record, got %+v (%v)", city, err)
+	}
+
+	if _, err := reader.LookupResult(nil); err == nil {
+		t.Error("expected an error for a nil address")
+	}
+}
+
+func TestLookupResultGetters(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	result, err := reader.LookupResult(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, ok := result.GetString("subdivisions", 0, "iso_code"); code != "ENG" || !ok {
+		t.Errorf("expected ENG, got %q, %v", code, ok)
+	}
+	if id, ok := result.GetUint32("country", "geoname_id"); id != 2635167 || !ok {
+		t.Errorf("expected 2635167, got %d, %v", id, ok)
+	}
+	if radius, ok := result.GetUint64("location", "accuracy_radius"); radius != 100 || !ok {
+		t.Errorf("expected 100, got %d, %v", radius, ok)
+	}
+	if latitude, ok := result.GetFloat64("location", "latitude"); latitude != 51.5142 || !ok {
+		t.Errorf("expected 51.5142, got %v, %v", latitude, ok)
+	}
+	if _, ok := result.GetString("location", "latitude"); ok {
+		t.Error("expected no string for a float")
+	}
+	if _, ok := result.GetBool("traits", "is_anycast"); ok {
+		t.Error("expected no value for a missing path")
+	}
+	if _, ok := result.GetString("country", 0); ok {
+		t.Error("expected no value for an invalid path")
+	}
+
+	reader, err = Open("test-data/test-data/GeoIP2-Anonymous-IP-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+	if result, err = reader.LookupResult(net.ParseIP("81.2.69.1")); err != nil {
+		t.Fatal(err)
+	}
+	if anonymous, ok := result.GetBool("is_anonymous"); !anonymous || !ok {
+		t.Errorf("expected true, got %v, %v", anonymous, ok)
+	}
+}
+
+func TestLookupResultGettersWithProfile(t *testing.T) {
+	reader, err := Open(
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+		WithDecodeProfile(PreciseLocationProfile),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	result, err := reader.LookupResult(net.ParseIP("81.2.69.160"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latitude, ok := result.GetFloat64("location", "latitude"); ok {
+		t.Errorf("expected the latitude to be omitted, got %v", latitude)
+	}
+	if longitude, ok := result.GetFloat64("location", "longitude"); ok {
+		t.Errorf("expected the longitude to be omitted, got %v", longitude)
+	}
+	if code, ok := result.GetString("postal", "code"); ok {
+		t.Errorf("expected the postal code to be omitted, got %q", code)
+	}
+	if radius, ok := result.GetUint64("location", "accuracy_radius"); radius != 100 || !ok {
+		t.Errorf("expected 100, got %d, %v", radius, ok)
+	}
+}
@@ -0,0 +1,74 @@
+package maxminddb
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// selfTestProbe is a lookup with a known answer. The addresses are those of
+// the MaxMind test databases, which also hold in the production databases:
+// 81.2.69.160 is in the United Kingdom, 2001:218::1 in Japan, and 1.128.0.1
+// is announced by Telstra, AS1221.
+type selfTestProbe struct {
+	address string
+	ipv6    bool
+	path    []string
+	want    interface{}
+}
+
+var (
+	countryProbes = []selfTestProbe{
+		{address: "81.2.69.160", path: []string{"country", "iso_code"}, want: "GB"},
+		{address: "2001:218::1", ipv6: true, path: []string{"country", "iso_code"}, want: "JP"},
+	}
+	asnProbes = []selfTestProbe{
+		{address: "1.128.0.1", path: []string{"autonomous_system_number"}, want: uint64(1221)},
+	}
+)
+
+// SelfTest makes a few lookups with known answers and checks the results,
+// so that deployment health checks can assert that geolocation works end to
+// end. The lookups depend on the DatabaseType of the metadata: City,
+// Country and Enterprise databases must place 81.2.69.160 in GB and, if
+// they support IPv6, 2001:218::1 in JP. ASN and ISP databases must map
+// 1.128.0.1 to AS1221. For other databases, SelfTest only checks that the
+// record of 81.2.69.160, if any, can be decoded.
+func (r *Reader) SelfTest() error {
+	databaseType := r.Metadata.DatabaseType
+	var probes []selfTestProbe
+	switch {
+	case strings.Contains(databaseType, "City"),
+		strings.Contains(databaseType, "Country"),
+		strings.Contains(databaseType, "Enterprise"):
+		probes = countryProbes
+	case strings.Contains(databaseType, "ASN"), strings.Contains(databaseType, "ISP"):
+		probes = asnProbes
+	default:
+		var record interface{}
+		if err := r.Lookup(net.ParseIP("81.2.69.160"), &record); err != nil {
+			return fmt.Errorf("maxminddb: self-test lookup of 81.2.69.160: %w", err)
+		}
+		return nil
+	}
+
+	for _, probe := range probes {
+		if probe.ipv6 && r.Metadata.IPVersion != 6 {
+			continue
+		}
+		var record interface{}
+		if err := r.Lookup(net.ParseIP(probe.address), &record); err != nil {
+			return fmt.Errorf("maxminddb: self-test lookup of %s: %w", probe.address, err)
+		}
+		value := record
+		for _, key := range probe.path {
+			m, _ := value.(map[string]interface{})
+			value = m[key]
+		}
+		if value != probe.want {
+			return fmt.Errorf("maxminddb: self-test lookup of %s: expected %s %v, got %v",
+				probe.address, strings.Join(probe.path, "."), probe.want, value)
+		}
+	}
+	return nil
+}
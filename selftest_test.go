@@ -0,0 +1,37 @@
+package maxminddb
+
+import "testing"
+
+func TestSelfTest(t *testing.T) {
+	for _, file := range []string{
+		"GeoIP2-City-Test.mmdb",
+		"GeoIP2-Country-Test.mmdb",
+		"GeoIP2-ISP-Test.mmdb",
+		"GeoLite2-ASN-Test.mmdb",
+		"GeoIP2-Domain-Test.mmdb",
+		"MaxMind-DB-test-ipv4-24.mmdb",
+	} {
+		reader, err := Open("test-data/test-data/" + file)
+		if err != nil {
+			t.Fatalf("unexpected error while opening database: %v", err)
+		}
+		if err := reader.SelfTest(); err != nil {
+			t.Errorf("unexpected self-test failure for %s: %v", file, err)
+		}
+		reader.Close()
+	}
+}
+
+func TestSelfTestFailure(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	reader.Metadata.DatabaseType = "GeoIP2-City"
+	err = reader.SelfTest()
+	if err == nil || err.Error() != "maxminddb: self-test lookup of 81.2.69.160: expected country.iso_code GB, got <nil>" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
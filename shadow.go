@@ -0,0 +1,174 @@
+package maxminddb
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// ShadowMismatch is an example of a lookup whose result differs between the
+// live and the candidate database.
+type ShadowMismatch struct {
+	IP    net.IP
+	Diffs []RecordDiff // differences from the live to the candidate record
+	Err   error        // the error of the comparison, if a lookup failed
+}
+
+// ShadowStats holds the counters of a ShadowReader.
+type ShadowStats struct {
+	Lookups    uint64 // lookups made on the live database
+	Compared   uint64 // lookups also made on the candidate database
+	Mismatches uint64 // compared lookups with differing results
+	Dropped    uint64 // sampled lookups not compared, too many comparisons were running
+	Examples   []ShadowMismatch
+}
+
+// maxShadowComparisons bounds the comparisons a ShadowReader runs at a time,
+// so that a slow candidate database cannot pile up goroutines.
+const maxShadowComparisons = 64
+
+// ShadowReader serves lookups from a live database and sends a sample of
+// them to a candidate database as well, recording how the results differ.
+// This allows validating a new database against production traffic before
+// switching to it. Comparisons run in the background, so the candidate
+// database does not slow lookups down. It is safe for concurrent use.
+type ShadowReader struct {
+	// The counters of stats are updated atomically, so that lookups do
+	// not contend on mu. They come first to be 64-bit aligned.
+	stats ShadowStats
+
+	live        *Reader
+	candidate   *Reader
+	sampleRate  float64
+	maxExamples int
+
+	// slots holds a value for each comparison in progress.
+	slots chan struct{}
+	wg    sync.WaitGroup
+
+	// mu guards the examples of stats and stopped.
+	mu      sync.Mutex
+	stopped bool
+}
+
+// NewShadowReader returns a ShadowReader comparing the given fraction of
+// lookups, between 0 and 1, and keeping up to maxExamples mismatches.
+func NewShadowReader(live, candidate *Reader, sampleRate float64, maxExamples int) *ShadowReader {
+	return &ShadowReader{
+		live:        live,
+		candidate:   candidate,
+		sampleRate:  sampleRate,
+		maxExamples: maxExamples,
+		slots:       make(chan struct{}, maxShadowComparisons),
+	}
+}
+
+// Lookup looks up ipAddress in the live database, like Reader.Lookup. The
+// candidate database never affects the result or the returned error.
+func (s *ShadowReader) Lookup(ipAddress net.IP, result interface{}) error {
+	atomic.AddUint64(&s.stats.Lookups, 1)
+	if s.sampleRate <= 0 || rand.Float64() >= s.sampleRate {
+		return s.live.Lookup(ipAddress, result)
+	}
+
+	offset, err := s.live.LookupOffset(ipAddress)
+	if err != nil {
+		return err
+	}
+	if offset != NotFound {
+		if err := s.live.Decode(offset, result); err != nil {
+			return err
+		}
+	}
+	s.compare(append(net.IP(nil), ipAddress...), offset)
+	return nil
+}
+
+// compare starts comparing the record at offset of the live database with
+// the record of ip in the candidate database, unless too many comparisons
+// are in progress or the ShadowReader is stopped.
+func (s *ShadowReader) compare(ip net.IP, offset uintptr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case s.slots <- struct{}{}:
+	default:
+		atomic.AddUint64(&s.stats.Dropped, 1)
+		return
+	}
+	s.wg.Add(1)
+
+	go func() {
+		defer s.wg.Done()
+		defer func() { <-s.slots }()
+
+		var liveRecord, candidateRecord interface{}
+		var liveErr error
+		if offset != NotFound {
+			liveErr = s.live.Decode(offset, &liveRecord)
+		}
+		candidateErr := s.candidate.Lookup(ip, &candidateRecord)
+
+		var diffs []RecordDiff
+		if liveErr == nil && candidateErr == nil {
+			diffs = DiffRecords(liveRecord, candidateRecord)
+		}
+
+		atomic.AddUint64(&s.stats.Compared, 1)
+		if liveErr != nil || candidateErr != nil || len(diffs) != 0 {
+			atomic.AddUint64(&s.stats.Mismatches, 1)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if len(s.stats.Examples) < s.maxExamples {
+				err := candidateErr
+				if liveErr != nil {
+					err = liveErr
+				}
+				s.stats.Examples = append(s.stats.Examples, ShadowMismatch{
+					IP:    ip,
+					Diffs: diffs,
+					Err:   err,
+				})
+			}
+		}
+	}()
+}
+
+// Stop stops comparing lookups and waits for the comparisons in progress,
+// which read both databases. It must be called before closing either of
+// them. Lookups on the live database still work afterwards.
+func (s *ShadowReader) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Stats returns a copy of the current counters and examples.
+func (s *ShadowReader) Stats() ShadowStats {
+	stats := ShadowStats{
+		Lookups:    atomic.LoadUint64(&s.stats.Lookups),
+		Compared:   atomic.LoadUint64(&s.stats.Compared),
+		Mismatches: atomic.LoadUint64(&s.stats.Mismatches),
+		Dropped:    atomic.LoadUint64(&s.stats.Dropped),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats.Examples = append([]ShadowMismatch(nil), s.stats.Examples...)
+	return stats
+}
+
+// ResetStats clears the counters and examples.
+func (s *ShadowReader) ResetStats() {
+	atomic.StoreUint64(&s.stats.Lookups, 0)
+	atomic.StoreUint64(&s.stats.Compared, 0)
+	atomic.StoreUint64(&s.stats.Mismatches, 0)
+	atomic.StoreUint64(&s.stats.Dropped, 0)
+	s.mu.Lock()
+	s.stats.Examples = nil
+	s.mu.Unlock()
+}
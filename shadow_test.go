@@ -0,0 +1,83 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestShadowReader(t *testing.T) {
+	live, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer live.Close()
+
+	candidate, err := Open("test-data/test-data/MaxMind-DB-test-mixed-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer candidate.Close()
+
+	shadow := NewShadowReader(live, candidate, 1, 1)
+	for _, address := range []string{"1.1.1.1", "1.1.1.3", "1.1.1.33"} {
+		var result map[string]string
+		if err := shadow.Lookup(net.ParseIP(address), &result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	shadow.Stop()
+
+	stats := shadow.Stats()
+	if stats.Lookups != 3 || stats.Compared != 3 || stats.Mismatches != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// The candidate is IPv4 only, so IPv6 lookups fail on it.
+	shadow = NewShadowReader(candidate, live, 1, 1)
+	for _, address := range []string{"::2:0:0", "::2:0:40"} {
+		var result map[string]string
+		ip := net.ParseIP(address)
+		if err := shadow.Lookup(ip, &result); err != nil {
+			t.Fatal(err)
+		}
+		if result["ip"] != address {
+			t.Errorf("unexpected live result for %s: %v", address, result)
+		}
+		// The comparison must not see changes to the caller's address.
+		for i := range ip {
+			ip[i] = 0
+		}
+	}
+	shadow.Stop()
+
+	stats = shadow.Stats()
+	if stats.Compared != 2 || stats.Mismatches != 2 || len(stats.Examples) != 1 || stats.Examples[0].Err == nil {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if ip := stats.Examples[0].IP.String(); ip != "::2:0:0" && ip != "::2:0:40" {
+		t.Errorf("unexpected example address %s", ip)
+	}
+
+	// Lookups are still counted, but not compared, once stopped, and
+	// failed lookups are counted too.
+	var result map[string]string
+	if err := shadow.Lookup(net.ParseIP("1.1.1.1"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if stats := shadow.Stats(); stats.Lookups != 3 || stats.Compared != 2 {
+		t.Errorf("unexpected stats after stopping: %+v", stats)
+	}
+	failing := NewShadowReader(live, candidate, 1, 1)
+	if err := failing.Lookup(net.ParseIP("::1"), &result); err == nil {
+		t.Error("expected an error looking up an IPv6 address in an IPv4 database")
+	}
+	failing.Stop()
+	if stats := failing.Stats(); stats.Lookups != 1 || stats.Compared != 0 {
+		t.Errorf("unexpected stats after a failed lookup: %+v", stats)
+	}
+
+	shadow.ResetStats()
+	if stats := shadow.Stats(); stats.Lookups != 0 || len(stats.Examples) != 0 {
+		t.Errorf("unexpected stats after reset: %+v", stats)
+	}
+}
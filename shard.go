@@ -0,0 +1,43 @@
+package maxminddb
+
+import (
+	"hash/fnv"
+	"net"
+)
+
+// ShardForNetwork maps a network, such as one returned by Networks, to a
+// shard in the range [0, numShards). The shard only depends on the network
+// address and prefix length, not on where its record happens to be stored,
+// so a network keeps its shard across database builds. Changing numShards
+// moves only about 1/numShards of the networks to a different shard.
+//
+// If numShards is less than 2, ShardForNetwork returns 0.
+func ShardForNetwork(network *net.IPNet, numShards int) int {
+	if numShards < 2 || network == nil {
+		return 0
+	}
+
+	ip := network.IP
+	if ipV4Address := ip.To4(); ipV4Address != nil && len(network.Mask) == net.IPv4len {
+		ip = ipV4Address
+	}
+	ones, _ := network.Mask.Size()
+
+	h := fnv.New64a()
+	h.Write(ip.Mask(network.Mask))
+	h.Write([]byte{byte(ones)})
+
+	return jumpHash(h.Sum64(), numShards)
+}
+
+// jumpHash is the consistent hash described in "A Fast, Minimal Memory,
+// Consistent Hash Algorithm" by Lamping and Veach.
+func jumpHash(key uint64, numBuckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(numBuckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
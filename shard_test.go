@@ -0,0 +1,48 @@
+package maxminddb
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestShardForNetwork(t *testing.T) {
+	var networks []*net.IPNet
+	for i := 0; i < 1000; i++ {
+		_, network, err := net.ParseCIDR(fmt.Sprintf("10.%d.%d.0/24", i/256, i%256))
+		if err != nil {
+			t.Fatal(err)
+		}
+		networks = append(networks, network)
+	}
+
+	moved := 0
+	for _, network := range networks {
+		shard := ShardForNetwork(network, 10)
+		if shard < 0 || shard >= 10 {
+			t.Fatalf("shard %d for %s is out of range", shard, network)
+		}
+		if again := ShardForNetwork(network, 10); again != shard {
+			t.Fatalf("shard for %s changed from %d to %d", network, shard, again)
+		}
+		if ShardForNetwork(network, 11) != shard {
+			moved++
+		}
+	}
+	// About 1/11 of the networks should move when adding a shard.
+	if moved == 0 || moved > 200 {
+		t.Errorf("%d of %d networks moved when adding a shard", moved, len(networks))
+	}
+}
+
+func TestShardForNetworkIgnoresHostBits(t *testing.T) {
+	network := &net.IPNet{IP: net.ParseIP("1.1.1.1"), Mask: net.CIDRMask(24, 32)}
+	_, canonical, _ := net.ParseCIDR("1.1.1.0/24")
+
+	if ShardForNetwork(network, 64) != ShardForNetwork(canonical, 64) {
+		t.Error("host bits changed the shard of the network")
+	}
+	if ShardForNetwork(canonical, 1) != 0 || ShardForNetwork(nil, 64) != 0 {
+		t.Error("expected shard 0 for a single shard or a nil network")
+	}
+}
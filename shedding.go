@@ -0,0 +1,46 @@
+package maxminddb
+
+import (
+	"errors"
+	"net"
+)
+
+// ErrOverloaded is returned by SheddingReader when the maximum number of
+// concurrent lookups is reached.
+var ErrOverloaded = errors.New("maxminddb: too many concurrent lookups")
+
+// SheddingReader wraps a Reader for latency-critical request paths. It
+// fails lookups immediately with ErrOverloaded rather than queueing them
+// when too many are in progress. Lookups run on the caller's goroutine, so
+// none outlives its call. It is safe for concurrent use.
+type SheddingReader struct {
+	reader *Reader
+	slots  chan struct{}
+}
+
+// NewSheddingReader returns a SheddingReader allowing up to maxConcurrent
+// lookups at a time. A maxConcurrent of 0 disables the limit.
+func NewSheddingReader(reader *Reader, maxConcurrent int) *SheddingReader {
+	s := &SheddingReader{reader: reader}
+	if maxConcurrent > 0 {
+		s.slots = make(chan struct{}, maxConcurrent)
+	}
+	return s
+}
+
+// Lookup looks up ipAddress like Reader.Lookup.
+func (s *SheddingReader) Lookup(ipAddress net.IP, result interface{}) error {
+	if s.slots != nil {
+		select {
+		case s.slots <- struct{}{}:
+		default:
+			return ErrOverloaded
+		}
+		defer s.release()
+	}
+	return s.reader.Lookup(ipAddress, result)
+}
+
+func (s *SheddingReader) release() {
+	<-s.slots
+}
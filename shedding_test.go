@@ -0,0 +1,43 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestSheddingReader(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	shedding := NewSheddingReader(reader, 1)
+
+	var result map[string]string
+	if err := shedding.Lookup(net.ParseIP("1.1.1.1"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["ip"] != "1.1.1.1" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	// Occupy the only slot.
+	shedding.slots <- struct{}{}
+	if err := shedding.Lookup(net.ParseIP("1.1.1.1"), &result); err != ErrOverloaded {
+		t.Errorf("expected ErrOverloaded, got %v", err)
+	}
+	shedding.release()
+
+	if err := shedding.Lookup(net.ParseIP("1.1.1.2"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["ip"] != "1.1.1.2" {
+		t.Errorf("unexpected result: %v", result)
+	}
+
+	unlimited := NewSheddingReader(reader, 0)
+	if err := unlimited.Lookup(net.ParseIP("1.1.1.1"), &result); err != nil || result["ip"] != "1.1.1.1" {
+		t.Errorf("unexpected result without a limit: %v (%v)", result, err)
+	}
+}
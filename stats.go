@@ -0,0 +1,79 @@
+package maxminddb
+
+import (
+	"strings"
+	"sync"
+)
+
+// DecodeStats records mismatches between the records decoded by a Reader
+// and the structs they are decoded into: keys present in a record that no
+// struct field maps to, and struct fields whose key is absent from the
+// record. Watching these counters surfaces changes to the schema of a
+// database that would otherwise silently leave data out. Use
+// WithDecodeStats to enable collection. A DecodeStats may be shared by
+// several Readers and is safe for concurrent use.
+//
+// Keys and fields are identified by their dot-separated path from the root
+// of the record, such as "location.metro_code". Structs with embedded
+// structs are not tracked.
+type DecodeStats struct {
+	mu       sync.Mutex
+	unmapped map[string]uint64
+	missing  map[string]uint64
+}
+
+// NewDecodeStats returns an empty DecodeStats.
+func NewDecodeStats() *DecodeStats {
+	return &DecodeStats{
+		unmapped: map[string]uint64{},
+		missing:  map[string]uint64{},
+	}
+}
+
+// UnmappedKeys returns how many times each record key was skipped because
+// the destination struct has no field for it.
+func (s *DecodeStats) UnmappedKeys() map[string]uint64 {
+	return s.snapshot(false)
+}
+
+// MissingFields returns how many times each struct field was left unset
+// because the record has no value for it.
+func (s *DecodeStats) MissingFields() map[string]uint64 {
+	return s.snapshot(true)
+}
+
+// Reset clears all the counters.
+func (s *DecodeStats) Reset() {
+	s.mu.Lock()
+	s.unmapped = map[string]uint64{}
+	s.missing = map[string]uint64{}
+	s.mu.Unlock()
+}
+
+func (s *DecodeStats) snapshot(missing bool) map[string]uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counters := s.unmapped
+	if missing {
+		counters = s.missing
+	}
+	snapshot := make(map[string]uint64, len(counters))
+	for path, count := range counters {
+		snapshot[path] = count
+	}
+	return snapshot
+}
+
+func (s *DecodeStats) add(missing bool, path []string, key string) {
+	// This copies key, which may point into the database.
+	fullPath := strings.Join(append(path[:len(path):len(path)], key), ".")
+
+	s.mu.Lock()
+	if missing {
+		s.missing[fullPath]++
+	} else {
+		s.unmapped[fullPath]++
+	}
+	s.mu.Unlock()
+}
@@ -0,0 +1,73 @@
+package maxminddb
+
+import (
+	"net"
+	"testing"
+)
+
+func TestDecodeStats(t *testing.T) {
+	stats := NewDecodeStats()
+	reader, err := Open(
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+		WithDecodeStats(stats),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var record struct {
+		City struct {
+			GeoNameID uint `maxminddb:"geoname_id"`
+		} `maxminddb:"city"`
+		Country struct {
+			IsoCode string `maxminddb:"iso_code"`
+		} `maxminddb:"country"`
+		Location struct {
+			Latitude  float64 `maxminddb:"latitude"`
+			MetroCode uint    `maxminddb:"metro_code"`
+		} `maxminddb:"location"`
+		Postal struct {
+			Code string `maxminddb:"code"`
+		} `maxminddb:"postal"`
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := reader.Lookup(net.ParseIP("81.2.69.142"), &record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	unmapped := stats.UnmappedKeys()
+	for _, path := range []string{
+		"continent",
+		"city.names",
+		"country.geoname_id",
+		"country.names",
+		"location.longitude",
+		"location.time_zone",
+		"subdivisions",
+	} {
+		if unmapped[path] != 2 {
+			t.Errorf("expected 2 unmapped %s keys, got %d", path, unmapped[path])
+		}
+	}
+	if unmapped["country.iso_code"] != 0 {
+		t.Error("country.iso_code was reported as unmapped")
+	}
+
+	missing := stats.MissingFields()
+	if missing["location.metro_code"] != 2 {
+		t.Errorf("expected 2 missing location.metro_code fields, got %v", missing)
+	}
+	for _, path := range []string{"city.geoname_id", "country.iso_code", "location.latitude"} {
+		if missing[path] != 0 || unmapped[path] != 0 {
+			t.Errorf("%s was reported as a mismatch", path)
+		}
+	}
+
+	stats.Reset()
+	if len(stats.UnmappedKeys()) != 0 || len(stats.MissingFields()) != 0 {
+		t.Error("expected no counters after Reset")
+	}
+}
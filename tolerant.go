@@ -0,0 +1,140 @@
+package maxminddb
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithWarnings makes the Reader report through warn the irregularities it
+// tolerates in a database, such as those accepted by WithLenientMetadata
+// and WithMappedIPv4Fallback.
+func WithWarnings(warn func(message string)) ReaderOption {
+	return func(o *readerOptions) {
+		o.warn = warn
+	}
+}
+
+// WithLenientMetadata makes the Reader accept metadata with unusual value
+// types, as written by some third-party producers: a description given as a
+// single string, languages given as a single string, non-string entries in
+// either, or numbers of an unexpected integer type. Values that cannot be
+// used are dropped.
+func WithLenientMetadata() ReaderOption {
+	return func(o *readerOptions) {
+		o.lenientMetadata = true
+	}
+}
+
+// WithMappedIPv4Fallback makes the Reader look up IPv4 addresses in the
+// IPv4-mapped subtree, ::ffff:0:0/96, of IPv6 databases that have no IPv4
+// subtree at ::/96. Some third-party producers only insert IPv4 networks
+// there, without the aliasing that MaxMind databases have.
+func WithMappedIPv4Fallback() ReaderOption {
+	return func(o *readerOptions) {
+		o.mappedIPv4Fallback = true
+	}
+}
+
+func (o *readerOptions) warnf(format string, args ...interface{}) {
+	if o.warn != nil {
+		o.warn(fmt.Sprintf(format, args...))
+	}
+}
+
+// decodeLenientMetadata decodes the metadata at offset 0 of d, tolerating
+// unusual value types.
+func decodeLenientMetadata(d decoder, opts *readerOptions) (Metadata, error) {
+	var raw map[string]interface{}
+	if _, err := d.decode(0, reflect.ValueOf(&raw)); err != nil {
+		return Metadata{}, err
+	}
+
+	var metadata Metadata
+	for key, target := range map[string]*uint{
+		"binary_format_major_version": &metadata.BinaryFormatMajorVersion,
+		"binary_format_minor_version": &metadata.BinaryFormatMinorVersion,
+		"build_epoch":                 &metadata.BuildEpoch,
+		"ip_version":                  &metadata.IPVersion,
+		"node_count":                  &metadata.NodeCount,
+		"record_size":                 &metadata.RecordSize,
+	} {
+		switch value := raw[key].(type) {
+		case uint64:
+			*target = uint(value)
+		case int:
+			if value < 0 {
+				return Metadata{}, newInvalidDatabaseError("the MaxMind DB metadata has a negative %s", key)
+			}
+			opts.warnf("metadata %s is a signed integer", key)
+			*target = uint(value)
+		case nil:
+		default:
+			opts.warnf("ignoring metadata %s of type %T", key, value)
+		}
+	}
+
+	switch value := raw["database_type"].(type) {
+	case string:
+		metadata.DatabaseType = value
+	case nil:
+	default:
+		opts.warnf("ignoring metadata database_type of type %T", value)
+	}
+
+	switch value := raw["description"].(type) {
+	case map[string]interface{}:
+		metadata.Description = map[string]string{}
+		for language, description := range value {
+			if s, ok := description.(string); ok {
+				metadata.Description[language] = s
+			} else {
+				opts.warnf("ignoring metadata description for %q of type %T", language, description)
+			}
+		}
+	case string:
+		opts.warnf("metadata description is a single string; using it for \"en\"")
+		metadata.Description = map[string]string{"en": value}
+	case nil:
+	default:
+		opts.warnf("ignoring metadata description of type %T", value)
+	}
+
+	switch value := raw["languages"].(type) {
+	case []interface{}:
+		for _, language := range value {
+			if s, ok := language.(string); ok {
+				metadata.Languages = append(metadata.Languages, s)
+			} else {
+				opts.warnf("ignoring metadata language of type %T", language)
+			}
+		}
+	case string:
+		opts.warnf("metadata languages is a single string")
+		metadata.Languages = []string{value}
+	case nil:
+	default:
+		opts.warnf("ignoring metadata languages of type %T", value)
+	}
+
+	return metadata, nil
+}
+
+// mappedIPv4Start returns the node of ::ffff:0:0/96, or the node count if
+// the database has no such subtree.
+func (r *Reader) mappedIPv4Start() (uint, error) {
+	nodeCount := r.Metadata.NodeCount
+
+	node := uint(0)
+	var err error
+	for i := 0; i < 96 && node < nodeCount; i++ {
+		bit := uint(0)
+		if i >= 80 {
+			bit = 1
+		}
+		node, err = r.readNode(node, bit)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,186 @@
+package maxminddb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// encodeTestValue encodes strings, uints, maps and slices in the MaxMind DB
+// data format.
+func encodeTestValue(value interface{}) []byte {
+	control := func(kind Kind, size int) []byte {
+		var b []byte
+		if kind > 7 {
+			b = []byte{0, byte(kind - 7)}
+		} else {
+			b = []byte{byte(kind) << 5}
+		}
+		if size < 29 {
+			b[0] |= byte(size)
+			return b
+		}
+		b[0] |= 29
+		return append(b, byte(size-29))
+	}
+
+	switch v := value.(type) {
+	case string:
+		return append(control(KindString, len(v)), v...)
+	case uint:
+		var payload [4]byte
+		binary.BigEndian.PutUint32(payload[:], uint32(v))
+		return append(control(KindUint32, 4), payload[:]...)
+	case []interface{}:
+		b := control(KindSlice, len(v))
+		for _, element := range v {
+			b = append(b, encodeTestValue(element)...)
+		}
+		return b
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		b := control(KindMap, len(v))
+		for _, key := range keys {
+			b = append(b, encodeTestValue(key)...)
+			b = append(b, encodeTestValue(v[key])...)
+		}
+		return b
+	default:
+		panic("unsupported test value")
+	}
+}
+
+func TestLenientMetadata(t *testing.T) {
+	original, err := ioutil.ReadFile("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := FromBytes(original)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadata := map[string]interface{}{
+		"binary_format_major_version": uint(2),
+		"binary_format_minor_version": uint(0),
+		"build_epoch":                 uint(reader.Metadata.BuildEpoch),
+		"database_type":               "Third-Party",
+		"description":                 "a single string",
+		"ip_version":                  uint(4),
+		"languages":                   "en",
+		"node_count":                  uint(reader.Metadata.NodeCount),
+		"record_size":                 uint(24),
+	}
+	markerEnd := bytes.LastIndex(original, metadataStartMarker) + len(metadataStartMarker)
+	data := append(append([]byte(nil), original[:markerEnd]...), encodeTestValue(metadata)...)
+
+	if _, err := FromBytes(data); err == nil {
+		t.Fatal("expected an error without WithLenientMetadata")
+	}
+
+	var warnings []string
+	reader, err = FromBytes(data, WithLenientMetadata(), WithWarnings(func(message string) {
+		warnings = append(warnings, message)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reader.Metadata.Description["en"] != "a single string" ||
+		len(reader.Metadata.Languages) != 1 ||
+		reader.Metadata.DatabaseType != "Third-Party" {
+		t.Errorf("unexpected metadata: %+v", reader.Metadata)
+	}
+	if len(warnings) == 0 || !strings.Contains(strings.Join(warnings, "\n"), "description") {
+		t.Errorf("expected a warning about the description, got %q", warnings)
+	}
+
+	var result map[string]string
+	if err := reader.Lookup(net.ParseIP("1.1.1.1"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["ip"] != "1.1.1.1" {
+		t.Errorf("unexpected record: %v", result)
+	}
+}
+
+// mappedOnlyDatabase returns an IPv6 database with a single record for
+// ::ffff:0:0/96 and no IPv4 subtree at ::/96.
+func mappedOnlyDatabase() []byte {
+	const nodeCount = 97
+	dataPointer := uint32(nodeCount + dataSectionSeparatorSize)
+
+	var tree []byte
+	record := func(value uint32) {
+		tree = append(tree, byte(value>>16), byte(value>>8), byte(value))
+	}
+	for depth := 0; depth < 96; depth++ {
+		if depth < 80 {
+			record(uint32(depth + 1))
+			record(nodeCount)
+		} else {
+			record(nodeCount)
+			record(uint32(depth + 1))
+		}
+	}
+	record(dataPointer)
+	record(dataPointer)
+
+	var data []byte
+	data = append(data, tree...)
+	data = append(data, make([]byte, dataSectionSeparatorSize)...)
+	data = append(data, encodeTestValue(map[string]interface{}{"ip": "mapped"})...)
+	data = append(data, metadataStartMarker...)
+	data = append(data, encodeTestValue(map[string]interface{}{
+		"binary_format_major_version": uint(2),
+		"binary_format_minor_version": uint(0),
+		"build_epoch":                 uint(0),
+		"database_type":               "Mapped-Only",
+		"description":                 map[string]interface{}{"en": "IPv4 only in ::ffff:0:0/96"},
+		"ip_version":                  uint(6),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint(nodeCount),
+		"record_size":                 uint(24),
+	})...)
+	return data
+}
+
+func TestMappedIPv4Fallback(t *testing.T) {
+	data := mappedOnlyDatabase()
+
+	reader, err := FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result map[string]string
+	if err := reader.Lookup(net.ParseIP("1.2.3.4"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result != nil {
+		t.Errorf("expected no record without the fallback, got %v", result)
+	}
+
+	var warnings []string
+	reader, err = FromBytes(data, WithMappedIPv4Fallback(), WithWarnings(func(message string) {
+		warnings = append(warnings, message)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reader.Lookup(net.ParseIP("1.2.3.4"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if result["ip"] != "mapped" {
+		t.Errorf("expected the mapped record, got %v", result)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning, got %q", warnings)
+	}
+}
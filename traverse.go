@@ -1,10 +1,14 @@
 package maxminddb
 
-import "net"
+import (
+	"errors"
+	"fmt"
+	"net"
+)
 
 // Internal structure used to keep track of nodes we still need to visit.
 type netNode struct {
-	ip      net.IP
+	ip      [16]byte
 	bit     uint
 	pointer uint
 }
@@ -14,7 +18,62 @@ type Networks struct {
 	reader   *Reader
 	nodes    []netNode // Nodes we still have to visit.
 	lastNode netNode
+	ipLen    int
 	err      error
+
+	reuse   bool
+	network net.IPNet
+
+	skipAliased bool
+}
+
+// NetworksOption configures a Networks iterator.
+type NetworksOption func(*Networks)
+
+// ReuseBuffers makes the iterator reuse its memory across networks, which
+// greatly reduces the garbage generated by full scans. The network returned
+// by Network is then only valid until the next call to Next. Maps and
+// slices already present in the result value are cleared and reused rather
+// than allocated again, so values decoded into them are only valid until
+// the next call to Network with the same result.
+func ReuseBuffers() NetworksOption {
+	return func(n *Networks) {
+		n.reuse = true
+	}
+}
+
+// SkipAliasedNetworks makes the iterator skip the networks of an IPv6
+// database that alias the IPv4 subtree, i.e., ::ffff:0:0/96 (IPv4-mapped),
+// 2001::/32 (Teredo) and 2002::/16 (6to4), so that each IPv4 network is
+// returned once, in the ::/96 subtree.
+func SkipAliasedNetworks() NetworksOption {
+	return func(n *Networks) {
+		n.skipAliased = true
+	}
+}
+
+// aliasedNetworks are the networks that IPv6 databases commonly point at the
+// IPv4 subtree.
+var aliasedNetworks = []struct {
+	ip  [16]byte
+	bit uint
+}{
+	{ip: [16]byte{10: 0xff, 11: 0xff}, bit: 96},
+	{ip: [16]byte{0: 0x20, 1: 0x01}, bit: 32},
+	{ip: [16]byte{0: 0x20, 1: 0x02}, bit: 16},
+}
+
+// isAliased reports whether node is the root of an aliased network.
+func (n *Networks) isAliased(node netNode) bool {
+	if !n.skipAliased || n.ipLen != 16 {
+		return false
+	}
+	for _, alias := range aliasedNetworks {
+		if node.bit == alias.bit && node.ip == alias.ip {
+			return true
+		}
+	}
+	return false
 }
 
 // Networks returns an iterator that can be used to traverse all networks in
@@ -22,37 +81,111 @@ type Networks struct {
 //
 // Please note that a MaxMind DB may map IPv4 networks into several locations
 // in in an IPv6 database. This iterator will iterate over all of these
-// locations separately.
-func (r *Reader) Networks() *Networks {
+// locations separately, unless the SkipAliasedNetworks option is used.
+func (r *Reader) Networks(options ...NetworksOption) *Networks {
 	s := 4
 	if r.Metadata.IPVersion == 6 {
 		s = 16
 	}
-	return &Networks{
+	n := &Networks{
 		reader: r,
-		nodes: []netNode{
-			{
-				ip: make(net.IP, s),
-			},
-		},
+		nodes:  []netNode{{}},
+		ipLen:  s,
+	}
+	for _, option := range options {
+		option(n)
+	}
+	if n.reuse {
+		n.network.IP = make(net.IP, s)
+		n.network.Mask = make(net.IPMask, s)
+	}
+	return n
+}
+
+// NetworksWithin is like Networks but only iterates over the networks
+// contained in network. The traversal starts at the node of the search tree
+// for network, so the rest of the tree is never read. If network is within a
+// larger network of the database, the iterator returns network itself with
+// the record of the larger one.
+//
+// In an IPv6 database, IPv4 networks are looked up in the IPv4 subtree, ::/96,
+// and the networks are returned in the same form as by Networks.
+func (r *Reader) NetworksWithin(network *net.IPNet, options ...NetworksOption) *Networks {
+	n := r.Networks(options...)
+	n.nodes = nil
+
+	start, err := r.subtree(network)
+	if err != nil {
+		n.err = err
+		return n
 	}
+	n.nodes = []netNode{start}
+	return n
+}
+
+// subtree returns the node of the search tree for network. If network is
+// within a larger network of the database, the node has the record of the
+// larger network but the bit count of network.
+func (r *Reader) subtree(network *net.IPNet) (netNode, error) {
+	if r.buffer == nil {
+		return netNode{}, ErrClosed
+	}
+	prefix, err := NetworkToPrefix(network)
+	if err != nil {
+		return netNode{}, err
+	}
+	ipLen := 4
+	if r.Metadata.IPVersion == 6 {
+		ipLen = 16
+	}
+	if prefix.Addr().Is6() && ipLen == 4 {
+		return netNode{}, fmt.Errorf("error getting networks with '%s': you attempted to use an IPv6 network in an IPv4-only database", prefix)
+	}
+
+	var start netNode
+	bits := uint(prefix.Bits())
+	if prefix.Addr().Is4() {
+		ip := prefix.Addr().As4()
+		copy(start.ip[ipLen-4:], ip[:])
+		bits += uint(ipLen-4) * 8
+	} else {
+		start.ip = prefix.Addr().As16()
+	}
+
+	nodeCount := r.Metadata.NodeCount
+	for start.bit < bits && start.pointer < nodeCount {
+		bit := uint(1) & (uint(start.ip[start.bit>>3]) >> (7 - (start.bit % 8)))
+		start.pointer, err = r.readNode(start.pointer, bit)
+		if err != nil {
+			return netNode{}, err
+		}
+		start.bit++
+	}
+	start.bit = bits
+	return start, nil
 }
 
 // Next prepares the next network for reading with the Network method. It
 // returns true if there is another network to be processed and false if there
 // are no more networks or if there is an error.
 func (n *Networks) Next() bool {
+	if n.reader.buffer == nil {
+		n.err = ErrClosed
+		return false
+	}
 	for len(n.nodes) > 0 {
 		node := n.nodes[len(n.nodes)-1]
 		n.nodes = n.nodes[:len(n.nodes)-1]
 
 		for {
+			if n.isAliased(node) {
+				break
+			}
 			if node.pointer < n.reader.Metadata.NodeCount {
-				ipRight := make(net.IP, len(node.ip))
-				copy(ipRight, node.ip)
-				if len(ipRight) <= int(node.bit>>3) {
+				ipRight := node.ip
+				if n.ipLen <= int(node.bit>>3) {
 					n.err = newInvalidDatabaseError(
-						"invalid search tree at %v/%v", ipRight, node.bit)
+						"invalid search tree at %v/%v", net.IP(ipRight[:n.ipLen]), node.bit)
 					return false
 				}
 				ipRight[node.bit>>3] |= 1 << uint(7-(node.bit%8))
@@ -92,17 +225,73 @@ func (n *Networks) Next() bool {
 // decoding the data for the network. It takes a pointer to a result value to
 // decode the network's data into.
 func (n *Networks) Network(result interface{}) (*net.IPNet, error) {
-	if err := n.reader.retrieveData(n.lastNode.pointer, result); err != nil {
+	offset, err := n.reader.resolveDataPointer(n.lastNode.pointer)
+	if err != nil {
+		return nil, err
+	}
+	if n.reuse {
+		err = n.reader.decodeReusing(offset, result)
+	} else {
+		err = n.reader.Decode(offset, result)
+	}
+	if err != nil {
 		return nil, err
 	}
+	return n.currentNetwork(), nil
+}
+
+func (n *Networks) currentNetwork() *net.IPNet {
+	if !n.reuse {
+		ip := make(net.IP, n.ipLen)
+		copy(ip, n.lastNode.ip[:])
+		return &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(int(n.lastNode.bit), n.ipLen*8),
+		}
+	}
 
-	return &net.IPNet{
-		IP:   n.lastNode.ip,
-		Mask: net.CIDRMask(int(n.lastNode.bit), len(n.lastNode.ip)*8),
-	}, nil
+	copy(n.network.IP, n.lastNode.ip[:])
+	ones := int(n.lastNode.bit)
+	for i := range n.network.Mask {
+		switch {
+		case ones >= 8:
+			n.network.Mask[i] = 0xff
+			ones -= 8
+		default:
+			n.network.Mask[i] = ^byte(0xff >> uint(ones))
+			ones = 0
+		}
+	}
+	return &n.network
 }
 
 // Err returns an error, if any, that was encountered during iteration.
 func (n *Networks) Err() error {
 	return n.err
 }
+
+// ErrStopWalk may be returned by the function passed to WalkNetworks to stop
+// the walk without error.
+var ErrStopWalk = errors.New("maxminddb: stop walking networks")
+
+// WalkNetworks calls fn for each network in the database, in the same order
+// as the Networks iterator, with the offset of its record, which may be
+// passed to Decode. If fn returns ErrStopWalk, the walk stops and
+// WalkNetworks returns nil. If it returns any other error, the walk stops
+// and WalkNetworks returns that error.
+func (r *Reader) WalkNetworks(fn func(network *net.IPNet, offset uintptr) error, options ...NetworksOption) error {
+	n := r.Networks(options...)
+	for n.Next() {
+		offset, err := r.resolveDataPointer(n.lastNode.pointer)
+		if err != nil {
+			return err
+		}
+		if err := fn(n.currentNetwork(), offset); err != nil {
+			if err == ErrStopWalk {
+				return nil
+			}
+			return err
+		}
+	}
+	return n.Err()
+}
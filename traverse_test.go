@@ -1,7 +1,10 @@
 package maxminddb
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"reflect"
 	"testing"
 )
 
@@ -57,3 +60,207 @@ func TestNetworksWithInvalidSearchTree(t *testing.T) {
 		t.Error(n.Err())
 	}
 }
+
+func TestNetworksReuseBuffers(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv6-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var expected []string
+	n := reader.Networks()
+	for n.Next() {
+		var record map[string]string
+		network, err := n.Network(&record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected = append(expected, network.String()+" "+record["ip"])
+	}
+	if n.Err() != nil {
+		t.Fatal(n.Err())
+	}
+
+	var got []string
+	record := map[string]string{"stale": "value"}
+	n = reader.Networks(ReuseBuffers())
+	for n.Next() {
+		network, err := n.Network(&record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := record["stale"]; ok {
+			t.Fatalf("expected the result map to be cleared, got %v", record)
+		}
+		got = append(got, network.String()+" "+record["ip"])
+	}
+	if n.Err() != nil {
+		t.Fatal(n.Err())
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestWalkNetworks(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var networks []string
+	err = reader.WalkNetworks(func(network *net.IPNet, offset uintptr) error {
+		var record struct {
+			IP string `maxminddb:"ip"`
+		}
+		if err := reader.Decode(offset, &record); err != nil {
+			return err
+		}
+		if record.IP != network.IP.String() {
+			t.Errorf("expected %s got %s", record.IP, network.IP.String())
+		}
+		networks = append(networks, network.String())
+		if len(networks) == 3 {
+			return ErrStopWalk
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"1.1.1.1/32", "1.1.1.2/31", "1.1.1.4/30"}
+	if !reflect.DeepEqual(networks, expected) {
+		t.Errorf("expected %v, got %v", expected, networks)
+	}
+
+	abort := errors.New("abort")
+	count := 0
+	err = reader.WalkNetworks(func(network *net.IPNet, offset uintptr) error {
+		count++
+		return abort
+	}, ReuseBuffers())
+	if err != abort || count != 1 {
+		t.Errorf("expected the abort error after one network, got %v after %d", err, count)
+	}
+}
+
+func TestNetworksWithin(t *testing.T) {
+	tests := []struct {
+		fileName string
+		network  string
+		expected []string
+	}{
+		{
+			fileName: "MaxMind-DB-test-ipv4-24.mmdb",
+			network:  "1.1.1.0/29",
+			expected: []string{"1.1.1.1/32", "1.1.1.2/31", "1.1.1.4/30"},
+		},
+		{
+			// The network is within 1.1.1.16/28.
+			fileName: "MaxMind-DB-test-ipv4-24.mmdb",
+			network:  "1.1.1.16/30",
+			expected: []string{"1.1.1.16/30"},
+		},
+		{
+			fileName: "MaxMind-DB-test-ipv4-24.mmdb",
+			network:  "10.0.0.0/8",
+		},
+		{
+			fileName: "MaxMind-DB-test-mixed-24.mmdb",
+			network:  "1.1.1.0/30",
+			expected: []string{"::101:101/128", "::101:102/127"},
+		},
+		{
+			fileName: "MaxMind-DB-test-ipv6-24.mmdb",
+			network:  "::2:0:40/122",
+			expected: []string{"::2:0:40/124", "::2:0:50/125", "::2:0:58/127"},
+		},
+	}
+	for _, test := range tests {
+		reader, err := Open("test-data/test-data/" + test.fileName)
+		if err != nil {
+			t.Fatalf("unexpected error while opening database: %v", err)
+		}
+
+		_, network, err := net.ParseCIDR(test.network)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var networks []string
+		n := reader.NetworksWithin(network)
+		for n.Next() {
+			var record interface{}
+			network, err := n.Network(&record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			networks = append(networks, network.String())
+		}
+		if n.Err() != nil {
+			t.Fatal(n.Err())
+		}
+		if !reflect.DeepEqual(networks, test.expected) {
+			t.Errorf("expected %v within %s in %s, got %v", test.expected, test.network, test.fileName, networks)
+		}
+		reader.Close()
+	}
+}
+
+func TestNetworksWithinIPv6InIPv4Database(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	_, network, _ := net.ParseCIDR("2001:db8::/32")
+	n := reader.NetworksWithin(network)
+	if n.Next() {
+		t.Error("expected no networks")
+	}
+	if n.Err() == nil {
+		t.Error("expected an error for an IPv6 network in an IPv4 database")
+	}
+}
+
+func TestSkipAliasedNetworks(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-mixed-24.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	aliased := func(options ...NetworksOption) (count, total int) {
+		n := reader.Networks(options...)
+		for n.Next() {
+			var record interface{}
+			network, err := n.Network(&record)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total++
+			if network.IP.To4() != nil || network.IP[0] == 0x20 && (network.IP[1] == 0x01 || network.IP[1] == 0x02) {
+				count++
+			}
+		}
+		if n.Err() != nil {
+			t.Fatal(n.Err())
+		}
+		return count, total
+	}
+
+	count, all := aliased()
+	if count == 0 {
+		t.Fatal("expected the database to have aliased networks")
+	}
+	count, total := aliased(SkipAliasedNetworks())
+	if count != 0 {
+		t.Errorf("expected no aliased networks, got %d", count)
+	}
+	if total >= all {
+		t.Errorf("expected fewer networks when skipping aliases, got %d of %d", total, all)
+	}
+}
@@ -0,0 +1,209 @@
+// Package triage helps investigate MaxMind DB inputs that make the reader
+// fail, typically found by fuzzing: it checks an input, minimizes it while
+// preserving the failure and annotates offsets with the structure of the
+// database they belong to.
+package triage
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+const dataSectionSeparatorSize = 16
+
+// Failure describes how the reader failed on an input.
+type Failure struct {
+	// Stage is the operation that failed: "open", "search tree", "decode"
+	// or "verify".
+	Stage string
+	// Network is the network whose record failed to decode, if any.
+	Network *net.IPNet
+	// Offset is the offset in the input of the record that failed to
+	// decode, or -1 if it is unknown.
+	Offset int
+	// Panicked is true if the reader panicked rather than returning an
+	// error.
+	Panicked bool
+	Err      error
+}
+
+func (f *Failure) Error() string {
+	what := "error"
+	if f.Panicked {
+		what = "panic"
+	}
+	msg := fmt.Sprintf("%s %s: %v", f.Stage, what, f.Err)
+	if f.Network != nil {
+		msg += fmt.Sprintf(" (network %s)", f.Network)
+	}
+	return msg
+}
+
+// Check opens data as a database, decodes the records of all its networks
+// and verifies it, recovering from panics. It returns the first failure, or
+// nil if the reader handles the input correctly.
+func Check(data []byte) *Failure {
+	var reader *maxminddb.Reader
+	if failure := run("open", func() (err error) {
+		reader, err = maxminddb.FromBytes(data)
+		return err
+	}); failure != nil {
+		return failure
+	}
+
+	// The network and offset of each record are known before it is
+	// decoded, so that a decode failure can be located.
+	var (
+		network  *net.IPNet
+		offset   uintptr
+		decoding bool
+	)
+	if failure := run("search tree", func() error {
+		return reader.WalkNetworks(func(n *net.IPNet, o uintptr) error {
+			network, offset, decoding = n, o, true
+			var record interface{}
+			if err := reader.Decode(o, &record); err != nil {
+				return err
+			}
+			decoding = false
+			return nil
+		})
+	}); failure != nil {
+		if decoding {
+			failure.Stage = "decode"
+			failure.Network = network
+			failure.Offset = dataSectionStart(reader.Metadata) + int(offset)
+		}
+		return failure
+	}
+
+	return run("verify", reader.Verify)
+}
+
+// run calls f, turning its error or panic into a Failure of stage.
+func run(stage string, f func() error) (failure *Failure) {
+	defer func() {
+		if r := recover(); r != nil {
+			err, ok := r.(error)
+			if !ok {
+				err = fmt.Errorf("%v", r)
+			}
+			failure = &Failure{Stage: stage, Offset: -1, Panicked: true, Err: err}
+		}
+	}()
+	if err := f(); err != nil {
+		return &Failure{Stage: stage, Offset: -1, Err: err}
+	}
+	return nil
+}
+
+func dataSectionStart(metadata maxminddb.Metadata) int {
+	return int(metadata.NodeCount*metadata.RecordSize/4) + dataSectionSeparatorSize
+}
+
+// Minimize returns the smallest input it finds for which failing still
+// returns true, by repeatedly removing chunks of data of decreasing size.
+// It is deterministic, and failing is expected to be as well. failing must
+// return true for data.
+func Minimize(data []byte, failing func([]byte) bool) []byte {
+	data = append([]byte(nil), data...)
+	for chunk := len(data) / 2; chunk > 0; chunk /= 2 {
+		for start := 0; start+chunk <= len(data); {
+			candidate := make([]byte, 0, len(data)-chunk)
+			candidate = append(candidate, data[:start]...)
+			candidate = append(candidate, data[start+chunk:]...)
+			if failing(candidate) {
+				data = candidate
+				continue
+			}
+			start += chunk
+		}
+	}
+	return data
+}
+
+// SameFailure returns a function, suitable for Minimize, reporting whether
+// an input fails at the same stage and in the same way as failure.
+func SameFailure(failure *Failure) func([]byte) bool {
+	return func(data []byte) bool {
+		f := Check(data)
+		return f != nil && f.Stage == failure.Stage && f.Panicked == failure.Panicked
+	}
+}
+
+// Annotate describes the part of the database data that offset belongs to:
+// the search tree node, the data section separator, the value in the data
+// section and its type, or the metadata section.
+func Annotate(data []byte, offset int) string {
+	if offset < 0 || offset >= len(data) {
+		return fmt.Sprintf("offset %d: outside of the input (%d bytes)", offset, len(data))
+	}
+
+	markerStart := bytes.LastIndex(data, metadataStartMarker)
+	if markerStart == -1 {
+		return fmt.Sprintf("offset %d: unknown section (no metadata marker)", offset)
+	}
+	if offset >= markerStart {
+		return fmt.Sprintf("offset %d: metadata section, byte %d after the marker start", offset, offset-markerStart)
+	}
+
+	metadata, ok := readMetadata(data)
+	if !ok {
+		return fmt.Sprintf("offset %d: before the metadata (unreadable metadata)", offset)
+	}
+
+	treeSize := int(metadata.NodeCount * metadata.RecordSize / 4)
+	dataStart := dataSectionStart(metadata)
+	switch {
+	case offset < treeSize:
+		nodeSize := int(metadata.RecordSize / 4)
+		return fmt.Sprintf(
+			"offset %d: search tree node %d, byte %d (%d-bit records)",
+			offset, offset/nodeSize, offset%nodeSize, metadata.RecordSize,
+		)
+	case offset < dataStart:
+		return fmt.Sprintf("offset %d: data section separator, byte %d", offset, offset-treeSize)
+	default:
+		return fmt.Sprintf(
+			"offset %d: data section offset %d, %s",
+			offset, offset-dataStart, describeControl(data[offset:markerStart]),
+		)
+	}
+}
+
+func readMetadata(data []byte) (metadata maxminddb.Metadata, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		// The metadata may be fine even if the search tree is not.
+		if _, isInvalid := err.(maxminddb.InvalidDatabaseError); !isInvalid || reader == nil {
+			return maxminddb.Metadata{}, false
+		}
+	}
+	return reader.Metadata, true
+}
+
+// describeControl describes the control byte at the start of b.
+func describeControl(b []byte) string {
+	kind := maxminddb.Kind(b[0] >> 5)
+	size := int(b[0] & 0x1f)
+	if kind == maxminddb.KindExtended {
+		if len(b) < 2 {
+			return "truncated extended type"
+		}
+		kind = maxminddb.Kind(int(b[1]) + 7)
+	}
+	if kind == maxminddb.KindPointer {
+		return fmt.Sprintf("%s control byte %#02x", kind, b[0])
+	}
+	return fmt.Sprintf("%s control byte %#02x, size bits %d", kind, b[0], size)
+}
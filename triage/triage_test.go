@@ -0,0 +1,107 @@
+package triage
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+func TestCheck(t *testing.T) {
+	data, err := ioutil.ReadFile("../test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failure := Check(data); failure != nil {
+		t.Fatalf("unexpected failure: %v", failure)
+	}
+
+	failure := Check(data[:len(data)/2])
+	if failure == nil || failure.Stage != "open" {
+		t.Errorf("expected an open failure, got %v", failure)
+	}
+
+	data, err = ioutil.ReadFile("../test-data/test-data/MaxMind-DB-test-broken-pointers-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failure := Check(data); failure == nil {
+		t.Error("expected a failure for broken pointers")
+	}
+}
+
+func TestCheckCorruptedRecord(t *testing.T) {
+	data, err := ioutil.ReadFile("../test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := maxminddb.FromBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Make the first record of the data section an extended type that
+	// does not exist.
+	start := dataSectionStart(reader.Metadata)
+	data[start], data[start+1] = 0x00, 0xff
+
+	failure := Check(data)
+	if failure == nil || failure.Stage != "decode" {
+		t.Fatalf("expected a decode failure, got %v", failure)
+	}
+	if failure.Network == nil || failure.Offset < start {
+		t.Errorf("expected the network and offset of the record, got %v at %d", failure.Network, failure.Offset)
+	}
+	if !strings.Contains(Annotate(data, failure.Offset), "data section") {
+		t.Errorf("expected the offset to be in the data section, got %q", Annotate(data, failure.Offset))
+	}
+}
+
+func TestMinimize(t *testing.T) {
+	failing := func(data []byte) bool {
+		return bytes.Contains(data, []byte("crash"))
+	}
+	data := []byte("some input that makes the decoder crash eventually")
+	minimized := Minimize(data, failing)
+	if string(minimized) != "crash" {
+		t.Errorf("expected the input to be minimized to %q, got %q", "crash", minimized)
+	}
+	if string(Minimize(data, failing)) != string(minimized) {
+		t.Error("expected minimization to be deterministic")
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	data, err := ioutil.ReadFile("../test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker := bytes.LastIndex(data, metadataStartMarker)
+
+	tests := []struct {
+		offset   int
+		expected string
+	}{
+		{7, "search tree node 1, byte 1 (24-bit records)"},
+		{-1, "outside of the input"},
+		{marker + 3, "metadata section"},
+	}
+	for _, test := range tests {
+		if annotation := Annotate(data, test.offset); !strings.Contains(annotation, test.expected) {
+			t.Errorf("expected %q in the annotation of %d, got %q", test.expected, test.offset, annotation)
+		}
+	}
+
+	// The first value of the data section is a map.
+	for offset := range data {
+		annotation := Annotate(data, offset)
+		if strings.Contains(annotation, "data section offset 0,") {
+			if !strings.Contains(annotation, "map control byte") {
+				t.Errorf("unexpected annotation %q", annotation)
+			}
+			return
+		}
+	}
+	t.Error("no offset annotated as the start of the data section")
+}
@@ -0,0 +1,137 @@
+package maxminddb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Unmarshaler is implemented by types that decode themselves from a value
+// of the data section, e.g., to handle unusual record layouts or to
+// transform values while decoding. The decoder calls UnmarshalMaxMindDB
+// instead of decoding into a value whose type, or pointer type, implements
+// Unmarshaler.
+type Unmarshaler interface {
+	UnmarshalMaxMindDB(d *Decoder) error
+}
+
+var unmarshalerType = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+
+// Decoder gives an Unmarshaler access to the value it is decoded from.
+// Pointers in the data section are followed transparently.
+type Decoder struct {
+	d      *decoder
+	offset uint
+}
+
+// Kind returns the type of the value.
+func (d *Decoder) Kind() Kind {
+	kind, _, _, ok := d.d.resolve(d.offset)
+	if !ok {
+		return KindExtended
+	}
+	return kind
+}
+
+// Decode decodes the value into v, which must be a pointer, as Decode on
+// Reader does. Calling it with the type being unmarshaled would recurse
+// forever; use a type without the UnmarshalMaxMindDB method instead.
+func (d *Decoder) Decode(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return errors.New("result param must be a pointer")
+	}
+	_, err := d.d.decode(d.offset, rv)
+	return err
+}
+
+// DecodeMap calls fn for each entry of the value, which must be a map, in
+// the order of the data section. Keys stored as bytes are passed as strings,
+// as when decoding maps and structs, and the entries the DecodeProfile of the
+// Reader omits are skipped. It stops at the first error returned by fn.
+func (d *Decoder) DecodeMap(fn func(key string, value *Decoder) error) error {
+	kind, size, offset, ok := d.d.resolve(d.offset)
+	if !ok {
+		return newInvalidDatabaseError("unexpected end of database")
+	}
+	if kind != KindMap {
+		return fmt.Errorf("maxminddb: cannot decode a %s as a map", kind)
+	}
+	// The entries are decoded one level deeper than the map.
+	entries := *d.d
+	if err := entries.enter(); err != nil {
+		return err
+	}
+	for i := uint(0); i < size; i++ {
+		keyKind, keySize, keyOffset, ok := d.d.resolve(offset)
+		if !ok || (keyKind != KindString && keyKind != KindBytes) || keyOffset+keySize > uint(len(d.d.buffer)) {
+			return newInvalidDatabaseError("invalid map key at offset %d", offset)
+		}
+		if err := d.d.checkUTF8(keyOffset, keyOffset+keySize); err != nil {
+			return err
+		}
+		key := string(d.d.buffer[keyOffset : keyOffset+keySize])
+		var err error
+		if offset, err = d.d.nextValueOffset(offset, 1); err != nil {
+			return err
+		}
+		entries.node = d.d.node
+		if _, omitted := entries.enterKey(key); !omitted {
+			if err := fn(key, &Decoder{d: &entries, offset: offset}); err != nil {
+				return err
+			}
+		}
+		if offset, err = d.d.nextValueOffset(offset, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DecodeSlice calls fn for each element of the value, which must be an
+// array. It stops at the first error returned by fn.
+func (d *Decoder) DecodeSlice(fn func(value *Decoder) error) error {
+	kind, size, offset, ok := d.d.resolve(d.offset)
+	if !ok {
+		return newInvalidDatabaseError("unexpected end of database")
+	}
+	if kind != KindSlice {
+		return fmt.Errorf("maxminddb: cannot decode a %s as an array", kind)
+	}
+	elements := *d.d
+	if err := elements.enter(); err != nil {
+		return err
+	}
+	for i := uint(0); i < size; i++ {
+		if err := fn(&Decoder{d: &elements, offset: offset}); err != nil {
+			return err
+		}
+		var err error
+		if offset, err = d.d.nextValueOffset(offset, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unmarshaler returns the Unmarshaler to decode result with, if any. Only
+// types declared in a package are checked, which keeps the check off the
+// common path of built-in and unnamed types.
+func unmarshaler(result reflect.Value) (Unmarshaler, bool) {
+	if result.Kind() == reflect.Ptr {
+		if result.Type().Elem().PkgPath() == "" || !result.Type().Implements(unmarshalerType) {
+			return nil, false
+		}
+		if result.IsNil() {
+			result.Set(reflect.New(result.Type().Elem()))
+		}
+		return result.Interface().(Unmarshaler), true
+	}
+	if result.Type().PkgPath() == "" || !result.CanAddr() {
+		return nil, false
+	}
+	if u, ok := result.Addr().Interface().(Unmarshaler); ok {
+		return u, true
+	}
+	return nil, false
+}
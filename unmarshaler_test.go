@@ -0,0 +1,154 @@
+package maxminddb
+
+import (
+	"encoding/hex"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+)
+
+// coordinates decodes a location map into a latitude/longitude pair.
+type coordinates [2]float64
+
+func (c *coordinates) UnmarshalMaxMindDB(d *Decoder) error {
+	return d.DecodeMap(func(key string, value *Decoder) error {
+		switch key {
+		case "latitude":
+			return value.Decode(&c[0])
+		case "longitude":
+			return value.Decode(&c[1])
+		}
+		return nil
+	})
+}
+
+// isoCodes decodes an array of subdivisions into their ISO codes.
+type isoCodes []string
+
+func (c *isoCodes) UnmarshalMaxMindDB(d *Decoder) error {
+	if d.Kind() != KindSlice {
+		return errors.New("expected an array")
+	}
+	return d.DecodeSlice(func(value *Decoder) error {
+		var subdivision struct {
+			IsoCode string `maxminddb:"iso_code"`
+		}
+		if err := value.Decode(&subdivision); err != nil {
+			return err
+		}
+		*c = append(*c, subdivision.IsoCode)
+		return nil
+	})
+}
+
+func TestUnmarshaler(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test.mmdb")
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var city City
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &city); err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct {
+		Location     coordinates  `maxminddb:"location"`
+		Subdivisions *isoCodes    `maxminddb:"subdivisions"`
+		Country      *coordinates `maxminddb:"country"`
+	}
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &result); err != nil {
+		t.Fatal(err)
+	}
+	if expected := (coordinates{city.Location.Latitude, city.Location.Longitude}); result.Location != expected {
+		t.Errorf("expected %v, got %v", expected, result.Location)
+	}
+	if result.Subdivisions == nil || !reflect.DeepEqual([]string(*result.Subdivisions), city.SubdivisionISOCodes()) {
+		t.Errorf("unexpected subdivisions: %v", result.Subdivisions)
+	}
+	if result.Country == nil || *result.Country != (coordinates{}) {
+		t.Errorf("expected zero coordinates for the country, got %v", result.Country)
+	}
+
+	var codes isoCodes
+	if err := reader.Lookup(net.ParseIP("81.2.69.160"), &codes); err == nil {
+		t.Error("expected the error of the unmarshaler for a map")
+	}
+}
+
+func TestDecodeMapBytesKeys(t *testing.T) {
+	// A map of a string key and of a bytes key: {"a": "x", b"b": "y"}.
+	inputBytes, err := hex.DecodeString("e24161417881624179")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &Decoder{d: &decoder{buffer: inputBytes}}
+	entries := map[string]string{}
+	err = d.DecodeMap(func(key string, value *Decoder) error {
+		var s string
+		if err := value.Decode(&s); err != nil {
+			return err
+		}
+		entries[key] = s
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]string{"a": "x", "b": "y"}; !reflect.DeepEqual(entries, expected) {
+		t.Errorf("expected %v, got %v", expected, entries)
+	}
+}
+
+// entryKeys decodes a map into the list of its keys, and the keys of the
+// maps it holds, as paths.
+type entryKeys []string
+
+func (k *entryKeys) UnmarshalMaxMindDB(d *Decoder) error {
+	return d.DecodeMap(func(key string, value *Decoder) error {
+		*k = append(*k, key)
+		if value.Kind() != KindMap {
+			return nil
+		}
+		var children entryKeys
+		if err := value.Decode(&children); err != nil {
+			return err
+		}
+		for _, child := range children {
+			*k = append(*k, key+"."+child)
+		}
+		return nil
+	})
+}
+
+func TestDecodeMapWithProfile(t *testing.T) {
+	reader, err := Open(
+		"test-data/test-data/GeoIP2-City-Test.mmdb",
+		WithDecodeProfile(PreciseLocationProfile),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error while opening database: %v", err)
+	}
+	defer reader.Close()
+
+	var keys entryKeys
+	if err := reader.Lookup(net.ParseIP("81.2.69.142"), &keys); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, key := range keys {
+		found[key] = true
+	}
+	for _, key := range []string{"location.latitude", "location.longitude", "postal"} {
+		if found[key] {
+			t.Errorf("expected %s to be omitted, got %v", key, keys)
+		}
+	}
+	for _, key := range []string{"location.time_zone", "country.iso_code"} {
+		if !found[key] {
+			t.Errorf("expected %s, got %v", key, keys)
+		}
+	}
+}
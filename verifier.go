@@ -1,130 +1,245 @@
 package maxminddb
 
-import "reflect"
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
 
 type verifier struct {
 	reader *Reader
+	report *Report
+}
+
+// Severity is the severity of a Finding.
+type Severity int
+
+const (
+	// SeverityWarning marks findings where the database is readable but
+	// does not follow the conventions that this verifier expects.
+	SeverityWarning Severity = iota
+	// SeverityError marks findings where the database does not follow the
+	// specification.
+	SeverityError
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Finding is a problem found when verifying a database.
+type Finding struct {
+	// Section is the section of the database the problem is in: "metadata",
+	// "search tree", "data section separator" or "data section". It is
+	// "reader" if the Reader is closed.
+	Section string
+	// Offset is the offset of the problem from the start of the section,
+	// or -1 if it does not apply to a specific offset.
+	Offset   int
+	Severity Severity
+	Err      error
+}
+
+func (f Finding) String() string {
+	if f.Offset < 0 {
+		return fmt.Sprintf("%s: %s: %v", f.Severity, f.Section, f.Err)
+	}
+	return fmt.Sprintf("%s: %s at offset %d: %v", f.Severity, f.Section, f.Offset, f.Err)
+}
+
+// Report holds all the findings of the verification of a database, so that
+// database producers can fix every problem at once.
+type Report struct {
+	Findings []Finding
+}
+
+// Err returns the report as an error if it has any finding, or nil.
+func (r *Report) Err() error {
+	if len(r.Findings) == 0 {
+		return nil
+	}
+	return r
+}
+
+// Error returns the findings, one per line.
+func (r *Report) Error() string {
+	lines := make([]string, len(r.Findings))
+	for i, finding := range r.Findings {
+		lines[i] = finding.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (v *verifier) add(section string, offset int, severity Severity, err error) {
+	v.report.Findings = append(v.report.Findings, Finding{
+		Section:  section,
+		Offset:   offset,
+		Severity: severity,
+		Err:      err,
+	})
 }
 
 // Verify checks that the database is valid. It validates the search tree,
 // the data section, and the metadata section. This verifier is stricter than
 // the specification and may return errors on databases that are readable.
+// It returns the first problem found; use VerifyReport to get all of them.
 func (r *Reader) Verify() error {
-	v := verifier{r}
-	if err := v.verifyMetadata(); err != nil {
-		return err
+	report := r.VerifyReport()
+	if len(report.Findings) == 0 {
+		return nil
+	}
+	return report.Findings[0].Err
+}
+
+// VerifyReport is like Verify but returns all the problems found. Checking
+// stops early only when a problem prevents finding the others, such as an
+// unreadable search tree.
+func (r *Reader) VerifyReport() *Report {
+	return r.verify()
+}
+
+// VerifyReportContext is like VerifyReport but, with WithProfilerLabels,
+// labels the verification for CPU profiles on top of the labels of ctx.
+func (r *Reader) VerifyReportContext(ctx context.Context) *Report {
+	if r.labels != nil {
+		var report *Report
+		r.labeled(ctx, "verify", func() error {
+			report = r.verify()
+			return nil
+		})
+		return report
 	}
+	return r.verify()
+}
 
-	return v.verifyDatabase()
+func (r *Reader) verify() *Report {
+	v := verifier{reader: r, report: &Report{}}
+	if r.buffer == nil {
+		v.add("reader", -1, SeverityError, ErrClosed)
+		return v.report
+	}
+	if v.verifyMetadata() {
+		v.verifyDatabase()
+	}
+	return v.report
 }
 
-func (v *verifier) verifyMetadata() error {
+// verifyMetadata returns false if the metadata is too broken to verify the
+// rest of the database.
+func (v *verifier) verifyMetadata() bool {
 	metadata := v.reader.Metadata
+	ok := true
 
 	if metadata.BinaryFormatMajorVersion != 2 {
-		return testError(
+		v.add("metadata", -1, SeverityError, testError(
 			"binary_format_major_version",
 			2,
 			metadata.BinaryFormatMajorVersion,
-		)
+		))
 	}
 
 	if metadata.BinaryFormatMinorVersion != 0 {
-		return testError(
+		v.add("metadata", -1, SeverityWarning, testError(
 			"binary_format_minor_version",
 			0,
 			metadata.BinaryFormatMinorVersion,
-		)
+		))
 	}
 
 	if metadata.DatabaseType == "" {
-		return testError(
+		v.add("metadata", -1, SeverityError, testError(
 			"database_type",
 			"non-empty string",
 			metadata.DatabaseType,
-		)
+		))
 	}
 
 	if len(metadata.Description) == 0 {
-		return testError(
+		v.add("metadata", -1, SeverityWarning, testError(
 			"description",
 			"non-empty slice",
 			metadata.Description,
-		)
+		))
 	}
 
 	if metadata.IPVersion != 4 && metadata.IPVersion != 6 {
-		return testError(
+		v.add("metadata", -1, SeverityError, testError(
 			"ip_version",
 			"4 or 6",
 			metadata.IPVersion,
-		)
+		))
+		ok = false
 	}
 
 	if metadata.RecordSize != 24 &&
 		metadata.RecordSize != 28 &&
 		metadata.RecordSize != 32 {
-		return testError(
+		v.add("metadata", -1, SeverityError, testError(
 			"record_size",
 			"24, 28, or 32",
 			metadata.RecordSize,
-		)
+		))
+		ok = false
 	}
 
 	if metadata.NodeCount == 0 {
-		return testError(
+		v.add("metadata", -1, SeverityError, testError(
 			"node_count",
 			"positive integer",
 			metadata.NodeCount,
-		)
+		))
+		ok = false
 	}
-	return nil
+	return ok
 }
 
-func (v *verifier) verifyDatabase() error {
-	offsets, err := v.verifySearchTree()
-	if err != nil {
-		return err
-	}
-
-	if err := v.verifyDataSectionSeparator(); err != nil {
-		return err
-	}
-
-	return v.verifyDataSection(offsets)
+func (v *verifier) verifyDatabase() {
+	offsets, treeOK := v.verifySearchTree()
+	v.verifyDataSectionSeparator()
+	v.verifyDataSection(offsets, treeOK)
 }
 
-func (v *verifier) verifySearchTree() (map[uint]bool, error) {
+func (v *verifier) verifySearchTree() (map[uint]bool, bool) {
 	offsets := make(map[uint]bool)
 
 	it := v.reader.Networks()
 	for it.Next() {
 		offset, err := v.reader.resolveDataPointer(it.lastNode.pointer)
 		if err != nil {
-			return nil, err
+			v.add("search tree", -1, SeverityError, err)
+			return offsets, false
 		}
 		offsets[uint(offset)] = true
 	}
 	if err := it.Err(); err != nil {
-		return nil, err
+		v.add("search tree", -1, SeverityError, err)
+		return offsets, false
 	}
-	return offsets, nil
+	return offsets, true
 }
 
-func (v *verifier) verifyDataSectionSeparator() error {
+func (v *verifier) verifyDataSectionSeparator() {
 	separatorStart := v.reader.Metadata.NodeCount * v.reader.Metadata.RecordSize / 4
 
 	separator := v.reader.buffer[separatorStart : separatorStart+dataSectionSeparatorSize]
 
 	for _, b := range separator {
 		if b != 0 {
-			return newInvalidDatabaseError("unexpected byte in data separator: %v", separator)
+			v.add("data section separator", -1, SeverityError,
+				newInvalidDatabaseError("unexpected byte in data separator: %v", separator))
+			return
 		}
 	}
-	return nil
 }
 
-func (v *verifier) verifyDataSection(offsets map[uint]bool) error {
+// verifyDataSection decodes the data section and checks that the search
+// tree points to each of its values. If the search tree could not be fully
+// read, offsets is incomplete and only decoding is checked.
+func (v *verifier) verifyDataSection(offsets map[uint]bool, treeOK bool) {
 	pointerCount := len(offsets)
 
 	decoder := v.reader.decoder
@@ -136,39 +251,43 @@ func (v *verifier) verifyDataSection(offsets map[uint]bool) error {
 		rv := reflect.ValueOf(&data)
 		newOffset, err := decoder.decode(offset, rv)
 		if err != nil {
-			return newInvalidDatabaseError("received decoding error (%v) at offset of %v", err, offset)
+			v.add("data section", int(offset), SeverityError,
+				newInvalidDatabaseError("received decoding error (%v) at offset of %v", err, offset))
+			return
 		}
 		if newOffset <= offset {
-			return newInvalidDatabaseError("data section offset unexpectedly went from %v to %v", offset, newOffset)
+			v.add("data section", int(offset), SeverityError,
+				newInvalidDatabaseError("data section offset unexpectedly went from %v to %v", offset, newOffset))
+			return
 		}
 
 		pointer := offset
 
 		if _, ok := offsets[pointer]; ok {
 			delete(offsets, pointer)
-		} else {
-			return newInvalidDatabaseError("found data (%v) at %v that the search tree does not point to", data, pointer)
+		} else if treeOK {
+			v.add("data section", int(pointer), SeverityWarning,
+				newInvalidDatabaseError("found data (%v) at %v that the search tree does not point to", data, pointer))
 		}
 
 		offset = newOffset
 	}
 
 	if offset != bufferLen {
-		return newInvalidDatabaseError(
+		v.add("data section", int(offset), SeverityError, newInvalidDatabaseError(
 			"unexpected data at the end of the data section (last offset: %v, end: %v)",
 			offset,
 			bufferLen,
-		)
+		))
 	}
 
 	if len(offsets) != 0 {
-		return newInvalidDatabaseError(
+		v.add("search tree", -1, SeverityError, newInvalidDatabaseError(
 			"found %v pointers (of %v) in the search tree that we did not see in the data section",
 			len(offsets),
 			pointerCount,
-		)
+		))
 	}
-	return nil
 }
 
 func testError(
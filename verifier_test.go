@@ -58,3 +58,52 @@ func TestVerifyOnBrokenDatabases(t *testing.T) {
 		}
 	}
 }
+
+func TestVerifyReport(t *testing.T) {
+	reader, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	if report := reader.VerifyReport(); report.Err() != nil {
+		t.Fatalf("unexpected findings: %v", report)
+	}
+
+	reader.Metadata.BinaryFormatMinorVersion = 1
+	reader.Metadata.Description = nil
+	report := reader.VerifyReport()
+	if len(report.Findings) != 2 {
+		t.Fatalf("expected 2 findings, got %v", report)
+	}
+	for _, finding := range report.Findings {
+		if finding.Section != "metadata" || finding.Severity != SeverityWarning || finding.Offset != -1 {
+			t.Errorf("unexpected finding %v", finding)
+		}
+	}
+	if err := reader.Verify(); err != report.Findings[0].Err {
+		t.Errorf("expected Verify to return the first finding, got %v", err)
+	}
+}
+
+func TestVerifyReportOnBrokenDatabase(t *testing.T) {
+	reader, err := Open("test-data/test-data/GeoIP2-City-Test-Broken-Double-Format.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	report := reader.VerifyReport()
+	if report.Err() == nil {
+		t.Fatal("expected findings")
+	}
+	var found bool
+	for _, finding := range report.Findings {
+		if finding.Section == "data section" && finding.Severity == SeverityError && finding.Offset >= 0 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a data section error, got %v", report)
+	}
+}
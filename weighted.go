@@ -0,0 +1,138 @@
+package maxminddb
+
+import (
+	"hash/fnv"
+	"net"
+	"sync/atomic"
+)
+
+// Source identifies the database a WeightedReader served a lookup from.
+type Source int
+
+const (
+	// SourcePrimary is the primary database.
+	SourcePrimary Source = iota
+	// SourceSecondary is the secondary database.
+	SourceSecondary
+)
+
+func (s Source) String() string {
+	if s == SourceSecondary {
+		return "secondary"
+	}
+	return "primary"
+}
+
+// WeightedStats holds the counters of a WeightedReader.
+type WeightedStats struct {
+	Primary    uint64 // lookups served by the primary database
+	Secondary  uint64 // lookups served by the secondary database
+	Compared   uint64 // lookups also made on the other database
+	Mismatches uint64 // compared lookups with differing results
+}
+
+// WeightedReader routes a share of lookups to a secondary database, e.g.,
+// to migrate gradually from one vendor to another. Routing depends on the
+// IP address only, so that a client is consistently served by the same
+// database while the share is unchanged. It is safe for concurrent use.
+type WeightedReader struct {
+	// The counters are updated atomically, so that lookups do not
+	// contend on a lock. They come first to be 64-bit aligned.
+	stats WeightedStats
+
+	primary        *Reader
+	secondary      *Reader
+	secondaryShare float64
+	compare        bool
+}
+
+// NewWeightedReader returns a WeightedReader serving the given share of
+// lookups, between 0 and 1, from secondary. If compare is true, each lookup
+// is also made on the other database and mismatches are counted, which
+// doubles the cost of lookups.
+func NewWeightedReader(primary, secondary *Reader, secondaryShare float64, compare bool) *WeightedReader {
+	return &WeightedReader{
+		primary:        primary,
+		secondary:      secondary,
+		secondaryShare: secondaryShare,
+		compare:        compare,
+	}
+}
+
+// Lookup looks up ipAddress like Reader.Lookup in the database it routes
+// the address to, and returns which one it is. The other database never
+// affects the result or the returned error.
+func (w *WeightedReader) Lookup(ipAddress net.IP, result interface{}) (Source, error) {
+	source, serving, other := SourcePrimary, w.primary, w.secondary
+	if w.routesToSecondary(ipAddress) {
+		source, serving, other = SourceSecondary, w.secondary, w.primary
+	}
+
+	// The record is found once and decoded twice when comparing, rather
+	// than looked up again.
+	offset, err := serving.LookupOffset(ipAddress)
+	if err == nil && offset != NotFound {
+		err = serving.Decode(offset, result)
+	}
+	if err != nil || !w.compare {
+		w.count(source, false, false)
+		return source, err
+	}
+
+	var servedRecord, otherRecord interface{}
+	if offset != NotFound {
+		err = serving.Decode(offset, &servedRecord)
+	}
+	if err == nil {
+		err = other.Lookup(ipAddress, &otherRecord)
+	}
+	w.count(source, true, err != nil || !Equal(servedRecord, otherRecord))
+	return source, nil
+}
+
+func (w *WeightedReader) routesToSecondary(ipAddress net.IP) bool {
+	if w.secondaryShare <= 0 {
+		return false
+	}
+	if w.secondaryShare >= 1 {
+		return true
+	}
+	if ip := ipAddress.To4(); ip != nil {
+		ipAddress = ip
+	}
+	h := fnv.New64a()
+	h.Write(ipAddress)
+	return float64(h.Sum64()%10000) < w.secondaryShare*10000
+}
+
+func (w *WeightedReader) count(source Source, compared, mismatch bool) {
+	if source == SourceSecondary {
+		atomic.AddUint64(&w.stats.Secondary, 1)
+	} else {
+		atomic.AddUint64(&w.stats.Primary, 1)
+	}
+	if compared {
+		atomic.AddUint64(&w.stats.Compared, 1)
+	}
+	if mismatch {
+		atomic.AddUint64(&w.stats.Mismatches, 1)
+	}
+}
+
+// Stats returns a copy of the current counters.
+func (w *WeightedReader) Stats() WeightedStats {
+	return WeightedStats{
+		Primary:    atomic.LoadUint64(&w.stats.Primary),
+		Secondary:  atomic.LoadUint64(&w.stats.Secondary),
+		Compared:   atomic.LoadUint64(&w.stats.Compared),
+		Mismatches: atomic.LoadUint64(&w.stats.Mismatches),
+	}
+}
+
+// ResetStats clears the counters.
+func (w *WeightedReader) ResetStats() {
+	atomic.StoreUint64(&w.stats.Primary, 0)
+	atomic.StoreUint64(&w.stats.Secondary, 0)
+	atomic.StoreUint64(&w.stats.Compared, 0)
+	atomic.StoreUint64(&w.stats.Mismatches, 0)
+}
@@ -0,0 +1,60 @@
+package maxminddb
+
+import (
+	"fmt"
+	"net"
+	"testing"
+)
+
+func TestWeightedReader(t *testing.T) {
+	primary, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-24.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	secondary, err := Open("test-data/test-data/MaxMind-DB-test-ipv4-32.mmdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer secondary.Close()
+
+	weighted := NewWeightedReader(primary, secondary, 0.5, true)
+	sources := map[Source]int{}
+	for i := 0; i < 256; i++ {
+		ip := net.ParseIP(fmt.Sprintf("1.1.%d.%d", i%4, i))
+		var result map[string]string
+		source, err := weighted.Lookup(ip, &result)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sources[source]++
+
+		// Routing is consistent for an address.
+		again, _ := weighted.Lookup(ip, &result)
+		if again != source {
+			t.Errorf("expected %s to be routed consistently", ip)
+		}
+	}
+	if sources[SourcePrimary] == 0 || sources[SourceSecondary] == 0 {
+		t.Errorf("expected lookups to be split, got %v", sources)
+	}
+
+	stats := weighted.Stats()
+	if stats.Primary+stats.Secondary != 512 || stats.Compared != 512 || stats.Mismatches != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	weighted.ResetStats()
+	if stats := weighted.Stats(); stats != (WeightedStats{}) {
+		t.Errorf("expected the stats to be reset, got %+v", stats)
+	}
+
+	weighted = NewWeightedReader(primary, secondary, 0, false)
+	var result map[string]string
+	if source, err := weighted.Lookup(net.ParseIP("1.1.1.1"), &result); source != SourcePrimary || err != nil {
+		t.Errorf("expected the primary database, got %v, %v", source, err)
+	}
+	if stats := weighted.Stats(); stats != (WeightedStats{Primary: 1}) {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}